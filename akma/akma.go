@@ -0,0 +1,71 @@
+// Package akma implements the AKMA key and identifier derivations of
+// TS 33.535 Annex A (KAKMA, A-TID, A-KID and KAF) from a TUAK-based KAUSF.
+package akma
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"tuak"
+	"tuak/kdf"
+)
+
+// FC values of the AKMA derivations (TS 33.535 Annex A).
+const (
+	FCKAKMA = 0x80
+	FCATID  = 0x81
+	FCKAF   = 0x82
+)
+
+// Keys is the AKMA context the UE and the AAnF hold for one SUPI.
+type Keys struct {
+	SUPI  string
+	KAKMA []byte
+	// ATID is the 256-bit A-TID.
+	ATID []byte
+}
+
+// New derives KAKMA and A-TID from KAUSF and the SUPI. The SUPI is used
+// as given for P1 (e.g. the IMSI digits).
+func New(kausf []byte, supi string) *Keys {
+	return &Keys{
+		SUPI:  supi,
+		KAKMA: kdf.Derive(kausf, FCKAKMA, []byte("AKMA"), []byte(supi)),
+		ATID:  kdf.Derive(kausf, FCATID, []byte("A-TID"), []byte(supi)),
+	}
+}
+
+// FromTUAK runs f2345 on t, derives KAUSF for the serving network name and
+// SQN of the 5G AKA run, and returns the AKMA keys for the SUPI.
+func FromTUAK(t *tuak.TUAK, snn string, sqn []byte, supi string) (*Keys, error) {
+	if len(sqn) != 6 {
+		return nil, fmt.Errorf("akma: SQN length %d bytes, want 6", len(sqn))
+	}
+	_, ck, ik, ak, err := t.F2345()
+	if err != nil {
+		return nil, err
+	}
+	sqnXorAK := make([]byte, 6)
+	for i := range sqnXorAK {
+		sqnXorAK[i] = sqn[i] ^ ak[i]
+	}
+	return New(kdf.KAUSF(ck, ik, snn, sqnXorAK), supi), nil
+}
+
+// AKID returns the A-KID in NAI form username@realm (TS 33.535 6.1); the
+// username is the routing indicator and the hex-encoded A-TID joined by ".".
+func (k *Keys) AKID(routingIndicator, realm string) string {
+	return routingIndicator + "." + hex.EncodeToString(k.ATID) + "@" + realm
+}
+
+// KAF derives KAF = KDF(KAKMA, AF_ID, SUPI) for an application function.
+func (k *Keys) KAF(afID []byte) []byte {
+	return kdf.Derive(k.KAKMA, FCKAF, afID, []byte(k.SUPI))
+}
+
+// AFID returns AF_ID = FQDN of the AF || Ua* security protocol identifier.
+func AFID(fqdn string, uaProtocol []byte) []byte {
+	out := make([]byte, 0, len(fqdn)+len(uaProtocol))
+	out = append(out, fqdn...)
+	return append(out, uaProtocol...)
+}
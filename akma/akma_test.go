@@ -0,0 +1,93 @@
+package akma
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"tuak"
+	"tuak/kdf"
+	"tuak/testvectors"
+)
+
+func TestKeys(t *testing.T) {
+	kausf := bytes.Repeat([]byte{0x5a}, 32)
+	supi := "001010000000001"
+	k := New(kausf, supi)
+
+	if want := referenceKDF(kausf, FCKAKMA, []byte("AKMA"), []byte(supi)); !bytes.Equal(k.KAKMA, want) {
+		t.Fatalf("KAKMA = %x, want %x", k.KAKMA, want)
+	}
+	if want := referenceKDF(kausf, FCATID, []byte("A-TID"), []byte(supi)); !bytes.Equal(k.ATID, want) {
+		t.Fatalf("A-TID = %x, want %x", k.ATID, want)
+	}
+	afID := AFID("af.example.org", []byte{0x01, 0x00, 0x00, 0x00, 0x02})
+	if want := referenceKDF(k.KAKMA, FCKAF, afID, []byte(supi)); !bytes.Equal(k.KAF(afID), want) {
+		t.Fatalf("KAF mismatch")
+	}
+
+	akid := k.AKID("0000", "mnc001.mcc001.3gppnetwork.org")
+	user, realm, ok := strings.Cut(akid, "@")
+	if !ok || realm != "mnc001.mcc001.3gppnetwork.org" || user != "0000."+hex.EncodeToString(k.ATID) {
+		t.Fatalf("A-KID = %q", akid)
+	}
+}
+
+func TestFromTUAK(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	v := data.Tests[0]
+	tk, err := tuak.NewWithTOPc(decodeHex(t, v.K), decodeHex(t, v.Topc), decodeHex(t, v.Rand), nil, nil,
+		tuak.WithRESLength(v.RESLength),
+		tuak.WithCKLength(v.CKlength),
+		tuak.WithIKLength(v.IKlength),
+		tuak.WithKeccakIterations(v.KeccakIterations),
+	)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	snn := "5G:mnc001.mcc001.3gppnetwork.org"
+	sqn := decodeHex(t, v.SQN)
+	supi := "001010000000001"
+	k, err := FromTUAK(tk, snn, sqn, supi)
+	if err != nil {
+		t.Fatalf("FromTUAK: %v", err)
+	}
+	ak := decodeHex(t, v.F5)
+	for i := range sqn {
+		sqn[i] ^= ak[i]
+	}
+	kausf := kdf.KAUSF(decodeHex(t, v.F3), decodeHex(t, v.F4), snn, sqn)
+	if want := New(kausf, supi); !bytes.Equal(k.KAKMA, want.KAKMA) || !bytes.Equal(k.ATID, want.ATID) {
+		t.Fatalf("FromTUAK keys differ from KAUSF derivation")
+	}
+	if _, err := FromTUAK(tk, snn, sqn[:5], supi); err == nil {
+		t.Fatalf("FromTUAK accepted 5-byte SQN")
+	}
+}
+
+// referenceKDF builds S = FC || P0 || L0 || ... by hand (TS 33.220 B.2).
+func referenceKDF(key []byte, fc byte, params ...[]byte) []byte {
+	s := []byte{fc}
+	for _, p := range params {
+		s = append(s, p...)
+		s = append(s, byte(len(p)>>8), byte(len(p)))
+	}
+	m := hmac.New(sha256.New, key)
+	m.Write(s)
+	return m.Sum(nil)
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+// Package ausf implements the AUSF and SEAF side of 5G AKA confirmation
+// (TS 33.501 6.1.3.2) for vectors produced by package udm.
+package ausf
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"tuak/kdf"
+	"tuak/udm"
+)
+
+var (
+	// ErrHRESStarMismatch is returned when HRES* does not equal HXRES*.
+	ErrHRESStarMismatch = errors.New("ausf: HRES* does not match HXRES*")
+	// ErrRESStarMismatch is returned when RES* does not equal XRES*.
+	ErrRESStarMismatch = errors.New("ausf: RES* does not match XRES*")
+)
+
+// SEAV is the 5G SE AV handed to the SEAF.
+type SEAV struct {
+	RAND      []byte
+	AUTN      []byte
+	HXRESStar []byte
+}
+
+// Context holds the AUSF state of one 5G AKA authentication.
+type Context struct {
+	snn      string
+	rand     []byte
+	autn     []byte
+	xresStar []byte
+	kausf    []byte
+}
+
+// Start requests a 5G HE AV from the UDM and returns the AUSF context.
+func Start(ctx context.Context, client *udm.Client, supi, snn, ausfInstanceID string) (*Context, error) {
+	res, err := client.GenerateAuthData(ctx, supi, &udm.AuthenticationInfoRequest{
+		ServingNetworkName: snn,
+		AusfInstanceID:     ausfInstanceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.AuthenticationVector == nil {
+		return nil, fmt.Errorf("ausf: missing authentication vector")
+	}
+	return NewContext(res.AuthenticationVector, snn)
+}
+
+// NewContext builds the AUSF context from a 5G HE AKA vector.
+func NewContext(v *udm.AuthenticationVector, snn string) (*Context, error) {
+	if v.AvType != udm.AvType5GHEAKA {
+		return nil, fmt.Errorf("ausf: unexpected avType %q", v.AvType)
+	}
+	c := &Context{snn: snn}
+	fields := []struct {
+		name string
+		hex  string
+		dst  *[]byte
+	}{
+		{"rand", v.Rand, &c.rand},
+		{"autn", v.Autn, &c.autn},
+		{"xresStar", v.XresStar, &c.xresStar},
+		{"kausf", v.Kausf, &c.kausf},
+	}
+	for _, f := range fields {
+		b, err := hex.DecodeString(f.hex)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("ausf: invalid %s", f.name)
+		}
+		*f.dst = b
+	}
+	return c, nil
+}
+
+// SEAV returns the vector for the SEAF, with XRES* replaced by HXRES*.
+func (c *Context) SEAV() *SEAV {
+	return &SEAV{
+		RAND:      c.rand,
+		AUTN:      c.autn,
+		HXRESStar: kdf.HXRESStar(c.rand, c.xresStar),
+	}
+}
+
+// KAUSF returns the KAUSF of this authentication.
+func (c *Context) KAUSF() []byte {
+	return c.kausf
+}
+
+// Confirm compares RES* received from the UE with XRES* and returns KSEAF.
+func (c *Context) Confirm(resStar []byte) ([]byte, error) {
+	if subtle.ConstantTimeCompare(resStar, c.xresStar) != 1 {
+		return nil, ErrRESStarMismatch
+	}
+	return kdf.KSEAF(c.kausf, c.snn), nil
+}
+
+// CheckRESStar is the SEAF check of HRES* against HXRES*.
+func (s *SEAV) CheckRESStar(resStar []byte) error {
+	if subtle.ConstantTimeCompare(kdf.HXRESStar(s.RAND, resStar), s.HXRESStar) != 1 {
+		return ErrHRESStarMismatch
+	}
+	return nil
+}
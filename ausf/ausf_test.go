@@ -0,0 +1,101 @@
+package ausf
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"tuak"
+	"tuak/kdf"
+	"tuak/store"
+	"tuak/testvectors"
+	"tuak/udm"
+)
+
+const testSNN = "5G:mnc001.mcc001.3gppnetwork.org"
+
+func TestConfirm(t *testing.T) {
+	sub := testSubscriber(t)
+	subs := store.NewMemory()
+	if err := subs.Put(context.Background(), sub); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(udm.NewHandler(subs))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := &udm.Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+	c, err := Start(context.Background(), client, sub.ID, testSNN, "ausf-1")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	se := c.SEAV()
+
+	// UE side: RES from f2, then RES* from CK/IK.
+	tk, err := tuak.NewWithTOPc(sub.K, sub.TOPc, se.RAND, nil, nil, sub.TUAKOptions()...)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	res, ck, ik, _, err := tk.F2345()
+	if err != nil {
+		t.Fatalf("F2345: %v", err)
+	}
+	resStar := kdf.XRESStar(ck, ik, testSNN, se.RAND, res)
+
+	if err := se.CheckRESStar(resStar); err != nil {
+		t.Fatalf("CheckRESStar: %v", err)
+	}
+	kseaf, err := c.Confirm(resStar)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if want := kdf.KSEAF(c.KAUSF(), testSNN); hex.EncodeToString(kseaf) != hex.EncodeToString(want) {
+		t.Fatalf("KSEAF mismatch")
+	}
+
+	resStar[0] ^= 0x01
+	if err := se.CheckRESStar(resStar); !errors.Is(err, ErrHRESStarMismatch) {
+		t.Fatalf("CheckRESStar with bad RES*: err = %v", err)
+	}
+	if _, err := c.Confirm(resStar); !errors.Is(err, ErrRESStarMismatch) {
+		t.Fatalf("Confirm with bad RES*: err = %v", err)
+	}
+}
+
+func TestNewContextRejectsEAPAKAPrime(t *testing.T) {
+	_, err := NewContext(&udm.AuthenticationVector{AvType: udm.AvTypeEAPAKAPrime}, testSNN)
+	if err == nil {
+		t.Fatalf("NewContext accepted an EAP-AKA' vector")
+	}
+}
+
+func testSubscriber(t *testing.T) *store.Subscriber {
+	t.Helper()
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	v := data.Tests[1]
+	k, _ := hex.DecodeString(v.K)
+	topc, _ := hex.DecodeString(v.Topc)
+	return &store.Subscriber{
+		ID:      "imsi-001010000000001",
+		K:       k,
+		TOPc:    topc,
+		Options: optionsFromVector(v),
+	}
+}
+
+func optionsFromVector(v testvectors.TUAKVector) tuak.Options {
+	return tuak.Options{
+		KLength:          v.Klength,
+		MACLength:        v.MAClength,
+		RESLength:        v.RESLength,
+		CKLength:         v.CKlength,
+		IKLength:         v.IKlength,
+		KeccakIterations: v.KeccakIterations,
+	}
+}
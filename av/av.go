@@ -0,0 +1,276 @@
+// Package av generates authentication vectors from TUAK subscriber credentials.
+package av
+
+import (
+	crand "crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"tuak"
+	"tuak/kdf"
+)
+
+// ErrMACFailure is returned when a received MAC does not verify.
+var ErrMACFailure = errors.New("av: MAC verification failed")
+
+// Credentials holds the long-term TUAK secrets of a subscriber.
+type Credentials struct {
+	K       []byte
+	TOPc    []byte
+	Options []tuak.Option
+}
+
+// UMTS is a UMTS authentication vector (quintet).
+type UMTS struct {
+	RAND tuak.Hex `json:"rand"`
+	XRES tuak.Hex `json:"xres"`
+	CK   tuak.Hex `json:"ck"`
+	IK   tuak.Hex `json:"ik"`
+	AUTN AUTN     `json:"autn"`
+	AK   tuak.Hex `json:"ak"`
+}
+
+// Triplet is a GSM authentication triplet derived with c2 and c3.
+type Triplet struct {
+	RAND tuak.Hex `json:"rand"`
+	SRES tuak.Hex `json:"sres"`
+	Kc   tuak.Hex `json:"kc"`
+}
+
+// HE5G is a 5G home environment authentication vector (TS 33.501 6.1.3.2).
+type HE5G struct {
+	RAND     tuak.Hex `json:"rand"`
+	AUTN     AUTN     `json:"autn"`
+	XRESStar tuak.Hex `json:"xres_star"`
+	KAUSF    tuak.Hex `json:"kausf"`
+}
+
+// EAPAKAPrime is an EAP-AKA' authentication vector (TS 33.501 6.1.3.1).
+type EAPAKAPrime struct {
+	RAND    tuak.Hex `json:"rand"`
+	AUTN    AUTN     `json:"autn"`
+	XRES    tuak.Hex `json:"xres"`
+	CKPrime tuak.Hex `json:"ck_prime"`
+	IKPrime tuak.Hex `json:"ik_prime"`
+}
+
+// Generator computes authentication vectors.
+type Generator struct {
+	// Rand supplies RAND values. crypto/rand is used when nil.
+	Rand io.Reader
+	// Seed is the DRBG seed Rand was instantiated from, to be recorded
+	// with the generated vectors; nil for other sources.
+	Seed *Seed
+
+	// rec, when set by Campaign, collects the generated vectors.
+	rec *recorder
+}
+
+// recorder holds the vectors of one campaign generator in the order their
+// RAND values were drawn.
+type recorder struct {
+	mu      sync.Mutex
+	vectors []Vector
+}
+
+// generate runs fn and, for a campaign generator, records its vector. The
+// lock is held across fn so that the record order is the DRBG order.
+func (g *Generator) generate(fn func() (Vector, error)) error {
+	if g.rec == nil {
+		_, err := fn()
+		return err
+	}
+	g.rec.mu.Lock()
+	defer g.rec.mu.Unlock()
+	v, err := fn()
+	if err != nil {
+		return err
+	}
+	g.rec.vectors = append(g.rec.vectors, v)
+	return nil
+}
+
+// UMTS computes a UMTS authentication vector for the given SQN and AMF.
+func (g *Generator) UMTS(c Credentials, sqn, amf []byte) (*UMTS, error) {
+	var v *UMTS
+	err := g.generate(func() (Vector, error) {
+		var err error
+		v, err = g.umts(c, sqn, amf)
+		return Vector{UMTS: v}, err
+	})
+	return v, err
+}
+
+// GSM computes a GSM triplet. CK and IK must be 128 bits long.
+func (g *Generator) GSM(c Credentials) (*Triplet, error) {
+	var v *Triplet
+	err := g.generate(func() (Vector, error) {
+		r, err := g.newRAND()
+		if err != nil {
+			return Vector{}, err
+		}
+		v, err = ComputeGSM(c, r)
+		return Vector{GSM: v}, err
+	})
+	return v, err
+}
+
+// HE5G computes a 5G HE AV for the given serving network name.
+func (g *Generator) HE5G(c Credentials, sqn, amf []byte, snn string) (*HE5G, error) {
+	var he *HE5G
+	err := g.generate(func() (Vector, error) {
+		v, err := g.umts(c, sqn, amf)
+		if err != nil {
+			return Vector{}, err
+		}
+		sqnXorAK := v.AUTN[:6]
+		he = &HE5G{
+			RAND:     v.RAND,
+			AUTN:     v.AUTN,
+			XRESStar: kdf.XRESStar(v.CK, v.IK, snn, v.RAND, v.XRES),
+			KAUSF:    kdf.KAUSF(v.CK, v.IK, snn, sqnXorAK),
+		}
+		return Vector{HE5G: he}, nil
+	})
+	return he, err
+}
+
+// EAPAKAPrime computes an EAP-AKA' vector for the given serving network name.
+func (g *Generator) EAPAKAPrime(c Credentials, sqn, amf []byte, snn string) (*EAPAKAPrime, error) {
+	var ea *EAPAKAPrime
+	err := g.generate(func() (Vector, error) {
+		v, err := g.umts(c, sqn, amf)
+		if err != nil {
+			return Vector{}, err
+		}
+		ckPrime, ikPrime := kdf.CKIKPrime(v.CK, v.IK, snn, v.AUTN[:6])
+		ea = &EAPAKAPrime{
+			RAND:    v.RAND,
+			AUTN:    v.AUTN,
+			XRES:    v.XRES,
+			CKPrime: ckPrime,
+			IKPrime: ikPrime,
+		}
+		return Vector{EAPAKAPrime: ea}, nil
+	})
+	return ea, err
+}
+
+func (g *Generator) umts(c Credentials, sqn, amf []byte) (*UMTS, error) {
+	r, err := g.newRAND()
+	if err != nil {
+		return nil, err
+	}
+	return ComputeUMTS(c, r, sqn, amf)
+}
+
+// ComputeUMTS computes a UMTS authentication vector for a given RAND.
+func ComputeUMTS(c Credentials, rand, sqn, amf []byte) (*UMTS, error) {
+	t, err := tuak.NewWithTOPc(c.K, c.TOPc, rand, sqn, amf, c.Options...)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := t.F1()
+	if err != nil {
+		return nil, err
+	}
+	res, ck, ik, ak, err := t.F2345()
+	if err != nil {
+		return nil, err
+	}
+	return &UMTS{
+		RAND: rand,
+		XRES: res,
+		CK:   ck,
+		IK:   ik,
+		AUTN: BuildAUTN(sqn, ak, amf, mac),
+		AK:   ak,
+	}, nil
+}
+
+// ComputeGSM computes a GSM triplet for a given RAND.
+func ComputeGSM(c Credentials, rand []byte) (*Triplet, error) {
+	t, err := tuak.NewWithTOPc(c.K, c.TOPc, rand, nil, nil, c.Options...)
+	if err != nil {
+		return nil, err
+	}
+	sres, kc, err := t.GSM()
+	if err != nil {
+		return nil, err
+	}
+	return &Triplet{RAND: rand, SRES: sres, Kc: kc}, nil
+}
+
+// Triplet converts a UMTS vector to a GSM triplet (TS 33.102 6.8.1.2).
+func (v *UMTS) Triplet() (*Triplet, error) {
+	sres, err := tuak.C2(v.XRES)
+	if err != nil {
+		return nil, err
+	}
+	kc, err := tuak.C3(v.CK, v.IK)
+	if err != nil {
+		return nil, err
+	}
+	return &Triplet{RAND: v.RAND, SRES: sres, Kc: kc}, nil
+}
+
+// BuildAUTN returns SQN xor AK || AMF || MAC.
+func BuildAUTN(sqn, ak, amf, mac []byte) AUTN {
+	autn := make([]byte, 0, 6+len(amf)+len(mac))
+	autn = append(autn, xor(sqn, ak)...)
+	autn = append(autn, amf...)
+	return append(autn, mac...)
+}
+
+// Resync recovers SQN_MS from AUTS (SQN_MS xor AK* || MAC-S) and verifies
+// MAC-S using the dummy AMF of TS 33.102 6.3.3.
+func Resync(c Credentials, rand, auts []byte) ([]byte, error) {
+	if len(auts) < 6 {
+		return nil, fmt.Errorf("av: AUTS length %d bytes", len(auts))
+	}
+	t, err := tuak.NewWithTOPc(c.K, c.TOPc, rand, nil, nil, c.Options...)
+	if err != nil {
+		return nil, err
+	}
+	akStar, err := t.F5Star()
+	if err != nil {
+		return nil, err
+	}
+	sqnMS := xor(auts[:6], akStar)
+
+	t, err = tuak.NewWithTOPc(c.K, c.TOPc, rand, sqnMS, make([]byte, 2), c.Options...)
+	if err != nil {
+		return nil, err
+	}
+	macS, err := t.F1Star()
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(macS, auts[6:]) != 1 {
+		return nil, ErrMACFailure
+	}
+	return sqnMS, nil
+}
+
+func (g *Generator) newRAND() ([]byte, error) {
+	src := g.Rand
+	if src == nil {
+		src = crand.Reader
+	}
+	out := make([]byte, 16)
+	if _, err := io.ReadFull(src, out); err != nil {
+		return nil, fmt.Errorf("av: read RAND: %w", err)
+	}
+	return out, nil
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
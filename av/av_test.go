@@ -0,0 +1,447 @@
+package av
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"tuak"
+	"tuak/kdf"
+	"tuak/testvectors"
+)
+
+func TestComputeUMTSVectors(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		c := credentialsFromVector(t, v)
+		sqn := decodeHex(t, v.SQN)
+		amf := decodeHex(t, v.AMF)
+		got, err := ComputeUMTS(c, decodeHex(t, v.Rand), sqn, amf)
+		if err != nil {
+			t.Fatalf("vector %d ComputeUMTS: %v", v.ID, err)
+		}
+		if !bytes.Equal(got.XRES, decodeHex(t, v.F2)) {
+			t.Fatalf("vector %d XRES mismatch", v.ID)
+		}
+		if !bytes.Equal(got.CK, decodeHex(t, v.F3)) || !bytes.Equal(got.IK, decodeHex(t, v.F4)) {
+			t.Fatalf("vector %d CK/IK mismatch", v.ID)
+		}
+		wantAUTN := BuildAUTN(sqn, decodeHex(t, v.F5), amf, decodeHex(t, v.F1))
+		if !bytes.Equal(got.AUTN, wantAUTN) {
+			t.Fatalf("vector %d AUTN mismatch", v.ID)
+		}
+	}
+}
+
+func TestGeneratorHE5G(t *testing.T) {
+	v := loadVector(t, 1)
+	c := credentialsFromVector(t, v)
+	rand := decodeHex(t, v.Rand)
+	snn := "5G:mnc001.mcc001.3gppnetwork.org"
+	g := &Generator{Rand: bytes.NewReader(rand)}
+
+	he, err := g.HE5G(c, decodeHex(t, v.SQN), decodeHex(t, v.AMF), snn)
+	if err != nil {
+		t.Fatalf("HE5G: %v", err)
+	}
+	if !bytes.Equal(he.RAND, rand) {
+		t.Fatalf("RAND not taken from generator source")
+	}
+	want := kdf.XRESStar(decodeHex(t, v.F3), decodeHex(t, v.F4), snn, rand, decodeHex(t, v.F2))
+	if !bytes.Equal(he.XRESStar, want) {
+		t.Fatalf("XRES* mismatch")
+	}
+	if len(he.KAUSF) != 32 {
+		t.Fatalf("KAUSF length = %d, want 32", len(he.KAUSF))
+	}
+}
+
+func TestGSMTriplets(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		c := credentialsFromVector(t, v)
+		rand := decodeHex(t, v.Rand)
+		triplet, err := (&Generator{Rand: bytes.NewReader(rand)}).GSM(c)
+		if v.CKlength != 128 || v.IKlength != 128 {
+			if err == nil {
+				t.Fatalf("vector %d: GSM accepted %d/%d-bit CK/IK", v.ID, v.CKlength, v.IKlength)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("vector %d GSM: %v", v.ID, err)
+		}
+		umts, err := ComputeUMTS(c, rand, decodeHex(t, v.SQN), decodeHex(t, v.AMF))
+		if err != nil {
+			t.Fatalf("ComputeUMTS: %v", err)
+		}
+		want, err := umts.Triplet()
+		if err != nil {
+			t.Fatalf("Triplet: %v", err)
+		}
+		if !bytes.Equal(triplet.SRES, want.SRES) || !bytes.Equal(triplet.Kc, want.Kc) {
+			t.Fatalf("vector %d triplet mismatch", v.ID)
+		}
+		if len(v.F2) == 8 && !bytes.Equal(triplet.SRES, decodeHex(t, v.F2)) {
+			t.Fatalf("vector %d: SRES differs from 32-bit RES", v.ID)
+		}
+	}
+}
+
+func TestResync(t *testing.T) {
+	v := loadVector(t, 1)
+	c := credentialsFromVector(t, v)
+	rand := decodeHex(t, v.Rand)
+	sqnMS := decodeHex(t, "000000000120")
+
+	auts := buildAUTS(t, c, rand, sqnMS)
+	got, err := Resync(c, rand, auts)
+	if err != nil {
+		t.Fatalf("Resync: %v", err)
+	}
+	if !bytes.Equal(got, sqnMS) {
+		t.Fatalf("SQN_MS = %x, want %x", got, sqnMS)
+	}
+
+	auts[len(auts)-1] ^= 0x01
+	if _, err := Resync(c, rand, auts); !errors.Is(err, ErrMACFailure) {
+		t.Fatalf("Resync with bad MAC-S: err = %v, want ErrMACFailure", err)
+	}
+}
+
+func TestVectorEncoding(t *testing.T) {
+	v := loadVector(t, 1)
+	umts, err := ComputeUMTS(credentialsFromVector(t, v), decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF))
+	if err != nil {
+		t.Fatalf("ComputeUMTS: %v", err)
+	}
+	b, err := json.Marshal(umts)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !strings.Contains(string(b), `"rand":"`+v.Rand+`"`) || !strings.Contains(string(b), `"ck":"`+v.F3+`"`) {
+		t.Fatalf("JSON = %s", b)
+	}
+	var back UMTS
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !bytes.Equal(back.AUTN, umts.AUTN) || !bytes.Equal(back.IK, umts.IK) {
+		t.Fatalf("JSON round trip = %+v", back)
+	}
+
+	text, err := umts.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: %v", err)
+	}
+	back = UMTS{}
+	if err := back.UnmarshalText(text); err != nil || !bytes.Equal(back.XRES, umts.XRES) {
+		t.Fatalf("UnmarshalText = %+v, %v", back, err)
+	}
+
+	redacted, err := json.Marshal(tuak.Redact(umts))
+	if err != nil {
+		t.Fatalf("Marshal redacted: %v", err)
+	}
+	for _, secret := range []string{v.F2, v.F3, v.F4, v.F5} {
+		if strings.Contains(string(redacted), secret) {
+			t.Fatalf("redacted JSON leaks %s: %s", secret, redacted)
+		}
+	}
+	if !strings.Contains(string(redacted), umts.AUTN.String()) {
+		t.Fatalf("redacted JSON lost AUTN: %s", redacted)
+	}
+	redactedText, err := tuak.Redact(umts).MarshalText()
+	if err != nil || strings.Contains(string(redactedText), v.F3) {
+		t.Fatalf("redacted text = %s, %v", redactedText, err)
+	}
+
+	var autn AUTN
+	if err := json.Unmarshal([]byte(`"0102"`), &autn); err == nil {
+		t.Fatalf("short AUTN accepted")
+	}
+
+	for _, v := range []any{UMTS{}, Triplet{}, HE5G{}, EAPAKAPrime{}} {
+		if b, err := json.Marshal(v); err != nil || b[0] != '{' {
+			t.Fatalf("%T JSON = %s, %v; want an object", v, b, err)
+		}
+	}
+}
+
+func buildAUTS(t *testing.T, c Credentials, rand, sqnMS []byte) []byte {
+	t.Helper()
+	tk, err := tuak.NewWithTOPc(c.K, c.TOPc, rand, sqnMS, make([]byte, 2), c.Options...)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	akStar, err := tk.F5Star()
+	if err != nil {
+		t.Fatalf("F5Star: %v", err)
+	}
+	macS, err := tk.F1Star()
+	if err != nil {
+		t.Fatalf("F1Star: %v", err)
+	}
+	return append(xor(sqnMS, akStar), macS...)
+}
+
+func loadVector(t *testing.T, id int) testvectors.TUAKVector {
+	t.Helper()
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		if v.ID == id {
+			return v
+		}
+	}
+	t.Fatalf("test set %d not found", id)
+	return testvectors.TUAKVector{}
+}
+
+func credentialsFromVector(t *testing.T, v testvectors.TUAKVector) Credentials {
+	t.Helper()
+	return Credentials{
+		K:    decodeHex(t, v.K),
+		TOPc: decodeHex(t, v.Topc),
+		Options: []tuak.Option{
+			tuak.WithMACLength(v.MAClength),
+			tuak.WithRESLength(v.RESLength),
+			tuak.WithCKLength(v.CKlength),
+			tuak.WithIKLength(v.IKlength),
+			tuak.WithKeccakIterations(v.KeccakIterations),
+		},
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
+
+func TestDRBGKnownAnswers(t *testing.T) {
+	// First COUNT = 0 entries of the NIST CAVP DRBG test vectors
+	// (drbgvectors_no_reseed): HMAC_DRBG SHA-256 and CTR_DRBG AES-256 with
+	// the derivation function, both without prediction resistance,
+	// personalization or additional input. Each returns the output of the
+	// second generate request.
+	for _, tc := range []struct {
+		m              Mechanism
+		entropy, nonce string
+		returned       string
+	}{
+		{HMACDRBG,
+			"ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488",
+			"659ba96c601dc69fc902940805ec0ca8",
+			"e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89" +
+				"d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc1" +
+				"07694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668" +
+				"961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8",
+		},
+		{CTRDRBG,
+			"36401940fa8b1fba91a1661f211d78a0b9389a74e5bccfece8d766af1a6d3b14",
+			"496f25b0f1301b4f501be30380a137eb",
+			"5862eb38bd558dd978a696e6df164782ddd887e7e9a6c9f3f1fbafb78941b535" +
+				"a64912dfd224c6dc7454e5250b3d97165e16260c2faf1cc7735cb75fb4f07e1d",
+		},
+	} {
+		d, err := NewDRBG(Seed{Mechanism: tc.m, Entropy: decodeHex(t, tc.entropy), Nonce: decodeHex(t, tc.nonce)})
+		if err != nil {
+			t.Fatalf("NewDRBG(%s): %v", tc.m, err)
+		}
+		got := make([]byte, len(tc.returned)/2)
+		for i := 0; i < 2; i++ {
+			if _, err := io.ReadFull(d, got); err != nil {
+				t.Fatalf("%s read %d: %v", tc.m, i, err)
+			}
+		}
+		if hex.EncodeToString(got) != tc.returned {
+			t.Fatalf("%s ReturnedBits = %x, want %s", tc.m, got, tc.returned)
+		}
+	}
+
+	// CTR_DRBG core against the Go FIPS 140 self-test, which instantiates
+	// without the derivation function and reseeds with additional input.
+	var entropy, reseed, additional [ctrSeedLen]byte
+	for i := range entropy {
+		entropy[i], reseed[i], additional[i] = byte(i+1), byte(i+0x31), byte(i+0x61)
+	}
+	d := &ctrDRBG{}
+	d.block, _ = aes.NewCipher(make([]byte, ctrKeyLen))
+	d.update(&entropy)
+	for i := range reseed {
+		reseed[i] ^= additional[i]
+	}
+	d.update(&reseed)
+	got := make([]byte, 32)
+	d.generateWith(got, &additional)
+	if want := "6e6e479d24f86a3b7787a8f8186d985a53bebeeddeab9228f0f4ac6e10bf0193"; hex.EncodeToString(got) != want {
+		t.Fatalf("CTR_DRBG core = %x, want %s", got, want)
+	}
+}
+
+func TestDRBGRequests(t *testing.T) {
+	seed, err := NewSeed(HMACDRBG)
+	if err != nil {
+		t.Fatalf("NewSeed: %v", err)
+	}
+	d, _ := NewDRBG(seed)
+	long := make([]byte, drbgMaxRequest+100)
+	if _, err := d.Read(long); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	d, _ = NewDRBG(seed)
+	parts := make([]byte, len(long))
+	d.Read(parts[:drbgMaxRequest])
+	d.Read(parts[drbgMaxRequest:])
+	if !bytes.Equal(long, parts) {
+		t.Fatalf("long read is not split into maximum-size requests")
+	}
+
+	d.reseedCounter = drbgReseedInterval + 1
+	if _, err := d.Read(make([]byte, 16)); !errors.Is(err, ErrReseedRequired) {
+		t.Fatalf("Read past reseed interval: err = %v", err)
+	}
+	if _, err := NewDRBG(Seed{Mechanism: CTRDRBG, Entropy: make([]byte, 31)}); err == nil {
+		t.Fatalf("NewDRBG accepted 31 bytes of entropy")
+	}
+	if _, err := NewDRBG(Seed{Mechanism: "Hash_DRBG", Entropy: make([]byte, 32)}); err == nil {
+		t.Fatalf("NewDRBG accepted an unknown mechanism")
+	}
+}
+
+func TestSeededGeneratorReplay(t *testing.T) {
+	v := loadVector(t, 1)
+	c := credentialsFromVector(t, v)
+	sqn, amf := decodeHex(t, v.SQN), decodeHex(t, v.AMF)
+	for _, m := range []Mechanism{HMACDRBG, CTRDRBG} {
+		seed, err := NewSeed(m)
+		if err != nil {
+			t.Fatalf("NewSeed(%s): %v", m, err)
+		}
+		campaign, err := NewCampaign(seed)
+		if err != nil {
+			t.Fatalf("NewCampaign: %v", err)
+		}
+		var first []*UMTS
+		for _, sub := range []string{"imsi-001010000000001", "imsi-001010000000002"} {
+			g, err := campaign.Generator(sub)
+			if err != nil {
+				t.Fatalf("Generator(%s): %v", sub, err)
+			}
+			for i := 0; i < 3; i++ {
+				u, err := g.UMTS(c, sqn, amf)
+				if err != nil {
+					t.Fatalf("UMTS: %v", err)
+				}
+				first = append(first, u)
+			}
+		}
+
+		// Replay from the recorded seed, second subscriber first.
+		b, err := json.Marshal(campaign)
+		if err != nil {
+			t.Fatalf("Marshal campaign: %v", err)
+		}
+		var recorded Campaign
+		if err := json.Unmarshal(b, &recorded); err != nil {
+			t.Fatalf("Unmarshal campaign %s: %v", b, err)
+		}
+		replay, err := NewCampaign(recorded.Seed)
+		if err != nil {
+			t.Fatalf("NewCampaign(recorded): %v", err)
+		}
+		g2, _ := replay.Generator("imsi-001010000000002")
+		g1, _ := replay.Generator("imsi-001010000000001")
+		for _, i := range []int{3, 4, 0, 5, 1, 2} {
+			g := g1
+			if i >= 3 {
+				g = g2
+			}
+			u, err := g.UMTS(c, sqn, amf)
+			if err != nil {
+				t.Fatalf("UMTS: %v", err)
+			}
+			if !bytes.Equal(u.RAND, first[i].RAND) || !bytes.Equal(u.AUTN, first[i].AUTN) {
+				t.Fatalf("%s vector %d not regenerated: RAND %x, want %x", m, i, u.RAND, first[i].RAND)
+			}
+		}
+		if bytes.Equal(first[0].RAND, first[3].RAND) {
+			t.Fatalf("%s subscribers share a RAND sequence", m)
+		}
+		if g1.Seed == nil || string(g1.Seed.Personalization) != "imsi-001010000000001" {
+			t.Fatalf("generator seed = %+v", g1.Seed)
+		}
+	}
+}
+
+func TestCampaignRecords(t *testing.T) {
+	v := loadVector(t, 1)
+	c := credentialsFromVector(t, v)
+	sqn, amf := decodeHex(t, v.SQN), decodeHex(t, v.AMF)
+	snn := "5G:mnc001.mcc001.3gppnetwork.org"
+	seed, err := NewSeed(CTRDRBG)
+	if err != nil {
+		t.Fatalf("NewSeed: %v", err)
+	}
+	campaign, err := NewCampaign(seed)
+	if err != nil {
+		t.Fatalf("NewCampaign: %v", err)
+	}
+	for _, sub := range []string{"imsi-001010000000002", "imsi-001010000000001"} {
+		g, _ := campaign.Generator(sub)
+		if _, err := g.UMTS(c, sqn, amf); err != nil {
+			t.Fatalf("UMTS: %v", err)
+		}
+		if _, err := g.HE5G(c, sqn, amf, snn); err != nil {
+			t.Fatalf("HE5G: %v", err)
+		}
+		if _, err := g.EAPAKAPrime(c, sqn, amf, snn); err != nil {
+			t.Fatalf("EAPAKAPrime: %v", err)
+		}
+	}
+
+	b, err := json.Marshal(campaign.Records())
+	if err != nil {
+		t.Fatalf("Marshal records: %v", err)
+	}
+	var records []Record
+	if err := json.Unmarshal(b, &records); err != nil {
+		t.Fatalf("Unmarshal records %s: %v", b, err)
+	}
+	if len(records) != 2 || records[0].Subscriber != "imsi-001010000000001" {
+		t.Fatalf("records = %s", b)
+	}
+	for _, r := range records {
+		if !bytes.Equal(r.Seed.Entropy, seed.Entropy) || len(r.Seed.Personalization) != 0 {
+			t.Fatalf("%s: seed = %+v, want the campaign seed", r.Subscriber, r.Seed)
+		}
+		if len(r.Vectors) != 3 || r.Vectors[0].UMTS == nil || r.Vectors[1].HE5G == nil || r.Vectors[2].EAPAKAPrime == nil {
+			t.Fatalf("%s: vectors = %+v", r.Subscriber, r.Vectors)
+		}
+		replay, _ := NewCampaign(r.Seed)
+		g, _ := replay.Generator(r.Subscriber)
+		u, _ := g.UMTS(c, sqn, amf)
+		he, _ := g.HE5G(c, sqn, amf, snn)
+		ea, _ := g.EAPAKAPrime(c, sqn, amf, snn)
+		if !bytes.Equal(u.AUTN, r.Vectors[0].UMTS.AUTN) || !bytes.Equal(he.KAUSF, r.Vectors[1].HE5G.KAUSF) || !bytes.Equal(ea.CKPrime, r.Vectors[2].EAPAKAPrime.CKPrime) {
+			t.Fatalf("%s: vectors not regenerated from the record", r.Subscriber)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package av
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"tuak"
+)
+
+// Mechanism names a NIST SP 800-90A DRBG.
+type Mechanism string
+
+// DRBG mechanisms, both at the 256-bit security strength.
+const (
+	// HMACDRBG is HMAC_DRBG with SHA-256 (SP 800-90A 10.1.2).
+	HMACDRBG Mechanism = "HMAC_DRBG"
+	// CTRDRBG is CTR_DRBG with AES-256 and the derivation function
+	// (SP 800-90A 10.2.1).
+	CTRDRBG Mechanism = "CTR_DRBG"
+)
+
+// Seed is everything a DRBG is instantiated from. The same seed always
+// yields the same RAND sequence, so recording it with generated vectors is
+// enough to regenerate them bit for bit.
+type Seed struct {
+	Mechanism       Mechanism `json:"mechanism"`
+	Entropy         tuak.Hex  `json:"entropy"`
+	Nonce           tuak.Hex  `json:"nonce,omitempty"`
+	Personalization tuak.Hex  `json:"personalization,omitempty"`
+}
+
+// NewSeed returns a seed for m with 32 bytes of entropy input and a 16-byte
+// nonce read from crypto/rand.
+func NewSeed(m Mechanism) (Seed, error) {
+	b := make([]byte, 48)
+	if _, err := io.ReadFull(crand.Reader, b); err != nil {
+		return Seed{}, fmt.Errorf("av: read seed: %w", err)
+	}
+	s := Seed{Mechanism: m, Entropy: b[:32], Nonce: b[32:]}
+	return s, s.validate()
+}
+
+// Derive returns a copy of s with label appended to the personalization
+// string. DRBGs instantiated from seeds derived with different labels
+// produce independent sequences.
+func (s Seed) Derive(label string) Seed {
+	out := s.clone()
+	out.Personalization = append(out.Personalization, label...)
+	return out
+}
+
+func (s Seed) clone() Seed {
+	return Seed{
+		Mechanism:       s.Mechanism,
+		Entropy:         append(tuak.Hex(nil), s.Entropy...),
+		Nonce:           append(tuak.Hex(nil), s.Nonce...),
+		Personalization: append(tuak.Hex(nil), s.Personalization...),
+	}
+}
+
+func (s Seed) validate() error {
+	switch s.Mechanism {
+	case HMACDRBG, CTRDRBG:
+	default:
+		return fmt.Errorf("av: unknown DRBG mechanism %q", s.Mechanism)
+	}
+	if len(s.Entropy) < 32 {
+		return fmt.Errorf("av: DRBG entropy input %d bytes, want at least 32", len(s.Entropy))
+	}
+	return nil
+}
+
+// ErrReseedRequired is returned once a DRBG has served the SP 800-90A
+// maximum of 2^48 requests; start again from a new seed.
+var ErrReseedRequired = errors.New("av: DRBG reseed required")
+
+const (
+	drbgReseedInterval = 1 << 48
+	drbgMaxRequest     = (1 << 19) / 8
+)
+
+// DRBG is a deterministic RAND source for Generator.Rand. Each Read is
+// one SP 800-90A generate request without additional input (split into
+// requests of 64 KiB at most), so Generator draws one request per RAND.
+// It is safe for concurrent use, although concurrent callers then share
+// the sequence in an unpredictable order.
+type DRBG struct {
+	seed Seed
+
+	mu            sync.Mutex
+	gen           drbgGenerator
+	reseedCounter uint64
+}
+
+type drbgGenerator interface {
+	generate(out []byte)
+}
+
+// NewDRBG instantiates the DRBG seed describes.
+func NewDRBG(seed Seed) (*DRBG, error) {
+	if err := seed.validate(); err != nil {
+		return nil, err
+	}
+	d := &DRBG{seed: seed.clone(), reseedCounter: 1}
+	switch seed.Mechanism {
+	case HMACDRBG:
+		d.gen = newHMACDRBG(seed.Entropy, seed.Nonce, seed.Personalization)
+	case CTRDRBG:
+		d.gen = newCTRDRBG(seed.Entropy, seed.Nonce, seed.Personalization)
+	}
+	return d, nil
+}
+
+// Seed returns the seed d was instantiated from.
+func (d *DRBG) Seed() Seed {
+	return d.seed.clone()
+}
+
+// Read implements io.Reader.
+func (d *DRBG) Read(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		if d.reseedCounter > drbgReseedInterval {
+			return n, ErrReseedRequired
+		}
+		end := min(len(p), n+drbgMaxRequest)
+		d.gen.generate(p[n:end])
+		d.reseedCounter++
+		n = end
+	}
+	return n, nil
+}
+
+// NewSeededGenerator returns a Generator drawing RAND from the DRBG seed
+// describes, with Seed set to a copy of seed.
+func NewSeededGenerator(seed Seed) (*Generator, error) {
+	d, err := NewDRBG(seed)
+	if err != nil {
+		return nil, err
+	}
+	s := d.Seed()
+	return &Generator{Rand: d, Seed: &s}, nil
+}
+
+// Campaign hands out one seeded Generator per subscriber of a test
+// campaign. Each subscriber's DRBG is instantiated from Seed derived with
+// the subscriber ID, so its vectors can be regenerated from the recorded
+// campaign seed regardless of the order or number of other subscribers.
+type Campaign struct {
+	Seed Seed `json:"seed"`
+
+	mu   sync.Mutex
+	gens map[string]*Generator
+}
+
+// NewCampaign starts a campaign from seed; use NewSeed for a fresh one.
+func NewCampaign(seed Seed) (*Campaign, error) {
+	if err := seed.validate(); err != nil {
+		return nil, err
+	}
+	return &Campaign{Seed: seed.clone()}, nil
+}
+
+// Generator returns the generator of subscriber, e.g. its SUPI or IMSI.
+// Repeated calls return the same generator, continuing its sequence.
+func (c *Campaign) Generator(subscriber string) (*Generator, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if g, ok := c.gens[subscriber]; ok {
+		return g, nil
+	}
+	g, err := NewSeededGenerator(c.Seed.Derive(subscriber))
+	if err != nil {
+		return nil, err
+	}
+	g.rec = &recorder{}
+	if c.gens == nil {
+		c.gens = map[string]*Generator{}
+	}
+	c.gens[subscriber] = g
+	return g, nil
+}
+
+// Records returns, for every subscriber that has a generator, the vectors
+// it generated so far bundled with the campaign seed and the subscriber
+// label, sorted by subscriber. A call that fails after drawing its RAND is
+// not recorded, although it still advances the sequence.
+func (c *Campaign) Records() []Record {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Record, 0, len(c.gens))
+	for sub, g := range c.gens {
+		g.rec.mu.Lock()
+		out = append(out, Record{
+			Seed:       c.Seed.clone(),
+			Subscriber: sub,
+			Vectors:    append([]Vector(nil), g.rec.vectors...),
+		})
+		g.rec.mu.Unlock()
+	}
+	slices.SortFunc(out, func(a, b Record) int { return strings.Compare(a.Subscriber, b.Subscriber) })
+	return out
+}
+
+// Record is the output of one subscriber in a campaign. Replaying the
+// vectors' calls in order on NewCampaign(Seed).Generator(Subscriber), with
+// the same credentials, SQN, AMF and serving network names, regenerates
+// them bit for bit.
+type Record struct {
+	Seed       Seed     `json:"seed"`
+	Subscriber string   `json:"subscriber"`
+	Vectors    []Vector `json:"vectors"`
+}
+
+// Vector is one generated vector. Exactly one field is set, naming the
+// Generator method that produced it.
+type Vector struct {
+	UMTS        *UMTS        `json:"umts,omitempty"`
+	GSM         *Triplet     `json:"gsm,omitempty"`
+	HE5G        *HE5G        `json:"he5g,omitempty"`
+	EAPAKAPrime *EAPAKAPrime `json:"eap_aka_prime,omitempty"`
+}
+
+// hmacDRBG is HMAC_DRBG (SP 800-90A 10.1.2) with SHA-256.
+type hmacDRBG struct {
+	k, v []byte
+}
+
+func newHMACDRBG(entropy, nonce, personalization []byte) *hmacDRBG {
+	d := &hmacDRBG{
+		k: make([]byte, sha256.Size),
+		v: make([]byte, sha256.Size),
+	}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+	d.update(entropy, nonce, personalization)
+	return d
+}
+
+func (d *hmacDRBG) mac(key []byte, data ...[]byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// update is HMAC_DRBG_Update; provided is the concatenation of its parts.
+func (d *hmacDRBG) update(provided ...[]byte) {
+	empty := true
+	for _, p := range provided {
+		empty = empty && len(p) == 0
+	}
+	for _, sep := range []byte{0x00, 0x01} {
+		d.k = d.mac(d.k, append([][]byte{d.v, {sep}}, provided...)...)
+		d.v = d.mac(d.k, d.v)
+		if empty {
+			return
+		}
+	}
+}
+
+func (d *hmacDRBG) generate(out []byte) {
+	for n := 0; n < len(out); {
+		d.v = d.mac(d.k, d.v)
+		n += copy(out[n:], d.v)
+	}
+	d.update()
+}
+
+// ctrDRBG is CTR_DRBG (SP 800-90A 10.2.1) with AES-256 and the
+// derivation function.
+type ctrDRBG struct {
+	block cipher.Block
+	v     [aes.BlockSize]byte
+}
+
+const (
+	ctrKeyLen  = 32
+	ctrSeedLen = ctrKeyLen + aes.BlockSize
+)
+
+func newCTRDRBG(entropy, nonce, personalization []byte) *ctrDRBG {
+	d := &ctrDRBG{}
+	d.block, _ = aes.NewCipher(make([]byte, ctrKeyLen))
+	seed := blockCipherDF(ctrSeedLen, entropy, nonce, personalization)
+	d.update((*[ctrSeedLen]byte)(seed))
+	return d
+}
+
+// update is CTR_DRBG_Update.
+func (d *ctrDRBG) update(provided *[ctrSeedLen]byte) {
+	var temp [ctrSeedLen]byte
+	for i := 0; i < ctrSeedLen; i += aes.BlockSize {
+		d.increment()
+		d.block.Encrypt(temp[i:], d.v[:])
+	}
+	if provided != nil {
+		subtle.XORBytes(temp[:], temp[:], provided[:])
+	}
+	d.block, _ = aes.NewCipher(temp[:ctrKeyLen])
+	copy(d.v[:], temp[ctrKeyLen:])
+}
+
+func (d *ctrDRBG) increment() {
+	for i := len(d.v) - 1; i >= 0; i-- {
+		d.v[i]++
+		if d.v[i] != 0 {
+			return
+		}
+	}
+}
+
+func (d *ctrDRBG) generate(out []byte) {
+	d.generateWith(out, nil)
+}
+
+// generateWith is CTR_DRBG_Generate; additional is the additional input
+// after the derivation function, nil for none.
+func (d *ctrDRBG) generateWith(out []byte, additional *[ctrSeedLen]byte) {
+	if additional != nil {
+		d.update(additional)
+	}
+	var block [aes.BlockSize]byte
+	for n := 0; n < len(out); {
+		d.increment()
+		d.block.Encrypt(block[:], d.v[:])
+		n += copy(out[n:], block[:])
+	}
+	d.update(additional)
+}
+
+// blockCipherDF is Block_Cipher_df (SP 800-90A 10.3.2) with AES-256,
+// returning n bytes derived from the concatenation of input.
+func blockCipherDF(n int, input ...[]byte) []byte {
+	var l int
+	for _, in := range input {
+		l += len(in)
+	}
+	s := binary.BigEndian.AppendUint32(nil, uint32(l))
+	s = binary.BigEndian.AppendUint32(s, uint32(n))
+	for _, in := range input {
+		s = append(s, in...)
+	}
+	s = append(s, 0x80)
+	for len(s)%aes.BlockSize != 0 {
+		s = append(s, 0x00)
+	}
+
+	key := make([]byte, ctrKeyLen)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	block, _ := aes.NewCipher(key)
+	var temp []byte
+	for i := uint32(0); len(temp) < ctrSeedLen; i++ {
+		var iv [aes.BlockSize]byte
+		binary.BigEndian.PutUint32(iv[:], i)
+		temp = append(temp, bcc(block, iv[:], s)...)
+	}
+
+	block, _ = aes.NewCipher(temp[:ctrKeyLen])
+	x := temp[ctrKeyLen:ctrSeedLen]
+	out := make([]byte, 0, n+aes.BlockSize)
+	for len(out) < n {
+		block.Encrypt(x, x)
+		out = append(out, x...)
+	}
+	return out[:n]
+}
+
+// bcc is the BCC function of SP 800-90A 10.3.3 over the blocks of data.
+func bcc(block cipher.Block, data ...[]byte) []byte {
+	chain := make([]byte, aes.BlockSize)
+	for _, d := range data {
+		for i := 0; i < len(d); i += aes.BlockSize {
+			subtle.XORBytes(chain, chain, d[i:i+aes.BlockSize])
+			block.Encrypt(chain, chain)
+		}
+	}
+	return chain
+}
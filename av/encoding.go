@@ -0,0 +1,154 @@
+package av
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"tuak/internal/textfmt"
+)
+
+// AUTN is SQN xor AK || AMF || MAC. Its JSON and text forms are
+// lowercase hex.
+type AUTN []byte
+
+// String returns a in lowercase hex.
+func (a AUTN) String() string {
+	return hex.EncodeToString(a)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a AUTN) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(a)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. AUTN must hold at
+// least SQN xor AK and AMF.
+func (a *AUTN) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("av: decode AUTN: %w", err)
+	}
+	if len(b) < 8 {
+		return fmt.Errorf("av: AUTN length %d bytes", len(b))
+	}
+	*a = b
+	return nil
+}
+
+// The vector types below implement encoding.TextMarshaler, which
+// encoding/json would otherwise prefer to their struct tags and encode as
+// a quoted string; their MarshalJSON and UnmarshalJSON methods keep the
+// JSON form an object.
+
+// SecretFields implements tuak.Redactor.
+func (UMTS) SecretFields() []string {
+	return []string{"xres", "ck", "ik", "ak"}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v UMTS) MarshalJSON() ([]byte, error) {
+	type plain UMTS
+	return json.Marshal(plain(v))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *UMTS) UnmarshalJSON(b []byte) error {
+	type plain UMTS
+	return json.Unmarshal(b, (*plain)(v))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v UMTS) MarshalText() ([]byte, error) {
+	return textfmt.Marshal(&v)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *UMTS) UnmarshalText(text []byte) error {
+	*v = UMTS{}
+	return textfmt.Unmarshal(text, v)
+}
+
+// SecretFields implements tuak.Redactor.
+func (Triplet) SecretFields() []string {
+	return []string{"sres", "kc"}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v Triplet) MarshalJSON() ([]byte, error) {
+	type plain Triplet
+	return json.Marshal(plain(v))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *Triplet) UnmarshalJSON(b []byte) error {
+	type plain Triplet
+	return json.Unmarshal(b, (*plain)(v))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v Triplet) MarshalText() ([]byte, error) {
+	return textfmt.Marshal(&v)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *Triplet) UnmarshalText(text []byte) error {
+	*v = Triplet{}
+	return textfmt.Unmarshal(text, v)
+}
+
+// SecretFields implements tuak.Redactor.
+func (HE5G) SecretFields() []string {
+	return []string{"xres_star", "kausf"}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v HE5G) MarshalJSON() ([]byte, error) {
+	type plain HE5G
+	return json.Marshal(plain(v))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *HE5G) UnmarshalJSON(b []byte) error {
+	type plain HE5G
+	return json.Unmarshal(b, (*plain)(v))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v HE5G) MarshalText() ([]byte, error) {
+	return textfmt.Marshal(&v)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *HE5G) UnmarshalText(text []byte) error {
+	*v = HE5G{}
+	return textfmt.Unmarshal(text, v)
+}
+
+// SecretFields implements tuak.Redactor.
+func (EAPAKAPrime) SecretFields() []string {
+	return []string{"xres", "ck_prime", "ik_prime"}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v EAPAKAPrime) MarshalJSON() ([]byte, error) {
+	type plain EAPAKAPrime
+	return json.Marshal(plain(v))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *EAPAKAPrime) UnmarshalJSON(b []byte) error {
+	type plain EAPAKAPrime
+	return json.Unmarshal(b, (*plain)(v))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v EAPAKAPrime) MarshalText() ([]byte, error) {
+	return textfmt.Marshal(&v)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *EAPAKAPrime) UnmarshalText(text []byte) error {
+	*v = EAPAKAPrime{}
+	return textfmt.Unmarshal(text, v)
+}
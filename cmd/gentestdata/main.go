@@ -0,0 +1,170 @@
+// Command gentestdata regenerates the JSON fixtures under
+// testvectors/testdata from text extracts of the 3GPP TS 35.232 and
+// TS 35.233 test data documents.
+//
+// It replaces scripts/generate_testdata.py, which wrote ts35232_keccak.json,
+// ts35233_keccak.json and ts35233_vectors_text.json; the tool writes those
+// files in the same format (two-space indented JSON with sorted keys and no
+// trailing newline). It also generates ts35232_f2345.json from TS 35.232
+// clause 7, which was previously maintained by hand, and
+// ts35232_intermediate.json with the IN/OUT blocks of clauses 6 and 7.
+// The intermediate file is not committed (it is listed in .gitignore); it
+// is written for local use next to the extracts.
+//
+// With -check it compares instead of writing and exits non-zero when a
+// fixture is stale. A missing intermediate file is not reported.
+//
+// Usage:
+//
+//	go run ./cmd/gentestdata [-reference dir] [-testdata dir] [-check]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	file35232 = "35232-i00_chapter4-7.txt"
+	file35233 = "35233-i00_chapter5-6.txt"
+
+	source35232     = "3GPP TS 35.232 V18.0.0 (Implementers test data)"
+	source35232Text = source35232 + " - text extract"
+	source35233Text = "3GPP TS 35.233 V18.0.0 (Design conformance test data) - text extract"
+
+	// intermediateSets is the number of test sets with IN/OUT dumps.
+	intermediateSets = 6
+)
+
+func main() {
+	reference := flag.String("reference", "reference", "directory holding the TS 35.232/35.233 text extracts")
+	testdata := flag.String("testdata", "testvectors/testdata", "fixture output directory")
+	check := flag.Bool("check", false, "verify the fixtures instead of writing them")
+	flag.Parse()
+
+	files, err := generate(*reference)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gentestdata:", err)
+		os.Exit(1)
+	}
+	if *check {
+		stale, err := checkFiles(*testdata, files)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gentestdata:", err)
+			os.Exit(1)
+		}
+		for _, name := range stale {
+			fmt.Fprintf(os.Stderr, "gentestdata: %s is missing or out of date\n", filepath.Join(*testdata, name))
+		}
+		if len(stale) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := writeFiles(*testdata, files); err != nil {
+		fmt.Fprintln(os.Stderr, "gentestdata:", err)
+		os.Exit(1)
+	}
+}
+
+// fixture is one generated file.
+type fixture struct {
+	name string
+	data []byte
+	// local marks a file that is not committed.
+	local bool
+}
+
+// generate parses the text extracts in dir and renders every fixture.
+func generate(dir string) ([]fixture, error) {
+	text35232, err := os.ReadFile(filepath.Join(dir, file35232))
+	if err != nil {
+		return nil, err
+	}
+	text35233, err := os.ReadFile(filepath.Join(dir, file35233))
+	if err != nil {
+		return nil, err
+	}
+	return render(string(text35232), string(text35233))
+}
+
+func render(text35232, text35233 string) ([]fixture, error) {
+	keccak := parseKeccak(text35232)
+	intermediate, err := parseIntermediate(text35232, intermediateSets)
+	if err != nil {
+		return nil, err
+	}
+	docs := []struct {
+		name  string
+		doc   map[string]interface{}
+		local bool
+	}{
+		{"ts35232_keccak.json", map[string]interface{}{"source": source35232Text, "keccak_f1600": keccak}, false},
+		// 35.233 uses the same Keccak vectors; keep a stable copy.
+		{"ts35233_keccak.json", map[string]interface{}{"source": source35233Text, "keccak_f1600": keccak}, false},
+		{"ts35233_vectors_text.json", map[string]interface{}{"source": source35233Text, "tests": parseTUAK(text35233)}, false},
+		{"ts35232_f2345.json", map[string]interface{}{"source": source35232, "tests": parseF2345(text35232)}, false},
+		{"ts35232_intermediate.json", map[string]interface{}{"source": source35232Text, "tests": intermediate}, true},
+	}
+	files := make([]fixture, 0, len(docs))
+	for _, d := range docs {
+		b, err := marshal(d.doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.name, err)
+		}
+		files = append(files, fixture{name: d.name, data: b, local: d.local})
+	}
+	return files, nil
+}
+
+// marshal matches Python's json.dumps(v, indent=2, sort_keys=True): map
+// keys are sorted by encoding/json, and HTML escaping and the encoder's
+// trailing newline are dropped.
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func writeFiles(dir string, files []fixture) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), f.data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFiles returns the names of fixtures that are missing or differ.
+// Local fixtures are only compared when present.
+func checkFiles(dir string, files []fixture) ([]string, error) {
+	var stale []string
+	for _, f := range files {
+		got, err := os.ReadFile(filepath.Join(dir, f.name))
+		if os.IsNotExist(err) {
+			if !f.local {
+				stale = append(stale, f.name)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(got, f.data) {
+			stale = append(stale, f.name)
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// vector is one JSON test entry; keys are sorted on output.
+type vector map[string]interface{}
+
+var (
+	reKeccakSet = regexp.MustCompile(`(?i)^5\.\d+\s+Test set\s+(\d+)`)
+	reTUAKSet   = regexp.MustCompile(`(?i)^6\.\d+\s+Test set\s+(\d+)`)
+	reF2345Set  = regexp.MustCompile(`(?i)^7\.\d+\s+Test set\s+(\d+)`)
+	reHexByte   = regexp.MustCompile(`\b[0-9a-fA-F]{2}\b`)
+	reKV        = regexp.MustCompile(`^([A-Za-z0-9\*]+):\s+([0-9a-fA-F]+)\b`)
+	reIter      = regexp.MustCompile(`\bKeccakIterations\b\s*=\s*(\d+)`)
+	reAsFor     = regexp.MustCompile(`As for Test Set\s+(\d+)`)
+)
+
+var lengthKeys = []struct{ key, field string }{
+	{"Klength", "klength"},
+	{"MAClength", "maclength"},
+	{"CKlength", "cklength"},
+	{"IKlength", "iklength"},
+	{"RESLength", "reslength"},
+}
+
+var lengthRE = func() map[string]*regexp.Regexp {
+	m := make(map[string]*regexp.Regexp)
+	for _, l := range lengthKeys {
+		m[l.key] = regexp.MustCompile(`\b` + l.key + `\b\s*=\s*(\d+)`)
+	}
+	return m
+}()
+
+var kvFields = map[string]string{
+	"K":    "k",
+	"RAND": "rand",
+	"SQN":  "sqn",
+	"AMF":  "amf",
+	"TOP":  "top",
+	"TOPc": "topc",
+	"f1":   "f1",
+	"f1*":  "f1_star",
+	"f2":   "f2",
+	"f3":   "f3",
+	"f4":   "f4",
+	"f5":   "f5",
+	"f5*":  "f5_star",
+}
+
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func hexTokens(s string) string {
+	return strings.ToLower(strings.Join(reHexByte.FindAllString(s, -1), ""))
+}
+
+// parseKeccak extracts the Keccak-f[1600] sets of TS 35.232 clause 5.
+func parseKeccak(text string) []vector {
+	vectors := []vector{}
+	var current vector
+	inMode, outMode, inSection := false, false, false
+	for _, line := range splitLines(text) {
+		s := strings.TrimSpace(line)
+		if m := reKeccakSet.FindStringSubmatch(s); m != nil {
+			if current != nil {
+				vectors = append(vectors, current)
+			}
+			current = vector{"id": atoi(m[1]), "in": "", "out": ""}
+			inSection, inMode, outMode = true, false, false
+			continue
+		}
+		if inSection && isHeading(s, "6") {
+			inSection = false
+			continue
+		}
+		if !inSection || current == nil {
+			continue
+		}
+		if strings.HasPrefix(s, "IN") {
+			inMode, outMode = true, false
+			continue
+		}
+		if strings.HasPrefix(s, "OUT") {
+			inMode, outMode = false, true
+			continue
+		}
+		if !inMode && !outMode {
+			continue
+		}
+		h := hexTokens(s)
+		if h == "" {
+			continue
+		}
+		if inMode {
+			current["in"] = current["in"].(string) + h
+		} else {
+			current["out"] = current["out"].(string) + h
+		}
+	}
+	if current != nil {
+		vectors = append(vectors, current)
+	}
+	for _, v := range vectors {
+		v["in_len"] = len(v["in"].(string)) / 2
+		v["out_len"] = len(v["out"].(string)) / 2
+	}
+	return vectors
+}
+
+// parseTUAK extracts the TUAK sets of TS 35.233 clause 6.
+func parseTUAK(text string) []vector {
+	return parseSets(text, reTUAKSet, nil)
+}
+
+// f2345Fields are the fields kept for the f2-f5 sets of TS 35.232 clause 7.
+// keccak_iterations was added to the original hand-written fixture so that
+// testvectors.RunConformance can run set 6, which uses two iterations.
+var f2345Fields = []string{"k", "rand", "top", "topc", "f2", "f3", "f4", "f5", "f5_star", "klength", "cklength", "iklength", "keccak_iterations"}
+
+// parseF2345 extracts the f2-f5 sets of TS 35.232 clause 7 that list f2.
+func parseF2345(text string) []vector {
+	out := []vector{}
+	for _, v := range parseSets(text, reF2345Set, f2345Fields) {
+		if _, ok := v["f2"]; ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseSets reads "name: hex" lines and "... = N bits" length lines of the
+// sections matched by reSet, up to any "Binary Format" block. A non-nil
+// keep restricts the fields recorded.
+func parseSets(text string, reSet *regexp.Regexp, keep []string) []vector {
+	kept := func(field string) bool {
+		if keep == nil {
+			return true
+		}
+		for _, k := range keep {
+			if k == field {
+				return true
+			}
+		}
+		return false
+	}
+	vectors := []vector{}
+	var current vector
+	inBinary := false
+	for _, line := range splitLines(text) {
+		s := strings.TrimSpace(line)
+		if m := reSet.FindStringSubmatch(s); m != nil {
+			if current != nil {
+				vectors = append(vectors, current)
+			}
+			current = vector{"id": atoi(m[1])}
+			inBinary = false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.HasPrefix(s, "Binary Format") {
+			inBinary = true
+			continue
+		}
+		if inBinary {
+			continue
+		}
+		if strings.Contains(s, "bits") || strings.Contains(s, "KeccakIterations") {
+			for _, l := range lengthKeys {
+				if m := lengthRE[l.key].FindStringSubmatch(s); m != nil && kept(l.field) {
+					current[l.field] = atoi(m[1])
+				}
+			}
+			if m := reIter.FindStringSubmatch(s); m != nil && kept("keccak_iterations") {
+				current["keccak_iterations"] = atoi(m[1])
+			}
+			continue
+		}
+		if m := reKV.FindStringSubmatch(s); m != nil {
+			field, ok := kvFields[m[1]]
+			if _, seen := current[field]; ok && !seen && kept(field) {
+				current[field] = strings.ToLower(m[2])
+			}
+		}
+	}
+	if current != nil {
+		vectors = append(vectors, current)
+	}
+	return vectors
+}
+
+// intermediateBlock locates the IN/OUT dumps of one function in a test set
+// of TS 35.232 clause 6 (TOPc, f1, f1*) or clause 7 (f2-f5, f5*).
+type intermediateBlock struct {
+	function string
+	clause   int
+	label    string
+	// stop ends the OUT capture at the next function's IN block.
+	stop []string
+}
+
+var intermediateBlocks = []intermediateBlock{
+	{"topc", 6, "TOPc", []string{"IN when computing f1:"}},
+	{"f1", 6, "f1", []string{"IN when computing f1*:"}},
+	{"f1star", 6, "f1*", nil},
+	{"f2345", 7, "f2-f5", []string{"IN when computing f5*:", "As for Test Set"}},
+	{"f5star", 7, "f5*", nil},
+}
+
+// parseIntermediate extracts the 200-byte IN and final OUT buffers of
+// every function and test set. f5* sets stated "As for Test Set N" reuse
+// the buffers of set N; sets without TOPc dumps are omitted.
+func parseIntermediate(text string, sets int) ([]vector, error) {
+	lines := splitLines(text)
+	out := []vector{}
+	for id := 1; id <= sets; id++ {
+		for _, b := range intermediateBlocks {
+			section := sectionLines(lines, b.clause, id, sets)
+			in, outBuf := captureBlock(section, b)
+			if in == "" && b.function == "f5star" {
+				if ref := referencedSet(section); ref != 0 && ref != id {
+					in, outBuf = captureBlock(sectionLines(lines, b.clause, ref, sets), b)
+				}
+			}
+			if in == "" && outBuf == "" {
+				continue
+			}
+			if len(in) != 400 || len(outBuf) != 400 {
+				return nil, fmt.Errorf("test set %d %s: IN %d bytes, OUT %d bytes, want 200", id, b.function, len(in)/2, len(outBuf)/2)
+			}
+			out = append(out, vector{"id": id, "function": b.function, "in": in, "out": outBuf})
+		}
+	}
+	return out, nil
+}
+
+func sectionLines(lines []string, clause, id, sets int) []string {
+	start := fmt.Sprintf("%d.%d", clause, id+2)
+	end := ""
+	switch {
+	case id < sets:
+		end = fmt.Sprintf("%d.%d", clause, id+3)
+	case clause == 6:
+		end = "7"
+	}
+	var out []string
+	inTest := false
+	for _, line := range lines {
+		s := strings.TrimSpace(line)
+		if isHeading(s, start) && strings.Contains(s, fmt.Sprintf("Test set %d", id)) {
+			inTest = true
+			continue
+		}
+		if inTest && end != "" && isHeading(s, end) {
+			break
+		}
+		if inTest {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func captureBlock(section []string, b intermediateBlock) (in, out string) {
+	inPrefix := "IN when computing " + b.label + ":"
+	outPrefixes := []string{
+		"OUT when computing " + b.label + ":",
+		"OUT/IN after one Keccak iteration, when computing " + b.label + ":",
+		"OUT after second Keccak iteration, when computing " + b.label + ":",
+	}
+	inCapture, outCapture := false, false
+	for _, s := range section {
+		switch {
+		case strings.HasPrefix(s, inPrefix):
+			inCapture, outCapture, in = true, false, ""
+			continue
+		case hasPrefixAny(s, outPrefixes):
+			inCapture, outCapture, out = false, true, ""
+			continue
+		case hasPrefixAny(s, b.stop):
+			inCapture, outCapture = false, false
+			continue
+		}
+		if inCapture {
+			in += hexTokens(s)
+		}
+		if outCapture {
+			out += hexTokens(s)
+		}
+	}
+	return in, out
+}
+
+func referencedSet(section []string) int {
+	for _, s := range section {
+		if strings.Contains(s, "computing f5*") {
+			if m := reAsFor.FindStringSubmatch(s); m != nil {
+				return atoi(m[1])
+			}
+		}
+	}
+	return 0
+}
+
+func hasPrefixAny(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHeading reports whether s is the heading of clause or one of its
+// subclauses. A bare prefix test would also match hex rows such as
+// "6c 52 08 ..." and later clauses such as 6.31 for 6.3.
+func isHeading(s, clause string) bool {
+	rest, ok := strings.CutPrefix(s, clause)
+	return ok && (rest == "" || rest[0] == '.' || rest[0] == ' ' || rest[0] == '\t')
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,232 @@
+// Command toprotate re-derives the TOPc of every subscriber when the
+// operator rotates its TOP, keeping the old TOPc as topc_previous until
+// the transition is finished.
+//
+// It streams a subscriber file (-in) to a new file (-out), or updates a
+// SQLite store (-sqlite) in place, and writes a JSON report with counts
+// and SHA-256 digests (see package rotate). TOPs and the KEK are read as
+// hex from files so that they never appear on the command line. The exit
+// status is non-zero when any subscriber failed to rotate.
+//
+// Usage:
+//
+//	go run ./cmd/toprotate -old-top-file f -new-top-file f (-in f -out f | -sqlite db) [-kek-file f] [-workers n] [-report f] [-dry-run]
+//	go run ./cmd/toprotate -verify report.json -new-top-file f (-in f | -sqlite db) [-kek-file f]
+//	go run ./cmd/toprotate -finish (-in f -out f | -sqlite db)
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"tuak/rotate"
+	"tuak/store"
+	"tuak/store/sqlite"
+)
+
+type flags struct {
+	oldTOPFile string
+	newTOPFile string
+	kekFile    string
+	in         string
+	out        string
+	sqlite     string
+	workers    int
+	report     string
+	verify     string
+	finish     bool
+	dryRun     bool
+}
+
+// errFailures reports subscribers that did not rotate after the report
+// was written.
+var errFailures = errors.New("some subscribers failed to rotate; see the report")
+
+func main() {
+	var f flags
+	flag.StringVar(&f.oldTOPFile, "old-top-file", "", "file holding the current TOP in hex")
+	flag.StringVar(&f.newTOPFile, "new-top-file", "", "file holding the new TOP in hex")
+	flag.StringVar(&f.kekFile, "kek-file", "", "file holding the KEK of wrapped subscribers in hex")
+	flag.StringVar(&f.in, "in", "", "subscriber file to read")
+	flag.StringVar(&f.out, "out", "", "subscriber file to write")
+	flag.StringVar(&f.sqlite, "sqlite", "", "SQLite store to update in place")
+	flag.IntVar(&f.workers, "workers", runtime.GOMAXPROCS(0), "subscribers computed in parallel")
+	flag.StringVar(&f.report, "report", "", "report output file (default stdout)")
+	flag.StringVar(&f.verify, "verify", "", "verify the store against this report instead of rotating")
+	flag.BoolVar(&f.finish, "finish", false, "end the transition by dropping the previous TOPc")
+	flag.BoolVar(&f.dryRun, "dry-run", false, "compute the report without writing subscribers")
+	flag.Parse()
+
+	if err := run(context.Background(), f, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "toprotate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, f flags, stdout io.Writer) error {
+	if (f.sqlite == "") == (f.in == "") {
+		return errors.New("give exactly one of -in and -sqlite")
+	}
+	var opts []rotate.Option
+	if f.kekFile != "" {
+		kek, err := readHex("kek-file", f.kekFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, rotate.WithKEK(kek))
+	}
+	opts = append(opts, rotate.WithWorkers(f.workers))
+
+	var (
+		src store.Ranger
+		dst rotate.Sink
+	)
+	if f.sqlite != "" {
+		if f.out != "" {
+			return errors.New("-out cannot be used with -sqlite")
+		}
+		s, err := sqlite.Open(f.sqlite)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		src, dst = s, s
+		opts = append(opts, rotate.WithChangedOnly())
+	} else {
+		src = store.NewFileScanner(f.in)
+	}
+
+	switch {
+	case f.verify != "":
+		return verify(ctx, f, src, opts, stdout)
+	case f.finish:
+		return finish(ctx, f, src, dst, opts, stdout)
+	}
+
+	oldTOP, err := readHex("old-top-file", f.oldTOPFile)
+	if err != nil {
+		return err
+	}
+	newTOP, err := readHex("new-top-file", f.newTOPFile)
+	if err != nil {
+		return err
+	}
+	var fw *store.FileWriter
+	switch {
+	case f.dryRun:
+		dst = nil
+	case f.sqlite == "":
+		if f.out == "" {
+			return errors.New("-in needs -out or -dry-run")
+		}
+		if fw, err = store.CreateFile(f.out); err != nil {
+			return err
+		}
+		dst = fw
+	}
+	rep, err := rotate.Rotate(ctx, src, dst, oldTOP, newTOP, opts...)
+	if fw != nil {
+		if err != nil {
+			fw.Discard()
+		} else {
+			err = fw.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeReport(f.report, stdout, rep); err != nil {
+		return err
+	}
+	if rep.Failed > 0 {
+		return errFailures
+	}
+	return nil
+}
+
+func verify(ctx context.Context, f flags, src store.Ranger, opts []rotate.Option, stdout io.Writer) error {
+	b, err := os.ReadFile(f.verify)
+	if err != nil {
+		return err
+	}
+	var rep rotate.Report
+	if err := json.Unmarshal(b, &rep); err != nil {
+		return fmt.Errorf("decode %s: %w", f.verify, err)
+	}
+	newTOP, err := readHex("new-top-file", f.newTOPFile)
+	if err != nil {
+		return err
+	}
+	if err := rotate.Verify(ctx, src, &rep, newTOP, opts...); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "verified %d subscribers against %s\n", rep.Total, f.verify)
+	return nil
+}
+
+func finish(ctx context.Context, f flags, src store.Ranger, dst rotate.Sink, opts []rotate.Option, stdout io.Writer) error {
+	var fw *store.FileWriter
+	if dst == nil {
+		if f.out == "" {
+			return errors.New("-in needs -out")
+		}
+		var err error
+		if fw, err = store.CreateFile(f.out); err != nil {
+			return err
+		}
+		dst = fw
+	}
+	n, err := rotate.Finish(ctx, src, dst, opts...)
+	if fw != nil {
+		if err != nil {
+			fw.Discard()
+		} else {
+			err = fw.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "dropped the previous TOPc of %d subscribers\n", n)
+	return nil
+}
+
+// readHex reads a hex value from the file the flag name gives, ignoring
+// surrounding whitespace.
+func readHex(name, path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("-%s is required", name)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// writeReport writes rep as indented JSON to path, or to stdout when path
+// is empty.
+func writeReport(path string, stdout io.Writer, rep *rotate.Report) error {
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if path == "" {
+		_, err = stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
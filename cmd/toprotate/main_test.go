@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tuak"
+	"tuak/rotate"
+	"tuak/store"
+	"tuak/store/sqlite"
+)
+
+// setup writes TOP files and a subscriber base of n subscribers under the
+// old TOP, returning the flags naming them.
+func setup(t *testing.T, n int) (flags, []*store.Subscriber) {
+	t.Helper()
+	dir := t.TempDir()
+	f := flags{
+		oldTOPFile: filepath.Join(dir, "old.top"),
+		newTOPFile: filepath.Join(dir, "new.top"),
+		in:         filepath.Join(dir, "subscribers.json"),
+		out:        filepath.Join(dir, "rotated.json"),
+		report:     filepath.Join(dir, "report.json"),
+		workers:    4,
+	}
+	oldTOP := bytes.Repeat([]byte{0xa1}, 32)
+	for path, top := range map[string][]byte{f.oldTOPFile: oldTOP, f.newTOPFile: bytes.Repeat([]byte{0xb2}, 32)} {
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(top)+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var subs []*store.Subscriber
+	for i := 0; i < n; i++ {
+		k := bytes.Repeat([]byte{byte(i + 1)}, 16)
+		topc, err := tuak.ComputeTOPc(k, oldTOP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subs = append(subs, &store.Subscriber{ID: fmt.Sprintf("imsi-%015d", 1010000000001+i), K: k, TOPc: topc})
+	}
+	if err := store.WriteFile(f.in, subs); err != nil {
+		t.Fatal(err)
+	}
+	return f, subs
+}
+
+func readReport(t *testing.T, path string) rotate.Report {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rep rotate.Report
+	if err := json.Unmarshal(b, &rep); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	return rep
+}
+
+func TestRotateFile(t *testing.T) {
+	ctx := context.Background()
+	f, subs := setup(t, 5)
+	var stdout bytes.Buffer
+	if err := run(ctx, f, &stdout); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	rep := readReport(t, f.report)
+	if rep.Total != 5 || rep.Rotated != 5 || rep.Failed != 0 {
+		t.Fatalf("report counts = %d/%d/%d, want 5/5/0", rep.Total, rep.Rotated, rep.Failed)
+	}
+	rotated, err := store.ReadFile(f.out)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	for i, sub := range rotated {
+		if !bytes.Equal(sub.PreviousTOPc, subs[i].TOPc) || bytes.Equal(sub.TOPc, subs[i].TOPc) {
+			t.Fatalf("%s not rotated", sub.ID)
+		}
+	}
+
+	v := flags{verify: f.report, newTOPFile: f.newTOPFile, in: f.out, workers: 2}
+	stdout.Reset()
+	if err := run(ctx, v, &stdout); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "verified 5 subscribers") {
+		t.Fatalf("verify output = %q", stdout.String())
+	}
+	v.in = f.in
+	if err := run(ctx, v, &stdout); err == nil {
+		t.Fatalf("verify accepted the unrotated file")
+	}
+
+	fin := flags{finish: true, in: f.out, out: f.out, workers: 1}
+	stdout.Reset()
+	if err := run(ctx, fin, &stdout); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	finished, err := store.ReadFile(f.out)
+	if err != nil || len(finished) != 5 || finished[0].PreviousTOPc != nil {
+		t.Fatalf("finished file = %d subscribers, %v; previous TOPc kept", len(finished), err)
+	}
+}
+
+func TestRotateFailures(t *testing.T) {
+	f, _ := setup(t, 3)
+	f.oldTOPFile = f.newTOPFile
+	f.dryRun = true
+	err := run(context.Background(), f, &bytes.Buffer{})
+	if !errors.Is(err, errFailures) {
+		t.Fatalf("err = %v, want errFailures", err)
+	}
+	if rep := readReport(t, f.report); rep.Failed != 3 {
+		t.Fatalf("Failed = %d, want 3", rep.Failed)
+	}
+	if _, err := os.Stat(f.out); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote %s", f.out)
+	}
+}
+
+func TestRotateSQLite(t *testing.T) {
+	ctx := context.Background()
+	f, subs := setup(t, 3)
+	db := filepath.Join(t.TempDir(), "subscribers.db")
+	s, err := sqlite.Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range subs {
+		if err := s.Put(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	f.in, f.out, f.sqlite = "", "", db
+	if err := run(ctx, f, &bytes.Buffer{}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	v := flags{verify: f.report, newTOPFile: f.newTOPFile, sqlite: db, workers: 2}
+	if err := run(ctx, v, &bytes.Buffer{}); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	// A second run finds everything rotated already.
+	if err := run(ctx, f, &bytes.Buffer{}); err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+	if rep := readReport(t, f.report); rep.Rotated != 0 || rep.Unchanged != 3 {
+		t.Fatalf("rerun counts = %d/%d, want 0/3", rep.Rotated, rep.Unchanged)
+	}
+}
+
+func TestFlagErrors(t *testing.T) {
+	for _, f := range []flags{
+		{workers: 1},
+		{in: "a.json", sqlite: "b.db", workers: 1},
+		{in: "a.json", workers: 1},
+	} {
+		if err := run(context.Background(), f, &bytes.Buffer{}); err == nil {
+			t.Errorf("run(%+v) succeeded", f)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Package config loads and stores TUAK deployment profiles as JSON, YAML
+// or TOML documents.
+//
+// A document is either a single profile or a list under "profiles":
+//
+//	name = "operator-a"
+//	maclength = 64
+//	reslength = 64
+//	cklength = 128
+//	iklength = 128
+//	keccak_iterations = 1
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"tuak"
+)
+
+// Format is a document encoding.
+type Format string
+
+// Supported formats.
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// FormatOf returns the format implied by the file extension of path.
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("config: unknown profile file format %q", path)
+	}
+}
+
+type document struct {
+	tuak.Profile `yaml:",inline"`
+	Profiles     []tuak.Profile `json:"profiles,omitempty" yaml:"profiles,omitempty" toml:"profiles,omitempty"`
+}
+
+// Load reads and validates the profiles in the file at path.
+func Load(path string) ([]tuak.Profile, error) {
+	f, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	ps, err := Decode(bytes.NewReader(b), f)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, path)
+	}
+	return ps, nil
+}
+
+// Register loads the file at path and registers every profile in it.
+func Register(path string) error {
+	ps, err := Load(path)
+	if err != nil {
+		return err
+	}
+	for _, p := range ps {
+		if err := tuak.RegisterProfile(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode reads and validates the profiles of one document. Unknown fields
+// are rejected so that misspelt lengths do not silently default.
+func Decode(r io.Reader, f Format) ([]tuak.Profile, error) {
+	var doc document
+	switch f {
+	case FormatJSON:
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("config: decode json: %w", err)
+		}
+	case FormatYAML:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("config: decode yaml: %w", err)
+		}
+	case FormatTOML:
+		md, err := toml.NewDecoder(r).Decode(&doc)
+		if err != nil {
+			return nil, fmt.Errorf("config: decode toml: %w", err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("config: decode toml: unknown field %q", undecoded[0].String())
+		}
+	default:
+		return nil, fmt.Errorf("config: unknown format %q", f)
+	}
+
+	ps := doc.Profiles
+	switch {
+	case len(ps) > 0 && doc.Profile != (tuak.Profile{}):
+		return nil, fmt.Errorf("config: document mixes a profile with a profiles list")
+	case len(ps) == 0:
+		ps = []tuak.Profile{doc.Profile}
+	}
+	for _, p := range ps {
+		if p.Name == "" {
+			return nil, fmt.Errorf("config: profile without name")
+		}
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+// Encode writes ps as one document: a single profile on its own, several
+// as a profiles list.
+func Encode(w io.Writer, f Format, ps ...tuak.Profile) error {
+	var v interface{} = struct {
+		Profiles []tuak.Profile `json:"profiles" yaml:"profiles" toml:"profiles"`
+	}{ps}
+	if len(ps) == 1 {
+		v = ps[0]
+	}
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatTOML:
+		return toml.NewEncoder(w).Encode(v)
+	default:
+		return fmt.Errorf("config: unknown format %q", f)
+	}
+}
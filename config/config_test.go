@@ -0,0 +1,98 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tuak"
+)
+
+var operatorA = tuak.Profile{Name: "operator-a", MACLength: 64, RESLength: 64, CKLength: 128, IKLength: 128, KeccakIterations: 1}
+
+var single = map[Format]string{
+	FormatJSON: `{"name": "operator-a", "maclength": 64, "reslength": 64, "cklength": 128, "iklength": 128, "keccak_iterations": 1}`,
+	FormatYAML: "name: operator-a\nmaclength: 64\nreslength: 64\ncklength: 128\niklength: 128\nkeccak_iterations: 1\n",
+	FormatTOML: "name = \"operator-a\"\nmaclength = 64\nreslength = 64\ncklength = 128\niklength = 128\nkeccak_iterations = 1\n",
+}
+
+func TestDecodeSingle(t *testing.T) {
+	for f, doc := range single {
+		ps, err := Decode(strings.NewReader(doc), f)
+		if err != nil {
+			t.Fatalf("%s Decode: %v", f, err)
+		}
+		if len(ps) != 1 || ps[0] != operatorA {
+			t.Fatalf("%s Decode = %+v", f, ps)
+		}
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	for name, c := range map[string]struct {
+		f   Format
+		doc string
+	}{
+		"unknown json field":  {FormatJSON, `{"name": "a", "maclenght": 64}`},
+		"unknown yaml field":  {FormatYAML, "name: a\nmac_length: 64\n"},
+		"unknown toml field":  {FormatTOML, "name = \"a\"\nmac = 64\n"},
+		"invalid MAC length":  {FormatJSON, strings.Replace(single[FormatJSON], `"maclength": 64`, `"maclength": 48`, 1)},
+		"zero iterations":     {FormatYAML, strings.Replace(single[FormatYAML], "keccak_iterations: 1", "keccak_iterations: 0", 1)},
+		"missing name":        {FormatTOML, strings.Replace(single[FormatTOML], `name = "operator-a"`, "", 1)},
+		"profile and list":    {FormatYAML, single[FormatYAML] + "profiles:\n  - name: b\n"},
+		"unsupported format":  {Format("ini"), "name=a"},
+		"malformed document":  {FormatJSON, "{"},
+		"invalid list member": {FormatTOML, "[[profiles]]\nname = \"b\"\nmaclength = 64\n"},
+	} {
+		if _, err := Decode(strings.NewReader(c.doc), c.f); err == nil {
+			t.Fatalf("%s: Decode succeeded", name)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	set6, err := tuak.LookupProfile("ts35233-set6")
+	if err != nil {
+		t.Fatalf("LookupProfile: %v", err)
+	}
+	for _, f := range []Format{FormatJSON, FormatYAML, FormatTOML} {
+		for _, ps := range [][]tuak.Profile{{operatorA}, {operatorA, set6}} {
+			var buf bytes.Buffer
+			if err := Encode(&buf, f, ps...); err != nil {
+				t.Fatalf("%s Encode: %v", f, err)
+			}
+			got, err := Decode(&buf, f)
+			if err != nil {
+				t.Fatalf("%s Decode: %v", f, err)
+			}
+			if !reflect.DeepEqual(got, ps) {
+				t.Fatalf("%s round trip = %+v, want %+v", f, got, ps)
+			}
+		}
+	}
+}
+
+func TestLoadAndRegister(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profiles.yml")
+	doc := "profiles:\n  - name: load-a\n    maclength: 128\n    reslength: 128\n    cklength: 256\n    iklength: 256\n    keccak_iterations: 2\n"
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := Register(path); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	p, err := tuak.LookupProfile("load-a")
+	if err != nil {
+		t.Fatalf("LookupProfile: %v", err)
+	}
+	if p.CKLength != 256 || p.KeccakIterations != 2 {
+		t.Fatalf("registered profile = %+v", p)
+	}
+	if _, err := Load(filepath.Join(dir, "profiles.ini")); err == nil {
+		t.Fatalf("Load accepted unknown extension")
+	}
+}
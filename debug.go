@@ -3,11 +3,18 @@ package tuak
 import (
 	"encoding/hex"
 	"strings"
+
+	"tuak/keccak"
 )
 
 // DebugHook receives labeled copies of intermediate buffers.
 type DebugHook func(label string, data []byte)
 
+// RoundHook receives a copy of the Keccak state after every step of every
+// round. label names the permutation as the DebugHook OUT label does, e.g.
+// "f1.out" or "f2345.out.2".
+type RoundHook func(label string, round int, step keccak.Step, state []byte)
+
 // DebugHexBytes formats bytes as space-separated lowercase hex (e.g. "01 02 03").
 func DebugHexBytes(data []byte) string {
 	if len(data) == 0 {
@@ -0,0 +1,177 @@
+package tuak
+
+import (
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"tuak/internal/textfmt"
+)
+
+// Hex is a byte string whose JSON and text forms are lowercase hex.
+type Hex []byte
+
+// String returns h in lowercase hex.
+func (h Hex) String() string {
+	return hex.EncodeToString(h)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (h Hex) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *Hex) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("tuak: decode hex: %w", err)
+	}
+	*h = b
+	return nil
+}
+
+// F2345Result holds the f2-f5 outputs under the JSON names of
+// testvectors.TUAKVector.
+type F2345Result struct {
+	RES Hex `json:"f2"`
+	CK  Hex `json:"f3"`
+	IK  Hex `json:"f4"`
+	AK  Hex `json:"f5"`
+}
+
+// F2345Result runs F2345 and returns its outputs as one value.
+func (t *TUAK) F2345Result() (*F2345Result, error) {
+	res, ck, ik, ak, err := t.F2345()
+	if err != nil {
+		return nil, err
+	}
+	return &F2345Result{RES: res, CK: ck, IK: ik, AK: ak}, nil
+}
+
+// SecretFields implements Redactor.
+func (F2345Result) SecretFields() []string {
+	return []string{"f2", "f3", "f4", "f5"}
+}
+
+// MarshalJSON implements json.Marshaler. Without it encoding/json would
+// use MarshalText and encode r as a string rather than an object.
+func (r F2345Result) MarshalJSON() ([]byte, error) {
+	type plain F2345Result
+	return json.Marshal(plain(r))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *F2345Result) UnmarshalJSON(b []byte) error {
+	type plain F2345Result
+	return json.Unmarshal(b, (*plain)(r))
+}
+
+// MarshalText implements encoding.TextMarshaler ("f2=...,f3=...").
+func (r F2345Result) MarshalText() ([]byte, error) {
+	return textfmt.Marshal(&r)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *F2345Result) UnmarshalText(text []byte) error {
+	*r = F2345Result{}
+	return textfmt.Unmarshal(text, r)
+}
+
+// MarshalJSON implements json.Marshaler, taking precedence over
+// MarshalText as for F2345Result. The hooks, key provider, tracer and
+// experimental rounds are not encoded.
+func (o Options) MarshalJSON() ([]byte, error) {
+	type plain Options
+	return json.Marshal(plain(o))
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It sets the length fields
+// and keeps the fields that are not encoded; unknown JSON fields are
+// ignored so that test vector entries decode directly.
+func (o *Options) UnmarshalJSON(b []byte) error {
+	type plain Options
+	p := plain(o.unencoded())
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*o = Options(p)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+// ("klength=128,maclength=64,...").
+func (o Options) MarshalText() ([]byte, error) {
+	return textfmt.Marshal(&o)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Like UnmarshalJSON
+// it keeps the fields that are not encoded.
+func (o *Options) UnmarshalText(text []byte) error {
+	p := o.unencoded()
+	if err := textfmt.Unmarshal(text, &p); err != nil {
+		return err
+	}
+	*o = p
+	return nil
+}
+
+// unencoded returns a copy of o with the encoded length fields cleared, so
+// that decoding leaves the fields it does not mention at zero.
+func (o Options) unencoded() Options {
+	o.KLength, o.MACLength, o.RESLength = 0, 0, 0
+	o.CKLength, o.IKLength, o.KeccakIterations = 0, 0, 0
+	return o
+}
+
+// Redactor is implemented by value types whose JSON or text form carries
+// secrets: long-term keys, derived keys and expected responses. RAND,
+// SQN, AMF and AUTN are not secret.
+type Redactor interface {
+	// SecretFields returns the JSON names of the secret fields.
+	SecretFields() []string
+}
+
+// Redacted wraps a value so that its JSON and text forms show "redacted"
+// in place of every non-empty secret field.
+type Redacted struct {
+	v Redactor
+}
+
+// Redact returns v wrapped for logging.
+func Redact(v Redactor) Redacted {
+	return Redacted{v: v}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Redacted) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(r.v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for _, name := range r.v.SecretFields() {
+		if v, ok := fields[name]; ok && string(v) != `""` && string(v) != "null" {
+			fields[name] = json.RawMessage(`"` + textfmt.Redacted + `"`)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// MarshalText implements encoding.TextMarshaler; the wrapped value must
+// implement it too.
+func (r Redacted) MarshalText() ([]byte, error) {
+	m, ok := r.v.(encoding.TextMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("tuak: %T has no text form", r.v)
+	}
+	b, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return textfmt.Redact(b, r.v.SecretFields())
+}
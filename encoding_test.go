@@ -0,0 +1,113 @@
+package tuak
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecodeVectorFileIntoTypes(t *testing.T) {
+	b, err := os.ReadFile("testvectors/testdata/ts35233_vectors.json")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var file struct {
+		Tests []json.RawMessage `json:"tests"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for i, raw := range file.Tests {
+		var in struct {
+			K    Hex `json:"k"`
+			Rand Hex `json:"rand"`
+			Topc Hex `json:"topc"`
+		}
+		var o Options
+		var want F2345Result
+		for _, v := range []interface{}{&in, &o, &want} {
+			if err := json.Unmarshal(raw, v); err != nil {
+				t.Fatalf("entry %d: Unmarshal %T: %v", i, v, err)
+			}
+		}
+		tk, err := NewWithTOPc(in.K, in.Topc, in.Rand, nil, nil, WithOptions(o))
+		if err != nil {
+			t.Fatalf("NewWithTOPc: %v", err)
+		}
+		got, err := tk.F2345Result()
+		if err != nil {
+			t.Fatalf("entry %d F2345Result: %v", i, err)
+		}
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Fatalf("entry %d: %s, want %s", i, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestOptionsEncoding(t *testing.T) {
+	hook := func(string, []byte) {}
+	o := Options{KLength: 256, MACLength: 128, RESLength: 64, CKLength: 128, IKLength: 256, KeccakIterations: 2, DebugHook: hook}
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `{"klength":256,"maclength":128,"reslength":64,"cklength":128,"iklength":256,"keccak_iterations":2}`
+	if string(b) != want {
+		t.Fatalf("JSON = %s, want %s", b, want)
+	}
+	tracer := &Tracer{redaction: RedactSecrets}
+	got := Options{MACLength: 64, DebugHook: hook, Tracer: tracer, ExperimentalKeccakRounds: 12}
+	if err := json.Unmarshal([]byte(`{"iklength":256,"keccak_iterations":2}`), &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got.DebugHook == nil || got.Tracer != tracer || got.ExperimentalKeccakRounds != 12 ||
+		got.MACLength != 0 || got.IKLength != 256 || got.KeccakIterations != 2 {
+		t.Fatalf("Unmarshal = %+v", got)
+	}
+
+	text, err := o.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: %v", err)
+	}
+	if string(text) != "klength=256,maclength=128,reslength=64,cklength=128,iklength=256,keccak_iterations=2" {
+		t.Fatalf("text = %s", text)
+	}
+	back := Options{Tracer: tracer, ExperimentalKeccakRounds: 12}
+	if err := back.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText: %v", err)
+	}
+	if back.MACLength != 128 || back.KeccakIterations != 2 || back.Tracer != tracer || back.ExperimentalKeccakRounds != 12 {
+		t.Fatalf("UnmarshalText = %+v", back)
+	}
+	if err := back.UnmarshalText([]byte("maclength=64,macs=1")); err == nil {
+		t.Fatalf("UnmarshalText accepted an unknown field")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	r := F2345Result{RES: Hex{0x01}, CK: Hex{0x02}, IK: Hex{0x03}}
+	text, err := r.MarshalText()
+	if err != nil || string(text) != "f2=01,f3=02,f4=03" {
+		t.Fatalf("MarshalText = %s, %v", text, err)
+	}
+	var back F2345Result
+	if err := back.UnmarshalText(text); err != nil || !bytes.Equal(back.IK, r.IK) {
+		t.Fatalf("UnmarshalText = %+v, %v", back, err)
+	}
+
+	redacted, err := Redact(r).MarshalText()
+	if err != nil || string(redacted) != "f2=redacted,f3=redacted,f4=redacted" {
+		t.Fatalf("redacted text = %s, %v", redacted, err)
+	}
+	b, err := json.Marshal(Redact(r))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if strings.Contains(string(b), "02") || !strings.Contains(string(b), `"f3":"redacted"`) || !strings.Contains(string(b), `"f5":""`) {
+		t.Fatalf("redacted JSON = %s", b)
+	}
+}
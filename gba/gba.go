@@ -0,0 +1,104 @@
+// Package gba implements the GBA bootstrapping key derivations of TS 33.220
+// (Ks, B-TID, Ks_NAF, Ks_ext_NAF and Ks_int_NAF) on TUAK outputs.
+package gba
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+
+	"tuak"
+	"tuak/kdf"
+)
+
+// FCNAF is the FC value of the NAF key derivation (TS 33.220 B.3).
+const FCNAF = 0x01
+
+// Derivation labels (static string P0 of TS 33.220 B.3).
+const (
+	labelME = "gba-me"
+	labelU  = "gba-u"
+)
+
+// Bootstrap is the state shared by UE and BSF after a bootstrapping run.
+type Bootstrap struct {
+	RAND []byte
+	// Ks is CK || IK.
+	Ks   []byte
+	IMPI string
+	BTID string
+}
+
+// New builds the bootstrapping state from RAND, CK and IK.
+func New(rand, ck, ik []byte, impi, bsfDomain string) *Bootstrap {
+	ks := make([]byte, 0, len(ck)+len(ik))
+	ks = append(ks, ck...)
+	ks = append(ks, ik...)
+	return &Bootstrap{
+		RAND: rand,
+		Ks:   ks,
+		IMPI: impi,
+		BTID: BTID(rand, bsfDomain),
+	}
+}
+
+// FromTUAK runs f2345 for the RAND of t and builds the bootstrapping state.
+func FromTUAK(t *tuak.TUAK, rand []byte, impi, bsfDomain string) (*Bootstrap, error) {
+	_, ck, ik, _, err := t.F2345()
+	if err != nil {
+		return nil, err
+	}
+	return New(rand, ck, ik, impi, bsfDomain), nil
+}
+
+// BTID returns base64encode(RAND)@BSF_servers_domain_name (TS 33.220 4.5.2).
+func BTID(rand []byte, bsfDomain string) string {
+	return base64.StdEncoding.EncodeToString(rand) + "@" + bsfDomain
+}
+
+// NAFID returns NAF_Id = FQDN of the NAF || Ua security protocol identifier.
+func NAFID(fqdn string, uaProtocol []byte) []byte {
+	out := make([]byte, 0, len(fqdn)+len(uaProtocol))
+	out = append(out, fqdn...)
+	return append(out, uaProtocol...)
+}
+
+// KsNAF derives the GBA_ME key Ks_NAF = KDF(Ks, "gba-me", RAND, IMPI, NAF_Id).
+func (b *Bootstrap) KsNAF(nafID []byte) []byte {
+	return DeriveNAF(b.Ks, labelME, b.RAND, b.IMPI, nafID)
+}
+
+// KsExtNAF derives the GBA_U key Ks_ext_NAF, which equals the GBA_ME Ks_NAF.
+func (b *Bootstrap) KsExtNAF(nafID []byte) []byte {
+	return DeriveNAF(b.Ks, labelME, b.RAND, b.IMPI, nafID)
+}
+
+// KsIntNAF derives the GBA_U key Ks_int_NAF = KDF(Ks, "gba-u", RAND, IMPI, NAF_Id),
+// which stays on the UICC.
+func (b *Bootstrap) KsIntNAF(nafID []byte) []byte {
+	return DeriveNAF(b.Ks, labelU, b.RAND, b.IMPI, nafID)
+}
+
+// DeriveNAF is the NAF key derivation of TS 33.220 B.3 with the given label.
+func DeriveNAF(ks []byte, label string, rand []byte, impi string, nafID []byte) []byte {
+	return kdf.Derive(ks, FCNAF, []byte(label), rand, []byte(impi), nafID)
+}
+
+// AUTNStar converts between AUTN and the GBA_U AUTN* by replacing MAC with
+// MAC* = MAC xor Trunc(SHA-1(IK)) (TS 33.220 5.3.2). Applying it twice
+// yields the original AUTN.
+func AUTNStar(autn, ik []byte) ([]byte, error) {
+	if len(autn) < 8 {
+		return nil, fmt.Errorf("gba: AUTN length %d bytes", len(autn))
+	}
+	mac := autn[8:]
+	digest := sha1.Sum(ik)
+	if len(mac) > len(digest) {
+		return nil, fmt.Errorf("gba: MAC length %d bits exceeds SHA-1 output", len(mac)*8)
+	}
+	out := append([]byte(nil), autn...)
+	for i := range mac {
+		out[8+i] ^= digest[i]
+	}
+	return out, nil
+}
@@ -0,0 +1,104 @@
+package gba
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"tuak"
+	"tuak/testvectors"
+)
+
+func TestBTID(t *testing.T) {
+	rand := decodeHex(t, "00112233445566778899aabbccddeeff")
+	got := BTID(rand, "bsf.mnc001.mcc001.pub.3gppnetwork.org")
+	want := "ABEiM0RVZneImaq7zN3u/w==@bsf.mnc001.mcc001.pub.3gppnetwork.org"
+	if got != want {
+		t.Fatalf("B-TID = %q, want %q", got, want)
+	}
+}
+
+// The NAF key and AUTN* vectors below were computed from the TS 33.220
+// Annex B and 5.3.2 definitions with Python's hmac and hashlib modules, so
+// that they do not depend on this package's KDF.
+
+func TestNAFKeys(t *testing.T) {
+	ck := bytes.Repeat([]byte{0x11}, 16)
+	ik := bytes.Repeat([]byte{0x22}, 16)
+	rand := bytes.Repeat([]byte{0x33}, 16)
+	impi := "001010000000001@ims.mnc001.mcc001.3gppnetwork.org"
+	nafID := NAFID("naf.example.org", []byte{0x01, 0x00, 0x00, 0x00, 0x02})
+	b := New(rand, ck, ik, impi, "bsf.example.org")
+
+	if !bytes.Equal(b.Ks, append(append([]byte(nil), ck...), ik...)) {
+		t.Fatalf("Ks is not CK || IK")
+	}
+	want := decodeHex(t, "697f84a02b4a74c73b43686318c5a36b1d13708ec097c280e9418aa192a83a19")
+	if got := b.KsNAF(nafID); !bytes.Equal(got, want) {
+		t.Fatalf("Ks_NAF = %x, want %x", got, want)
+	}
+	if got := b.KsExtNAF(nafID); !bytes.Equal(got, want) {
+		t.Fatalf("Ks_ext_NAF = %x, want %x", got, want)
+	}
+	wantInt := decodeHex(t, "c374ac1bbfa5c72073721d00e60712f896be040580172a224b11d489e174b248")
+	if got := b.KsIntNAF(nafID); !bytes.Equal(got, wantInt) {
+		t.Fatalf("Ks_int_NAF = %x, want %x", got, wantInt)
+	}
+}
+
+func TestFromTUAK(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	v := data.Tests[0]
+	rand := decodeHex(t, v.Rand)
+	tk, err := tuak.NewWithTOPc(decodeHex(t, v.K), decodeHex(t, v.Topc), rand, nil, nil,
+		tuak.WithRESLength(v.RESLength),
+		tuak.WithCKLength(v.CKlength),
+		tuak.WithIKLength(v.IKlength),
+		tuak.WithKeccakIterations(v.KeccakIterations),
+	)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	b, err := FromTUAK(tk, rand, "impi@example.org", "bsf.example.org")
+	if err != nil {
+		t.Fatalf("FromTUAK: %v", err)
+	}
+	want := append(decodeHex(t, v.F3), decodeHex(t, v.F4)...)
+	if !bytes.Equal(b.Ks, want) {
+		t.Fatalf("Ks = %x, want %x", b.Ks, want)
+	}
+}
+
+func TestAUTNStar(t *testing.T) {
+	autn := decodeHex(t, "0102030405068000a1a2a3a4a5a6a7a8")
+	ik := bytes.Repeat([]byte{0x22}, 16)
+	star, err := AUTNStar(autn, ik)
+	if err != nil {
+		t.Fatalf("AUTNStar: %v", err)
+	}
+	if want := decodeHex(t, "01020304050680009dfc061ee9b87d04"); !bytes.Equal(star, want) {
+		t.Fatalf("AUTN* = %x, want %x", star, want)
+	}
+	back, err := AUTNStar(star, ik)
+	if err != nil {
+		t.Fatalf("AUTNStar: %v", err)
+	}
+	if !bytes.Equal(back, autn) {
+		t.Fatalf("AUTNStar is not an involution: %x", back)
+	}
+	if _, err := AUTNStar(make([]byte, 8+32), ik); err == nil {
+		t.Fatalf("AUTNStar accepted 256-bit MAC")
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
@@ -0,0 +1,72 @@
+package tuak
+
+import "fmt"
+
+// C2 converts RES (XRES) into SRES (TS 33.102 6.8.1.2). RES is zero-padded
+// to a multiple of 32 bits and its 32-bit words are XORed, so a 32-bit RES
+// is returned unchanged. RES up to 256 bits (WithRESLength) is accepted.
+func C2(res []byte) ([]byte, error) {
+	if len(res) == 0 || len(res) > 32 {
+		return nil, fmt.Errorf("tuak: invalid RES length %d bytes", len(res))
+	}
+	sres := make([]byte, 4)
+	for i, b := range res {
+		sres[i%4] ^= b
+	}
+	return sres, nil
+}
+
+// C3 converts CK and IK into Kc = CK1 xor CK2 xor IK1 xor IK2 (TS 33.102
+// 6.8.1.2). Only 128-bit CK and IK are defined for the conversion.
+func C3(ck, ik []byte) ([]byte, error) {
+	if len(ck) != 16 {
+		return nil, fmt.Errorf("tuak: c3 requires 128-bit CK, got %d bits", len(ck)*8)
+	}
+	if len(ik) != 16 {
+		return nil, fmt.Errorf("tuak: c3 requires 128-bit IK, got %d bits", len(ik)*8)
+	}
+	kc := make([]byte, 8)
+	for i := 0; i < 8; i++ {
+		kc[i] = ck[i] ^ ck[i+8] ^ ik[i] ^ ik[i+8]
+	}
+	return kc, nil
+}
+
+// C4 converts Kc into CK = Kc || Kc.
+func C4(kc []byte) ([]byte, error) {
+	if len(kc) != 8 {
+		return nil, fmt.Errorf("tuak: invalid Kc length %d bytes", len(kc))
+	}
+	return append(append([]byte(nil), kc...), kc...), nil
+}
+
+// C5 converts Kc into IK = (Kc1 xor Kc2) || Kc || (Kc1 xor Kc2), where Kc1
+// and Kc2 are the 32-bit halves of Kc.
+func C5(kc []byte) ([]byte, error) {
+	if len(kc) != 8 {
+		return nil, fmt.Errorf("tuak: invalid Kc length %d bytes", len(kc))
+	}
+	x := make([]byte, 4)
+	for i := range x {
+		x[i] = kc[i] ^ kc[i+4]
+	}
+	ik := make([]byte, 0, 16)
+	ik = append(ik, x...)
+	ik = append(ik, kc...)
+	return append(ik, x...), nil
+}
+
+// GSM computes SRES and Kc for a GSM context from f2-f4 via c2 and c3.
+func (t *TUAK) GSM() (sres, kc []byte, err error) {
+	res, ck, ik, _, err := t.F2345()
+	if err != nil {
+		return nil, nil, err
+	}
+	if sres, err = C2(res); err != nil {
+		return nil, nil, err
+	}
+	if kc, err = C3(ck, ik); err != nil {
+		return nil, nil, err
+	}
+	return sres, kc, nil
+}
@@ -0,0 +1,82 @@
+package tuak
+
+import (
+	"bytes"
+	"testing"
+
+	"tuak/testvectors"
+)
+
+func TestC2(t *testing.T) {
+	cases := []struct {
+		res  string
+		want string
+	}{
+		{"657acd64", "657acd64"},
+		{"e9d749dc4eea0035", "a73d49e9"},
+		{"0102030405", "04020304"},
+	}
+	for _, c := range cases {
+		got, err := C2(decodeHex(t, c.res))
+		if err != nil {
+			t.Fatalf("C2(%s): %v", c.res, err)
+		}
+		if !bytes.Equal(got, decodeHex(t, c.want)) {
+			t.Fatalf("C2(%s) = %x, want %s", c.res, got, c.want)
+		}
+	}
+}
+
+func TestC3C4C5(t *testing.T) {
+	kc := decodeHex(t, "0011223344556677")
+	ck, err := C4(kc)
+	if err != nil {
+		t.Fatalf("C4: %v", err)
+	}
+	if !bytes.Equal(ck, decodeHex(t, "00112233445566770011223344556677")) {
+		t.Fatalf("C4 = %x", ck)
+	}
+	ik, err := C5(kc)
+	if err != nil {
+		t.Fatalf("C5: %v", err)
+	}
+	if !bytes.Equal(ik, decodeHex(t, "44444444001122334455667744444444")) {
+		t.Fatalf("C5 = %x", ik)
+	}
+	// c3(c4(Kc), c5(Kc)) = Kc xor Kc xor (Kc1^Kc2 || Kc1) xor (Kc2 || Kc1^Kc2) = Kc.
+	got, err := C3(ck, ik)
+	if err != nil {
+		t.Fatalf("C3: %v", err)
+	}
+	if !bytes.Equal(got, kc) {
+		t.Fatalf("C3(C4, C5) = %x, want %x", got, kc)
+	}
+	if _, err := C3(make([]byte, 32), make([]byte, 16)); err == nil {
+		t.Fatalf("C3 accepted 256-bit CK")
+	}
+}
+
+func TestGSMVectors(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		if v.CKlength != 128 || v.IKlength != 128 {
+			continue
+		}
+		tuak, err := newTUAKFromVector(t, v)
+		if err != nil {
+			t.Fatalf("NewWithTOPc: %v", err)
+		}
+		sres, kc, err := tuak.GSM()
+		if err != nil {
+			t.Fatalf("vector %d GSM: %v", v.ID, err)
+		}
+		wantSRES, _ := C2(decodeHex(t, v.F2))
+		wantKc, _ := C3(decodeHex(t, v.F3), decodeHex(t, v.F4))
+		if !bytes.Equal(sres, wantSRES) || !bytes.Equal(kc, wantKc) {
+			t.Fatalf("vector %d SRES/Kc mismatch", v.ID)
+		}
+	}
+}
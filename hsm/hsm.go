@@ -0,0 +1,250 @@
+//go:build cgo
+
+// Package hsm implements tuak.KeyProvider on a PKCS#11 token. K and TOPc
+// are stored wrapped under a key encryption key (KEK) that never leaves the
+// token; the token unwraps them into process memory only for the duration
+// of one TUAK computation.
+//
+// Deriving TOPc inside the token is out of scope: PKCS#11 defines no
+// mechanism for the TUAK Keccak computation, so when no wrapped TOPc is
+// stored it is derived from TOP in process memory alongside the unwrapped
+// K. Deployments that must keep TOPc off the host should provision it
+// wrapped.
+package hsm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+
+	"tuak"
+	"tuak/keywrap"
+	"tuak/store"
+)
+
+// Config selects the token and KEK.
+type Config struct {
+	// Module is the path of the PKCS#11 library, e.g. libsofthsm2.so.
+	Module     string
+	TokenLabel string
+	PIN        string
+	// KEKLabel is the CKA_LABEL of the AES key used to wrap K and TOPc.
+	KEKLabel string
+	// Scheme selects the wrapping mechanism; aes-kw when empty.
+	Scheme keywrap.Scheme
+}
+
+// Token is an open session on a PKCS#11 token. It is safe for concurrent use.
+type Token struct {
+	mu      sync.Mutex
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	kek     pkcs11.ObjectHandle
+	mech    []*pkcs11.Mechanism
+	scheme  keywrap.Scheme
+}
+
+// Open loads the module, logs in to the token and locates the KEK.
+func Open(cfg Config) (*Token, error) {
+	ctx := pkcs11.New(cfg.Module)
+	if ctx == nil {
+		return nil, fmt.Errorf("hsm: cannot load module %s", cfg.Module)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("hsm: initialize: %w", err)
+	}
+	t := &Token{ctx: ctx}
+	if err := t.open(cfg); err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Token) open(cfg Config) error {
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = keywrap.SchemeAESKW
+	}
+	mech, err := mechanism(scheme)
+	if err != nil {
+		return err
+	}
+	t.mech = mech
+	t.scheme = scheme
+
+	slot, err := findSlot(t.ctx, cfg.TokenLabel)
+	if err != nil {
+		return err
+	}
+	t.session, err = t.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return fmt.Errorf("hsm: open session: %w", err)
+	}
+	if err := t.ctx.Login(t.session, pkcs11.CKU_USER, cfg.PIN); err != nil {
+		return fmt.Errorf("hsm: login: %w", err)
+	}
+	t.kek, err = t.findKey(cfg.KEKLabel)
+	return err
+}
+
+// Close logs out and unloads the module.
+func (t *Token) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ctx.Logout(t.session)
+	t.ctx.CloseSession(t.session)
+	err := t.ctx.Finalize()
+	t.ctx.Destroy()
+	return err
+}
+
+// Scheme returns the wrapping scheme used by the token.
+func (t *Token) Scheme() keywrap.Scheme {
+	return t.scheme
+}
+
+// Wrap imports key as a temporary session object and returns it wrapped
+// under the KEK, for personalisation and tests.
+func (t *Token) Wrap(key []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	obj, err := t.ctx.CreateObject(t.session, secretTemplate(key))
+	if err != nil {
+		return nil, fmt.Errorf("hsm: import key: %w", err)
+	}
+	defer t.ctx.DestroyObject(t.session, obj)
+	wrapped, err := t.ctx.WrapKey(t.session, t.mech, t.kek, obj)
+	if err != nil {
+		return nil, fmt.Errorf("hsm: wrap: %w", err)
+	}
+	return wrapped, nil
+}
+
+// Provider returns a tuak.KeyProvider for K and TOPc wrapped under the
+// KEK. wrappedTOPc may be nil, in which case TOPc is derived from TOP.
+func (t *Token) Provider(wrappedK, wrappedTOPc []byte) tuak.KeyProvider {
+	return &provider{token: t, wrappedK: wrappedK, wrappedTOPc: wrappedTOPc}
+}
+
+// SubscriberProvider returns a function building providers for stored
+// subscribers, for use with udm.WithKeyProvider.
+func (t *Token) SubscriberProvider() func(sub *store.Subscriber) (tuak.KeyProvider, error) {
+	return func(sub *store.Subscriber) (tuak.KeyProvider, error) {
+		if keywrap.Scheme(sub.KeyWrap) != t.scheme {
+			return nil, fmt.Errorf("hsm: %s: key wrap %q does not match token scheme %q", sub.ID, sub.KeyWrap, t.scheme)
+		}
+		return t.Provider(sub.K, sub.TOPc), nil
+	}
+}
+
+type provider struct {
+	token       *Token
+	wrappedK    []byte
+	wrappedTOPc []byte
+}
+
+// UseKeys implements tuak.KeyProvider.
+func (p *provider) UseKeys(fn func(k, topc []byte) error) error {
+	k, err := p.token.unwrap(p.wrappedK)
+	if err != nil {
+		return err
+	}
+	defer clear(k)
+	var topc []byte
+	if p.wrappedTOPc != nil {
+		topc, err = p.token.unwrap(p.wrappedTOPc)
+		if err != nil {
+			return err
+		}
+		defer clear(topc)
+	}
+	return fn(k, topc)
+}
+
+// unwrap lets the token unwrap into a session object, reads its value and
+// destroys the object again.
+func (t *Token) unwrap(wrapped []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	obj, err := t.ctx.UnwrapKey(t.session, t.mech, t.kek, wrapped, secretTemplate(nil))
+	if err != nil {
+		return nil, fmt.Errorf("hsm: unwrap: %w", err)
+	}
+	defer t.ctx.DestroyObject(t.session, obj)
+	attrs, err := t.ctx.GetAttributeValue(t.session, obj, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hsm: read unwrapped key: %w", err)
+	}
+	return attrs[0].Value, nil
+}
+
+func (t *Token) findKey(label string) (pkcs11.ObjectHandle, error) {
+	tmpl := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := t.ctx.FindObjectsInit(t.session, tmpl); err != nil {
+		return 0, fmt.Errorf("hsm: find KEK: %w", err)
+	}
+	objs, _, err := t.ctx.FindObjects(t.session, 1)
+	t.ctx.FindObjectsFinal(t.session)
+	if err != nil {
+		return 0, fmt.Errorf("hsm: find KEK: %w", err)
+	}
+	if len(objs) == 0 {
+		return 0, fmt.Errorf("hsm: KEK %q not found", label)
+	}
+	return objs[0], nil
+}
+
+func findSlot(ctx *pkcs11.Ctx, label string) (uint, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("hsm: list slots: %w", err)
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			continue
+		}
+		if info.Label == label {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("hsm: token %q not found", label)
+}
+
+// secretTemplate describes an extractable, non-persistent generic secret.
+// A nil value is used as the unwrap template.
+func secretTemplate(value []byte) []*pkcs11.Attribute {
+	tmpl := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_GENERIC_SECRET),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+	}
+	if value != nil {
+		tmpl = append(tmpl, pkcs11.NewAttribute(pkcs11.CKA_VALUE, value))
+	}
+	return tmpl
+}
+
+func mechanism(s keywrap.Scheme) ([]*pkcs11.Mechanism, error) {
+	switch s {
+	case keywrap.SchemeAESKW:
+		return []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_WRAP, nil)}, nil
+	case keywrap.SchemeAESKWP:
+		return []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_WRAP_PAD, nil)}, nil
+	case keywrap.SchemeAESCBC:
+		return []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_CBC, make([]byte, 16))}, nil
+	default:
+		return nil, fmt.Errorf("hsm: unsupported scheme %q", s)
+	}
+}
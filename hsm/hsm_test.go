@@ -0,0 +1,176 @@
+//go:build cgo
+
+package hsm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/miekg/pkcs11"
+
+	"tuak"
+	"tuak/testvectors"
+)
+
+// The tests run against a real token, e.g. SoftHSMv2:
+//
+//	softhsm2-util --init-token --free --label tuak --pin 1234 --so-pin 0000
+//	TUAK_PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so \
+//	TUAK_PKCS11_TOKEN=tuak TUAK_PKCS11_PIN=1234 go test ./hsm
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	cfg := Config{
+		Module:     os.Getenv("TUAK_PKCS11_MODULE"),
+		TokenLabel: os.Getenv("TUAK_PKCS11_TOKEN"),
+		PIN:        os.Getenv("TUAK_PKCS11_PIN"),
+		KEKLabel:   fmt.Sprintf("tuak-test-kek-%d", time.Now().UnixNano()),
+	}
+	if cfg.Module == "" || cfg.TokenLabel == "" {
+		t.Skip("TUAK_PKCS11_MODULE and TUAK_PKCS11_TOKEN not set")
+	}
+	createKEK(t, cfg)
+	return cfg
+}
+
+func TestProviderConformanceVectors(t *testing.T) {
+	cfg := testConfig(t)
+	token, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer token.Close()
+
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		wk, err := token.Wrap(decodeHex(t, v.K))
+		if err != nil {
+			t.Fatalf("Wrap K: %v", err)
+		}
+		wtopc, err := token.Wrap(decodeHex(t, v.Topc))
+		if err != nil {
+			t.Fatalf("Wrap TOPc: %v", err)
+		}
+		if bytes.Contains(wk, decodeHex(t, v.K)) {
+			t.Fatalf("wrapped K contains clear K")
+		}
+
+		opts := []tuak.Option{
+			tuak.WithKeyProvider(token.Provider(wk, wtopc)),
+			tuak.WithMACLength(v.MAClength),
+			tuak.WithRESLength(v.RESLength),
+			tuak.WithCKLength(v.CKlength),
+			tuak.WithIKLength(v.IKlength),
+			tuak.WithKeccakIterations(v.KeccakIterations),
+		}
+		tk, err := tuak.NewWithTOPc(nil, nil, decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF), opts...)
+		if err != nil {
+			t.Fatalf("NewWithTOPc: %v", err)
+		}
+		checkEqual(t, v.ID, "f1", v.F1)(tk.F1())
+		checkEqual(t, v.ID, "f1*", v.F1Star)(tk.F1Star())
+		checkEqual(t, v.ID, "f5*", v.F5Star)(tk.F5Star())
+		res, ck, ik, ak, err := tk.F2345()
+		if err != nil {
+			t.Fatalf("F2345: %v", err)
+		}
+		checkEqual(t, v.ID, "f2", v.F2)(res, nil)
+		checkEqual(t, v.ID, "f3", v.F3)(ck, nil)
+		checkEqual(t, v.ID, "f4", v.F4)(ik, nil)
+		checkEqual(t, v.ID, "f5", v.F5)(ak, nil)
+
+		// TOPc derived from TOP with K unwrapped by the token.
+		tk, err = tuak.New(nil, decodeHex(t, v.Top), decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF),
+			append(opts, tuak.WithKeyProvider(token.Provider(wk, nil)))...)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		checkEqual(t, v.ID, "f1 (TOP)", v.F1)(tk.F1())
+	}
+}
+
+func checkEqual(t *testing.T, id int, name, want string) func([]byte, error) {
+	return func(got []byte, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("vector %d %s: %v", id, name, err)
+		}
+		if !bytes.Equal(got, decodeHex(t, want)) {
+			t.Fatalf("vector %d %s mismatch", id, name)
+		}
+	}
+}
+
+// createKEK stores a fresh AES-256 KEK on the token and removes it when
+// the test ends.
+func createKEK(t *testing.T, cfg Config) {
+	t.Helper()
+	withSession(t, cfg, func(ctx *pkcs11.Ctx, s pkcs11.SessionHandle) {
+		_, err := ctx.GenerateKey(s, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_GEN, nil)},
+			[]*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+				pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+				pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, 32),
+				pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, cfg.KEKLabel),
+				pkcs11.NewAttribute(pkcs11.CKA_WRAP, true),
+				pkcs11.NewAttribute(pkcs11.CKA_UNWRAP, true),
+				pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+			})
+		if err != nil {
+			t.Fatalf("generate KEK: %v", err)
+		}
+	})
+	t.Cleanup(func() {
+		withSession(t, cfg, func(ctx *pkcs11.Ctx, s pkcs11.SessionHandle) {
+			ctx.FindObjectsInit(s, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_LABEL, cfg.KEKLabel)})
+			objs, _, _ := ctx.FindObjects(s, 1)
+			ctx.FindObjectsFinal(s)
+			for _, o := range objs {
+				ctx.DestroyObject(s, o)
+			}
+		})
+	})
+}
+
+func withSession(t *testing.T, cfg Config, fn func(*pkcs11.Ctx, pkcs11.SessionHandle)) {
+	t.Helper()
+	ctx := pkcs11.New(cfg.Module)
+	if ctx == nil {
+		t.Fatalf("cannot load %s", cfg.Module)
+	}
+	defer ctx.Destroy()
+	if err := ctx.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	defer ctx.Finalize()
+	slot, err := findSlot(ctx, cfg.TokenLabel)
+	if err != nil {
+		t.Fatalf("findSlot: %v", err)
+	}
+	s, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		t.Fatalf("OpenSession: %v", err)
+	}
+	defer ctx.CloseSession(s)
+	if err := ctx.Login(s, pkcs11.CKU_USER, cfg.PIN); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	defer ctx.Logout(s)
+	fn(ctx, s)
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
@@ -0,0 +1,275 @@
+package tuak
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Instance is the INSTANCE byte of TS 35.231 6.2 in decoded form: the
+// function and the lengths it selects. Lengths are in bits; those the
+// function does not use are zero.
+type Instance struct {
+	Function  Function
+	MACLength int
+	RESLength int
+	CKLength  int
+	IKLength  int
+	KLength   int
+}
+
+// DecodeInstance decodes an INSTANCE byte.
+func DecodeInstance(b byte) (Instance, error) {
+	bit := func(i int) bool { return b&(1<<(7-i)) != 0 }
+	inst := Instance{KLength: 128}
+	if bit(7) {
+		inst.KLength = 256
+	}
+	code := (b >> 3) & 0x07 // bits 2-4
+	invalid := fmt.Errorf("tuak: invalid INSTANCE %#02x", b)
+	switch {
+	case b&0xFE == 0:
+		inst.Function = FunctionTOPc
+	case !bit(1):
+		inst.Function = FunctionF1
+		if bit(0) {
+			inst.Function = FunctionF1Star
+		}
+		if bit(5) || bit(6) {
+			return Instance{}, invalid
+		}
+		switch code {
+		case 0x1:
+			inst.MACLength = 64
+		case 0x2:
+			inst.MACLength = 128
+		case 0x4:
+			inst.MACLength = 256
+		default:
+			return Instance{}, invalid
+		}
+	case bit(0):
+		inst.Function = FunctionF5Star
+		if b&0x3E != 0 {
+			return Instance{}, invalid
+		}
+	default:
+		inst.Function = FunctionF2345
+		switch code {
+		case 0x0:
+			inst.RESLength = 32
+		case 0x1:
+			inst.RESLength = 64
+		case 0x2:
+			inst.RESLength = 128
+		case 0x4:
+			inst.RESLength = 256
+		default:
+			return Instance{}, invalid
+		}
+		inst.CKLength, inst.IKLength = 128, 128
+		if bit(5) {
+			inst.CKLength = 256
+		}
+		if bit(6) {
+			inst.IKLength = 256
+		}
+	}
+	return inst, nil
+}
+
+// Byte encodes the instance. Lengths not used by the function are ignored.
+func (i Instance) Byte() (byte, error) {
+	if i.KLength != 128 && i.KLength != 256 {
+		return 0, fmt.Errorf("tuak: invalid K length %d bits", i.KLength)
+	}
+	var b byte
+	switch i.Function {
+	case FunctionTOPc:
+	case FunctionF1, FunctionF1Star:
+		b = setInstanceBit(b, 0, i.Function == FunctionF1Star)
+		switch i.MACLength {
+		case 64:
+			b = setInstanceBit(b, 4, true)
+		case 128:
+			b = setInstanceBit(b, 3, true)
+		case 256:
+			b = setInstanceBit(b, 2, true)
+		default:
+			return 0, fmt.Errorf("tuak: invalid MAC length %d bits", i.MACLength)
+		}
+	case FunctionF2345:
+		b = setInstanceBit(b, 1, true)
+		switch i.RESLength {
+		case 32:
+		case 64:
+			b = setInstanceBit(b, 4, true)
+		case 128:
+			b = setInstanceBit(b, 3, true)
+		case 256:
+			b = setInstanceBit(b, 2, true)
+		default:
+			return 0, fmt.Errorf("tuak: invalid RES length %d bits", i.RESLength)
+		}
+		switch i.CKLength {
+		case 128:
+		case 256:
+			b = setInstanceBit(b, 5, true)
+		default:
+			return 0, fmt.Errorf("tuak: invalid CK length %d bits", i.CKLength)
+		}
+		switch i.IKLength {
+		case 128:
+		case 256:
+			b = setInstanceBit(b, 6, true)
+		default:
+			return 0, fmt.Errorf("tuak: invalid IK length %d bits", i.IKLength)
+		}
+	case FunctionF5Star:
+		b = setInstanceBit(b, 0, true)
+		b = setInstanceBit(b, 1, true)
+	default:
+		return 0, fmt.Errorf("tuak: invalid function %v", i.Function)
+	}
+	return setInstanceBit(b, 7, i.KLength == 256), nil
+}
+
+// String describes the function and the lengths it selects, e.g.
+// "f2345 RES=64 CK=128 IK=128 K=256".
+func (i Instance) String() string {
+	parts := []string{i.Function.String()}
+	for _, l := range []struct {
+		name string
+		bits int
+	}{
+		{"MAC", i.MACLength},
+		{"RES", i.RESLength},
+		{"CK", i.CKLength},
+		{"IK", i.IKLength},
+		{"K", i.KLength},
+	} {
+		if l.bits != 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", l.name, l.bits))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Window is a field of the OUT buffer, read back MSB first like the inputs
+// were written. Offset and Length are in bytes.
+type Window struct {
+	Name   string
+	Offset int
+	Length int
+}
+
+// Outputs returns the windows TS 35.231 defines for the instance:
+// TOPc; MAC-A or MAC-S; RES, CK, IK and AK; or AK*.
+func (i Instance) Outputs() []Window {
+	switch i.Function {
+	case FunctionTOPc:
+		return []Window{{"TOPc", offsetTOP, 32}}
+	case FunctionF1:
+		return []Window{{"MAC-A", offsetTOP, i.MACLength / 8}}
+	case FunctionF1Star:
+		return []Window{{"MAC-S", offsetTOP, i.MACLength / 8}}
+	case FunctionF2345:
+		return []Window{
+			{"RES", offsetTOP, i.RESLength / 8},
+			{"CK", 32, i.CKLength / 8},
+			{"IK", 64, i.IKLength / 8},
+			{"AK", 96, 6},
+		}
+	case FunctionF5Star:
+		return []Window{{"AK*", 96, 6}}
+	default:
+		return nil
+	}
+}
+
+// Inputs are the fields of a TUAK IN buffer for Compute. TOP and K are
+// required; RAND, AMF and SQN are left zero when nil.
+type Inputs struct {
+	// TOP is TOP for FunctionTOPc and TOPc otherwise.
+	TOP  []byte
+	RAND []byte
+	AMF  []byte
+	SQN  []byte
+	K    []byte
+	// AlgoName replaces the 7-byte ALGONAME "TUAK1.0" when set.
+	AlgoName []byte
+	// Outputs selects the OUT windows returned; nil selects
+	// Instance.Outputs.
+	Outputs []Window
+}
+
+// Compute builds the IN buffer for inst and in, applies the Keccak
+// permutation (WithKeccakIterations times) and returns the selected
+// windows in order. It is the primitive behind ComputeTOPc and the F*
+// methods; only iteration, round, debug and trace options apply.
+func Compute(inst Instance, in Inputs, opts ...Option) ([][]byte, error) {
+	if err := checkSelfTest(); err != nil {
+		return nil, err
+	}
+	return compute(inst, in, applyOptions(in.K, opts))
+}
+
+func compute(inst Instance, in Inputs, o Options) ([][]byte, error) {
+	b, err := inst.Byte()
+	if err != nil {
+		return nil, err
+	}
+	name := algoName
+	if in.AlgoName != nil {
+		name = in.AlgoName
+	}
+	for _, f := range []struct {
+		name string
+		v    []byte
+		n    int
+	}{
+		{"top", in.TOP, 32},
+		{"algoname", name, len(algoName)},
+		{"rand", in.RAND, 16},
+		{"amf", in.AMF, 2},
+		{"sqn", in.SQN, 6},
+		{"k", in.K, inst.KLength / 8},
+	} {
+		if f.v != nil || f.name == "top" || f.name == "k" {
+			if err := requireLen(f.name, f.v, f.n); err != nil {
+				return nil, err
+			}
+		}
+	}
+	windows := in.Outputs
+	if windows == nil {
+		windows = inst.Outputs()
+	}
+	for _, w := range windows {
+		if w.Offset < 0 || w.Length < 0 || w.Offset+w.Length > inSize {
+			return nil, fmt.Errorf("tuak: output window %q [%d:%d] outside the state", w.Name, w.Offset, w.Offset+w.Length)
+		}
+	}
+
+	state := newState()
+	pushData(state, offsetTOP, in.TOP)
+	state[offsetInst] = b
+	pushData(state, offsetAlgo, name)
+	pushData(state, offsetRAND, in.RAND)
+	pushData(state, offsetAMF, in.AMF)
+	pushData(state, offsetSQN, in.SQN)
+	pushData(state, offsetK, in.K)
+
+	// The tracer decodes the standard windows from the option lengths.
+	o.MACLength, o.RESLength, o.CKLength, o.IKLength = inst.MACLength, inst.RESLength, inst.CKLength, inst.IKLength
+	label := inst.Function.String()
+	callDebug(o, label+".in", state)
+	out, err := permute(state, o, label)
+	if err != nil {
+		return nil, err
+	}
+	results := make([][]byte, len(windows))
+	for i, w := range windows {
+		results[i] = pullData(out, w.Offset, w.Length)
+	}
+	return results, nil
+}
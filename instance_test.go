@@ -0,0 +1,92 @@
+package tuak
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeInstance(t *testing.T) {
+	valid := 0
+	for b := 0; b < 256; b++ {
+		inst, err := DecodeInstance(byte(b))
+		if err != nil {
+			continue
+		}
+		valid++
+		got, err := inst.Byte()
+		if err != nil || got != byte(b) {
+			t.Fatalf("DecodeInstance(%#02x) = %v, Byte() = %#02x, %v", b, inst, got, err)
+		}
+	}
+	// TOPc 2, f1/f1* 2*3*2, f2345 4*2*2*2, f5* 2.
+	if valid != 48 {
+		t.Fatalf("valid INSTANCE values = %d, want 48", valid)
+	}
+	inst, _ := DecodeInstance(0x4D)
+	if got := inst.String(); got != "f2345 RES=64 CK=256 IK=128 K=256" {
+		t.Fatalf("String() = %q", got)
+	}
+	if _, err := (Instance{Function: FunctionF1, MACLength: 32, KLength: 128}).Byte(); err == nil {
+		t.Fatalf("Byte accepted a 32-bit MAC")
+	}
+}
+
+func TestComputeWrapsFunctions(t *testing.T) {
+	v := loadTUAKVector(t, 6)
+	tk, err := newTUAKFromVector(t, v)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	res, ck, ik, ak, err := tk.F2345()
+	if err != nil {
+		t.Fatalf("F2345: %v", err)
+	}
+	inst := Instance{Function: FunctionF2345, RESLength: v.RESLength, CKLength: v.CKlength, IKLength: v.IKlength, KLength: v.Klength}
+	in := Inputs{TOP: decodeHex(t, v.Topc), RAND: decodeHex(t, v.Rand), K: decodeHex(t, v.K)}
+	var last []byte
+	out, err := Compute(inst, in, WithKeccakIterations(v.KeccakIterations), WithDebugHook(func(_ string, data []byte) { last = data }))
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	if !bytes.Equal(bytes.Join(out, nil), bytes.Join([][]byte{res, ck, ik, ak}, nil)) {
+		t.Fatalf("Compute = %x, F2345 = %x %x %x %x", out, res, ck, ik, ak)
+	}
+
+	in.Outputs = []Window{{"OUT", 0, inSize}}
+	out, err = Compute(inst, in, WithKeccakIterations(v.KeccakIterations))
+	if err != nil {
+		t.Fatalf("Compute whole state: %v", err)
+	}
+	if !bytes.Equal(out[0], pullData(last, 0, inSize)) {
+		t.Fatalf("whole-state window differs from the OUT buffer")
+	}
+	in.Outputs = []Window{{"past end", 190, 16}}
+	if _, err := Compute(inst, in); err == nil {
+		t.Fatalf("Compute accepted a window outside the state")
+	}
+}
+
+func TestComputeAlgoName(t *testing.T) {
+	v := loadTUAKVector(t, 1)
+	inst := Instance{Function: FunctionF5Star, KLength: v.Klength}
+	in := Inputs{TOP: decodeHex(t, v.Topc), RAND: decodeHex(t, v.Rand), K: decodeHex(t, v.K), AlgoName: []byte("TUAK1.1")}
+	var state *State
+	out, err := Compute(inst, in, WithDebugHook(func(label string, data []byte) {
+		if label == "f5star.in" {
+			state, _ = DecodeState(data)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	if state == nil || state.AlgoName != "TUAK1.1" {
+		t.Fatalf("IN state = %v", state)
+	}
+	if bytes.Equal(out[0], decodeHex(t, v.F5Star)) {
+		t.Fatalf("ALGONAME override did not change AK*")
+	}
+	in.AlgoName = []byte("TUAK")
+	if _, err := Compute(inst, in); err == nil {
+		t.Fatalf("Compute accepted a 4-byte ALGONAME")
+	}
+}
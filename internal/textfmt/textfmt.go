@@ -0,0 +1,139 @@
+// Package textfmt implements the text form shared by the TUAK value types:
+// comma-separated name=value pairs named after the JSON tags, with byte
+// strings in lowercase hex and integers in decimal.
+package textfmt
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces the value of secret fields.
+const Redacted = "redacted"
+
+// Marshal formats the exported fields of the struct v points to. Empty
+// byte strings and zero integers are omitted.
+func Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v).Elem()
+	var pairs []string
+	for i := 0; i < rv.NumField(); i++ {
+		name, ok := fieldName(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		f := rv.Field(i)
+		switch {
+		case isBytes(f.Type()):
+			if f.Len() > 0 {
+				pairs = append(pairs, name+"="+hex.EncodeToString(f.Bytes()))
+			}
+		case f.Kind() == reflect.Int:
+			if f.Int() != 0 {
+				pairs = append(pairs, name+"="+strconv.FormatInt(f.Int(), 10))
+			}
+		default:
+			return nil, fmt.Errorf("textfmt: unsupported field %s", name)
+		}
+	}
+	return []byte(strings.Join(pairs, ",")), nil
+}
+
+// Unmarshal parses text produced by Marshal into the struct v points to.
+// Unknown names are rejected.
+func Unmarshal(text []byte, v interface{}) error {
+	rv := reflect.ValueOf(v).Elem()
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < rv.NumField(); i++ {
+		if name, ok := fieldName(rv.Type().Field(i)); ok {
+			fields[name] = rv.Field(i)
+		}
+	}
+	pairs, err := Split(text)
+	if err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		f, ok := fields[p[0]]
+		if !ok {
+			return fmt.Errorf("textfmt: unknown field %q", p[0])
+		}
+		switch {
+		case isBytes(f.Type()):
+			b, err := hex.DecodeString(p[1])
+			if err != nil {
+				return fmt.Errorf("textfmt: %s: %w", p[0], err)
+			}
+			f.SetBytes(b)
+		case f.Kind() == reflect.Int:
+			n, err := strconv.Atoi(p[1])
+			if err != nil {
+				return fmt.Errorf("textfmt: %s: %w", p[0], err)
+			}
+			f.SetInt(int64(n))
+		}
+	}
+	return nil
+}
+
+// Split returns the name/value pairs of text.
+func Split(text []byte) ([][2]string, error) {
+	if len(text) == 0 {
+		return nil, nil
+	}
+	var out [][2]string
+	for _, pair := range strings.Split(string(text), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("textfmt: malformed pair %q", pair)
+		}
+		out = append(out, [2]string{name, value})
+	}
+	return out, nil
+}
+
+// Redact replaces the values of the named fields in text with Redacted.
+func Redact(text []byte, secret []string) ([]byte, error) {
+	pairs, err := Split(text)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(pairs))
+	for i, p := range pairs {
+		if contains(secret, p[0]) {
+			p[1] = Redacted
+		}
+		out[i] = p[0] + "=" + p[1]
+	}
+	return []byte(strings.Join(out, ",")), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name, true
+}
+
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
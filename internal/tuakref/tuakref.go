@@ -0,0 +1,285 @@
+// Package tuakref is a deliberately naive TUAK used as a differential
+// testing oracle for package tuak. Its data handling follows the TS 35.231
+// Annex C example code: one 200-byte INOUT buffer filled with explicit index
+// arithmetic (INOUT[offset+n-1-i] = data[i]) and literal INSTANCE constants.
+// The permutation is not the Annex C Keccak routine but a FIPS 202 oracle:
+// Keccak-f[1600] evaluated bit by bit from the step mappings of FIPS 202 3.2.
+// It shares no code or tables with packages tuak and keccak and performs
+// no input validation; callers pass well-formed inputs.
+package tuakref
+
+// INSTANCE values of TS 35.231 6.2, bit 0 being the most significant.
+const (
+	instF1     = 0x00
+	instF1Star = 0x80
+	instF2345  = 0x40
+	instF5Star = 0xC0
+
+	instMAC64  = 0x08
+	instMAC128 = 0x10
+	instMAC256 = 0x20
+
+	instRES32  = 0x00
+	instRES64  = 0x08
+	instRES128 = 0x10
+	instRES256 = 0x20
+
+	instCK256 = 0x04
+	instIK256 = 0x02
+	instK256  = 0x01
+)
+
+var algoName = [7]byte{'T', 'U', 'A', 'K', '1', '.', '0'}
+
+// TOPc computes TOPc from K and TOP.
+func TOPc(k, top []byte, iterations int) []byte {
+	var inout [200]byte
+	for i := 0; i < 32; i++ {
+		inout[31-i] = top[i]
+	}
+	inout[32] = 0x00
+	if len(k) == 32 {
+		inout[32] |= instK256
+	}
+	fill(&inout, nil, nil, nil, k)
+	run(&inout, iterations)
+	out := make([]byte, 32)
+	for i := 0; i < 32; i++ {
+		out[i] = inout[31-i]
+	}
+	return out
+}
+
+// F1 computes MAC-A, or MAC-S when star is set. macLength is in bits.
+func F1(topc, k, rand, sqn, amf []byte, macLength, iterations int, star bool) []byte {
+	var inout [200]byte
+	for i := 0; i < 32; i++ {
+		inout[31-i] = topc[i]
+	}
+	inout[32] = instF1
+	if star {
+		inout[32] = instF1Star
+	}
+	switch macLength {
+	case 64:
+		inout[32] |= instMAC64
+	case 128:
+		inout[32] |= instMAC128
+	case 256:
+		inout[32] |= instMAC256
+	}
+	if len(k) == 32 {
+		inout[32] |= instK256
+	}
+	fill(&inout, rand, amf, sqn, k)
+	run(&inout, iterations)
+	out := make([]byte, macLength/8)
+	for i := 0; i < len(out); i++ {
+		out[i] = inout[len(out)-1-i]
+	}
+	return out
+}
+
+// F2345 computes RES, CK, IK and AK. Lengths are in bits.
+func F2345(topc, k, rand []byte, resLength, ckLength, ikLength, iterations int) (res, ck, ik, ak []byte) {
+	var inout [200]byte
+	for i := 0; i < 32; i++ {
+		inout[31-i] = topc[i]
+	}
+	inout[32] = instF2345
+	switch resLength {
+	case 32:
+		inout[32] |= instRES32
+	case 64:
+		inout[32] |= instRES64
+	case 128:
+		inout[32] |= instRES128
+	case 256:
+		inout[32] |= instRES256
+	}
+	if ckLength == 256 {
+		inout[32] |= instCK256
+	}
+	if ikLength == 256 {
+		inout[32] |= instIK256
+	}
+	if len(k) == 32 {
+		inout[32] |= instK256
+	}
+	fill(&inout, rand, nil, nil, k)
+	run(&inout, iterations)
+
+	res = make([]byte, resLength/8)
+	for i := 0; i < len(res); i++ {
+		res[i] = inout[len(res)-1-i]
+	}
+	ck = make([]byte, ckLength/8)
+	for i := 0; i < len(ck); i++ {
+		ck[i] = inout[32+len(ck)-1-i]
+	}
+	ik = make([]byte, ikLength/8)
+	for i := 0; i < len(ik); i++ {
+		ik[i] = inout[64+len(ik)-1-i]
+	}
+	ak = make([]byte, 6)
+	for i := 0; i < 6; i++ {
+		ak[i] = inout[96+5-i]
+	}
+	return res, ck, ik, ak
+}
+
+// F5Star computes AK*.
+func F5Star(topc, k, rand []byte, iterations int) []byte {
+	var inout [200]byte
+	for i := 0; i < 32; i++ {
+		inout[31-i] = topc[i]
+	}
+	inout[32] = instF5Star
+	if len(k) == 32 {
+		inout[32] |= instK256
+	}
+	fill(&inout, rand, nil, nil, k)
+	run(&inout, iterations)
+	out := make([]byte, 6)
+	for i := 0; i < 6; i++ {
+		out[i] = inout[96+5-i]
+	}
+	return out
+}
+
+// fill writes ALGONAME, the optional RAND, AMF and SQN, K and the padding.
+func fill(inout *[200]byte, rand, amf, sqn, k []byte) {
+	for i := 0; i < 7; i++ {
+		inout[33+6-i] = algoName[i]
+	}
+	for i := 0; i < len(rand); i++ {
+		inout[40+15-i] = rand[i]
+	}
+	for i := 0; i < len(amf); i++ {
+		inout[56+1-i] = amf[i]
+	}
+	for i := 0; i < len(sqn); i++ {
+		inout[58+5-i] = sqn[i]
+	}
+	for i := 0; i < len(k); i++ {
+		inout[64+len(k)-1-i] = k[i]
+	}
+	inout[96] = 0x1F
+	inout[135] = 0x80
+}
+
+func run(inout *[200]byte, iterations int) {
+	for i := 0; i < iterations; i++ {
+		KeccakF1600(inout)
+	}
+}
+
+// KeccakF1600 applies Keccak-f[1600] to s in place. Bit z of lane (x, y)
+// is bit (64(5y+x)+z) mod 8 of byte (64(5y+x)+z)/8, as in FIPS 202 3.1.2.
+func KeccakF1600(s *[200]byte) {
+	var a [5][5][64]byte
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < 64; z++ {
+				i := 64*(5*y+x) + z
+				a[x][y][z] = s[i/8] >> (i % 8) & 1
+			}
+		}
+	}
+	for round := 0; round < 24; round++ {
+		a = theta(a)
+		a = rho(a)
+		a = pi(a)
+		a = chi(a)
+		a = iotaStep(a, round)
+	}
+	for i := range s {
+		s[i] = 0
+	}
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < 64; z++ {
+				i := 64*(5*y+x) + z
+				s[i/8] |= a[x][y][z] << (i % 8)
+			}
+		}
+	}
+}
+
+func theta(a [5][5][64]byte) [5][5][64]byte {
+	var c [5][64]byte
+	for x := 0; x < 5; x++ {
+		for z := 0; z < 64; z++ {
+			c[x][z] = a[x][0][z] ^ a[x][1][z] ^ a[x][2][z] ^ a[x][3][z] ^ a[x][4][z]
+		}
+	}
+	var out [5][5][64]byte
+	for x := 0; x < 5; x++ {
+		for z := 0; z < 64; z++ {
+			d := c[(x+4)%5][z] ^ c[(x+1)%5][(z+63)%64]
+			for y := 0; y < 5; y++ {
+				out[x][y][z] = a[x][y][z] ^ d
+			}
+		}
+	}
+	return out
+}
+
+func rho(a [5][5][64]byte) [5][5][64]byte {
+	out := a
+	x, y := 1, 0
+	for t := 0; t < 24; t++ {
+		offset := (t + 1) * (t + 2) / 2
+		for z := 0; z < 64; z++ {
+			out[x][y][z] = a[x][y][((z-offset)%64+64)%64]
+		}
+		x, y = y, (2*x+3*y)%5
+	}
+	return out
+}
+
+func pi(a [5][5][64]byte) [5][5][64]byte {
+	var out [5][5][64]byte
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			out[x][y] = a[(x+3*y)%5][x]
+		}
+	}
+	return out
+}
+
+func chi(a [5][5][64]byte) [5][5][64]byte {
+	var out [5][5][64]byte
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < 64; z++ {
+				out[x][y][z] = a[x][y][z] ^ (a[(x+1)%5][y][z]^1)&a[(x+2)%5][y][z]
+			}
+		}
+	}
+	return out
+}
+
+func iotaStep(a [5][5][64]byte, round int) [5][5][64]byte {
+	for j := 0; j <= 6; j++ {
+		a[0][0][(1<<j)-1] ^= rc(j + 7*round)
+	}
+	return a
+}
+
+// rc is the round constant LFSR of FIPS 202 Algorithm 5.
+func rc(t int) byte {
+	if t%255 == 0 {
+		return 1
+	}
+	r := [9]byte{1}
+	for i := 1; i <= t%255; i++ {
+		copy(r[1:], r[:8])
+		r[0] = 0
+		r[0] ^= r[8]
+		r[4] ^= r[8]
+		r[5] ^= r[8]
+		r[6] ^= r[8]
+	}
+	return r[0]
+}
@@ -0,0 +1,49 @@
+package tuakref
+
+import (
+	"bytes"
+	"testing"
+
+	"tuak/testvectors"
+)
+
+func TestKeccakVectors(t *testing.T) {
+	sets, err := testvectors.KeccakSets()
+	if err != nil {
+		t.Fatalf("KeccakSets: %v", err)
+	}
+	for _, v := range sets {
+		var s [200]byte
+		copy(s[:], v.In)
+		KeccakF1600(&s)
+		if !bytes.Equal(s[:], v.Out) {
+			t.Fatalf("Keccak set %d mismatch", v.ID)
+		}
+	}
+}
+
+func TestConformanceVectors(t *testing.T) {
+	sets, err := testvectors.TUAKSets()
+	if err != nil {
+		t.Fatalf("TUAKSets: %v", err)
+	}
+	for _, s := range sets {
+		n := s.KeccakIterations
+		if got := TOPc(s.K, s.TOP, n); !bytes.Equal(got, s.TOPc) {
+			t.Fatalf("%s TOPc = %x, want %x", s.Name(), got, s.TOPc)
+		}
+		if got := F1(s.TOPc, s.K, s.RAND, s.SQN, s.AMF, s.MACLength, n, false); !bytes.Equal(got, s.F1) {
+			t.Fatalf("%s f1 = %x, want %x", s.Name(), got, s.F1)
+		}
+		if got := F1(s.TOPc, s.K, s.RAND, s.SQN, s.AMF, s.MACLength, n, true); !bytes.Equal(got, s.F1Star) {
+			t.Fatalf("%s f1* = %x, want %x", s.Name(), got, s.F1Star)
+		}
+		res, ck, ik, ak := F2345(s.TOPc, s.K, s.RAND, s.RESLength, s.CKLength, s.IKLength, n)
+		if !bytes.Equal(res, s.F2) || !bytes.Equal(ck, s.F3) || !bytes.Equal(ik, s.F4) || !bytes.Equal(ak, s.F5) {
+			t.Fatalf("%s f2-f5 mismatch", s.Name())
+		}
+		if got := F5Star(s.TOPc, s.K, s.RAND, n); !bytes.Equal(got, s.F5Star) {
+			t.Fatalf("%s f5* = %x, want %x", s.Name(), got, s.F5Star)
+		}
+	}
+}
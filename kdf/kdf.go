@@ -0,0 +1,73 @@
+// Package kdf implements the 3GPP generic key derivation function
+// (TS 33.220 Annex B) and the 5G AKA derivations built on it (TS 33.501 Annex A).
+package kdf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// FC values used by the derivations in this package.
+const (
+	FCCKIKPrime = 0x20
+	FCKAUSF     = 0x6A
+	FCXRESStar  = 0x6B
+	FCKSEAF     = 0x6C
+)
+
+// Derive computes HMAC-SHA-256(key, FC || P0 || L0 || P1 || L1 ...).
+func Derive(key []byte, fc byte, params ...[]byte) []byte {
+	s := make([]byte, 0, 1+len(params)*2+paramsLen(params))
+	s = append(s, fc)
+	for _, p := range params {
+		s = append(s, p...)
+		s = binary.BigEndian.AppendUint16(s, uint16(len(p)))
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(s)
+	return mac.Sum(nil)
+}
+
+// XRESStar derives XRES* (or RES*) from CK, IK, the serving network name,
+// RAND and RES (TS 33.501 A.4).
+func XRESStar(ck, ik []byte, snn string, rand, res []byte) []byte {
+	out := Derive(concat(ck, ik), FCXRESStar, []byte(snn), rand, res)
+	return out[16:]
+}
+
+// HXRESStar derives HXRES* (or HRES*) from RAND and XRES* (TS 33.501 A.5).
+func HXRESStar(rand, xresStar []byte) []byte {
+	sum := sha256.Sum256(concat(rand, xresStar))
+	return sum[16:]
+}
+
+// KAUSF derives KAUSF for 5G AKA (TS 33.501 A.2).
+func KAUSF(ck, ik []byte, snn string, sqnXorAK []byte) []byte {
+	return Derive(concat(ck, ik), FCKAUSF, []byte(snn), sqnXorAK)
+}
+
+// KSEAF derives KSEAF from KAUSF (TS 33.501 A.6).
+func KSEAF(kausf []byte, snn string) []byte {
+	return Derive(kausf, FCKSEAF, []byte(snn))
+}
+
+// CKIKPrime derives CK' and IK' for EAP-AKA' (TS 33.402 A.2, TS 33.501 A.3).
+func CKIKPrime(ck, ik []byte, networkName string, sqnXorAK []byte) (ckPrime, ikPrime []byte) {
+	out := Derive(concat(ck, ik), FCCKIKPrime, []byte(networkName), sqnXorAK)
+	return out[:16], out[16:]
+}
+
+func paramsLen(params [][]byte) int {
+	n := 0
+	for _, p := range params {
+		n += len(p)
+	}
+	return n
+}
+
+func concat(a, b []byte) []byte {
+	out := make([]byte, 0, len(a)+len(b))
+	out = append(out, a...)
+	return append(out, b...)
+}
@@ -0,0 +1,67 @@
+package kdf
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestDeriveEncoding(t *testing.T) {
+	key := decodeHex(t, "000102030405060708090a0b0c0d0e0f")
+	got := Derive(key, 0x6B, []byte("ab"), []byte{0x01, 0x02, 0x03})
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte{0x6B, 'a', 'b', 0x00, 0x02, 0x01, 0x02, 0x03, 0x00, 0x03})
+	if want := mac.Sum(nil); !bytes.Equal(got, want) {
+		t.Fatalf("Derive = %x, want %x", got, want)
+	}
+}
+
+// RFC 5448 Appendix C, case 1.
+func TestCKIKPrimeRFC5448(t *testing.T) {
+	ck := decodeHex(t, "5349fbe098649f948f5d2e973a81c00f")
+	ik := decodeHex(t, "9744871ad32bf9bbd1dd5ce54e3e2e5a")
+	autn := decodeHex(t, "bb52e91c747ac3ab2a5c23d15ee351d5")
+
+	ckPrime, ikPrime := CKIKPrime(ck, ik, "WLAN", autn[:6])
+	if want := decodeHex(t, "0093962d0dd84aa5684b045c9edffa04"); !bytes.Equal(ckPrime, want) {
+		t.Fatalf("CK' = %x, want %x", ckPrime, want)
+	}
+	if want := decodeHex(t, "ccfc230ca74fcc96c0a5d61164f5a76c"); !bytes.Equal(ikPrime, want) {
+		t.Fatalf("IK' = %x, want %x", ikPrime, want)
+	}
+}
+
+func TestXRESStarLengths(t *testing.T) {
+	ck := bytes.Repeat([]byte{0x11}, 16)
+	ik := bytes.Repeat([]byte{0x22}, 16)
+	rand := bytes.Repeat([]byte{0x33}, 16)
+	res := bytes.Repeat([]byte{0x44}, 8)
+	snn := "5G:mnc001.mcc001.3gppnetwork.org"
+
+	xresStar := XRESStar(ck, ik, snn, rand, res)
+	if len(xresStar) != 16 {
+		t.Fatalf("XRES* length = %d, want 16", len(xresStar))
+	}
+	if hx := HXRESStar(rand, xresStar); len(hx) != 16 {
+		t.Fatalf("HXRES* length = %d, want 16", len(hx))
+	}
+	kausf := KAUSF(ck, ik, snn, res[:6])
+	if len(kausf) != 32 {
+		t.Fatalf("KAUSF length = %d, want 32", len(kausf))
+	}
+	if kseaf := KSEAF(kausf, snn); len(kseaf) != 32 {
+		t.Fatalf("KSEAF length = %d, want 32", len(kseaf))
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
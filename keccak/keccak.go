@@ -1,4 +1,5 @@
-// Package keccak implements Keccak-f[1600] used by TUAK.
+// Package keccak implements Keccak-f[1600] used by TUAK, and the
+// Keccak-p[b, n_r] family around it for cryptanalysis experiments.
 package keccak
 
 import (
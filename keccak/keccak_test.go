@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+
+	"tuak/internal/tuakref"
 	"tuak/testvectors"
 )
 
@@ -64,7 +66,7 @@ type keccakVector struct {
 }
 
 func loadKeccakVectors35233JSON() ([]keccakVector, error) {
-	content, err := os.ReadFile("../testdata/ts35233_keccak.json")
+	content, err := os.ReadFile("../testvectors/testdata/ts35233_keccak.json")
 	if err != nil {
 		return nil, err
 	}
@@ -84,3 +86,205 @@ func loadKeccakVectors35233JSON() ([]keccakVector, error) {
 	}
 	return out, nil
 }
+
+func FuzzPermuteF1600(f *testing.F) {
+	f.Add(make([]byte, 200))
+	f.Add(bytes.Repeat([]byte{0xa5}, 200))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var s [200]byte
+		copy(s[:], data)
+		got, err := PermuteF1600(s[:])
+		if err != nil {
+			t.Fatalf("PermuteF1600: %v", err)
+		}
+		tuakref.KeccakF1600(&s)
+		if !bytes.Equal(got, s[:]) {
+			t.Fatalf("PermuteF1600 differs from reference")
+		}
+	})
+}
+
+func TestPermuteF1600WithHook(t *testing.T) {
+	vectors, err := testvectors.KeccakSets()
+	if err != nil {
+		t.Fatalf("KeccakSets: %v", err)
+	}
+	for _, v := range vectors {
+		var calls int
+		var last []byte
+		got, err := PermuteF1600WithHook(v.In, func(round int, step Step, state []byte) {
+			if round != calls/5 || step != Step(calls%5) {
+				t.Fatalf("vector %d call %d = round %d %v", v.ID, calls, round, step)
+			}
+			if len(state) != 200 {
+				t.Fatalf("vector %d state length = %d", v.ID, len(state))
+			}
+			calls++
+			last = state
+		})
+		if err != nil {
+			t.Fatalf("vector %d: %v", v.ID, err)
+		}
+		if !bytes.Equal(got, v.Out) {
+			t.Fatalf("vector %d output mismatch", v.ID)
+		}
+		if calls != 120 {
+			t.Fatalf("vector %d: %d callbacks, want 120", v.ID, calls)
+		}
+		if !bytes.Equal(last, got) {
+			t.Fatalf("vector %d: last iota state differs from output", v.ID)
+		}
+	}
+	if _, err := PermuteF1600WithHook(make([]byte, 199), func(int, Step, []byte) {}); err == nil {
+		t.Fatalf("PermuteF1600WithHook accepted 199 bytes")
+	}
+	if got := StepIota.String(); got != "iota" {
+		t.Fatalf("StepIota.String() = %q", got)
+	}
+}
+
+// publishedP are published Keccak-p outputs. The zero-state Keccak-f[200]
+// and Keccak-f[1600] outputs are those of the XKCP test vectors. The
+// Keccak-p[1600, 12] inputs are the single padded block absorbed by
+// TurboSHAKE128 and KT128 for the empty message (RFC 9861), whose first 32
+// output bytes are the published 32-byte digests.
+var publishedP = []struct {
+	name   string
+	size   int
+	rounds int
+	pad    map[int]byte
+	want   string
+}{
+	{"Keccak-f[200] zero state", 25, 18, nil, "3c2826841cb35c171eaae9b811134ceaa3852c69d2c5abafea"},
+	{"Keccak-f[1600] zero state", 200, 24, nil, "e7dde140798f25f18a47c033f9ccd584eea95aa61e2698d54d49806f304715bd"},
+	{"TurboSHAKE128(empty, D=1F)", 200, 12, map[int]byte{0: 0x1f, 167: 0x80}, "1e415f1c5983aff2169217277d17bb538cd945a397ddec541f1ce41af2c1b74c"},
+	{"KT128(empty, empty)", 200, 12, map[int]byte{1: 0x07, 167: 0x80}, "1ac2d450fc3b4205d19da7bfca1b37513c0803577ac7167f06fe2ce1f0ef39e5"},
+}
+
+// pinnedP are the zero-state Keccak-f[400] and Keccak-f[800] outputs on
+// which PermuteP and referenceP agree. No published answer for these widths
+// is checked in; compare them with the XKCP KeccakF-400 and KeccakF-800 test
+// vectors when changing either implementation.
+var pinnedP = []struct {
+	size, rounds int
+	want         string
+}{
+	{50, 20, "f509ac40a90ff5149fe8a0ecd15b7078f0ef8fbf3703526075dcc90e76e74652a159815d956d146e3e63ee58ff714c718eb3"},
+	{100, 22, "5dd431e5fbc604f499bfa0232f45f8f142d0ff5178f539e5a7800bf0643697af4cf35abf24247a22152717888458689f54d05cb10efcf41b91fa66619a599e1a1f0a97a3879665ab688dabaf15104be7981a0034f3ef1941760e0a937080b28796e9ef11"},
+}
+
+func TestPermutePPublished(t *testing.T) {
+	for _, v := range publishedP {
+		in := make([]byte, v.size)
+		for i, b := range v.pad {
+			in[i] = b
+		}
+		want := decodeHex(t, v.want)
+		got, err := PermuteP(in, v.rounds)
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		if !bytes.Equal(got[:len(want)], want) {
+			t.Fatalf("%s = %x, want %x", v.name, got[:len(want)], want)
+		}
+		referenceP(in, v.rounds)
+		if !bytes.Equal(in[:len(want)], want) {
+			t.Fatalf("%s: reference = %x, want %x", v.name, in[:len(want)], want)
+		}
+	}
+	for _, v := range pinnedP {
+		got, err := PermuteP(make([]byte, v.size), v.rounds)
+		if err != nil {
+			t.Fatalf("Keccak-f[%d]: %v", v.size*8, err)
+		}
+		if want := decodeHex(t, v.want); !bytes.Equal(got, want) {
+			t.Fatalf("Keccak-f[%d] zero state = %x, want %x", v.size*8, got, want)
+		}
+	}
+}
+
+func TestPermuteP(t *testing.T) {
+	vectors, err := testvectors.KeccakSets()
+	if err != nil {
+		t.Fatalf("KeccakSets: %v", err)
+	}
+	for _, v := range vectors {
+		got, err := PermuteP(v.In, 24)
+		if err != nil {
+			t.Fatalf("vector %d: %v", v.ID, err)
+		}
+		if !bytes.Equal(got, v.Out) {
+			t.Fatalf("vector %d: Keccak-p[1600, 24] differs from Keccak-f[1600]", v.ID)
+		}
+	}
+
+	full := map[int]func([]byte) ([]byte, error){25: PermuteF200, 50: PermuteF400, 100: PermuteF800, 200: PermuteF1600}
+	rounds := map[int]int{25: 18, 50: 20, 100: 22, 200: 24}
+	for size, f := range full {
+		in := make([]byte, size)
+		for i := range in {
+			in[i] = byte(i*7 + 1)
+		}
+		for r := 1; r <= rounds[size]; r++ {
+			got, err := PermuteP(in, r)
+			if err != nil {
+				t.Fatalf("Keccak-p[%d, %d]: %v", size*8, r, err)
+			}
+			want := append([]byte(nil), in...)
+			referenceP(want, r)
+			if !bytes.Equal(got, want) {
+				t.Fatalf("Keccak-p[%d, %d] differs from reference", size*8, r)
+			}
+			if size == 200 {
+				hooked, err := PermuteP1600WithHook(in, r, func(round int, _ Step, _ []byte) {
+					if round < 24-r {
+						t.Fatalf("Keccak-p[1600, %d] reported round %d", r, round)
+					}
+				})
+				if err != nil || !bytes.Equal(hooked, got) {
+					t.Fatalf("PermuteP1600WithHook(%d) = %x, %v", r, hooked, err)
+				}
+			}
+		}
+		got, err := f(in)
+		if err != nil {
+			t.Fatalf("Keccak-f[%d]: %v", size*8, err)
+		}
+		want, _ := PermuteP(in, rounds[size])
+		if !bytes.Equal(got, want) {
+			t.Fatalf("Keccak-f[%d] differs from Keccak-p[%d, %d]", size*8, size*8, rounds[size])
+		}
+		if _, err := PermuteP(in, rounds[size]+1); err == nil {
+			t.Fatalf("Keccak-p[%d] accepted %d rounds", size*8, rounds[size]+1)
+		}
+		if _, err := f(in[1:]); err == nil {
+			t.Fatalf("Keccak-f[%d] accepted %d bytes", size*8, size-1)
+		}
+	}
+	if _, err := PermuteP(make([]byte, 64), 1); err == nil {
+		t.Fatalf("PermuteP accepted 64 bytes")
+	}
+	if _, err := PermuteP(make([]byte, 200), 0); err == nil {
+		t.Fatalf("PermuteP accepted 0 rounds")
+	}
+}
+
+func FuzzPermuteP(f *testing.F) {
+	f.Add(make([]byte, 25), uint8(1))
+	f.Add(bytes.Repeat([]byte{0xa5}, 200), uint8(4))
+	f.Fuzz(func(t *testing.T, data []byte, sel uint8) {
+		sizes := []int{25, 50, 100, 200}
+		size := sizes[int(sel)%len(sizes)]
+		rounds := 1 + int(sel/4)%(12+2*(int(sel)%len(sizes)+3))
+		s := make([]byte, size)
+		copy(s, data)
+		got, err := PermuteP(s, rounds)
+		if err != nil {
+			t.Fatalf("PermuteP(%d bytes, %d): %v", size, rounds, err)
+		}
+		referenceP(s, rounds)
+		if !bytes.Equal(got, s) {
+			t.Fatalf("Keccak-p[%d, %d] differs from reference", size*8, rounds)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package keccak
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// PermuteP applies Keccak-p[b, rounds] (FIPS 202 3.3) to a state of 25, 50,
+// 100 or 200 bytes, the width b being 8*len(in). rounds runs from 1 to the
+// full 12+2l of Keccak-f[b] (18, 20, 22 or 24); as in FIPS 202 the last
+// rounds of Keccak-f[b] are kept, so PermuteP(in, 24) on 200 bytes equals
+// PermuteF1600(in). Reduced-round variants are for cryptanalysis only.
+func PermuteP(in []byte, rounds int) ([]byte, error) {
+	w, err := laneWidth(len(in))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRounds(w, rounds); err != nil {
+		return nil, err
+	}
+	a := loadLanes(in, w)
+	keccakP(&a, w, rounds)
+	return storeLanes(&a, w), nil
+}
+
+// PermuteF200 applies Keccak-f[200] (18 rounds) to a 25-byte state.
+func PermuteF200(in []byte) ([]byte, error) {
+	return permuteF(in, 25)
+}
+
+// PermuteF400 applies Keccak-f[400] (20 rounds) to a 50-byte state.
+func PermuteF400(in []byte) ([]byte, error) {
+	return permuteF(in, 50)
+}
+
+// PermuteF800 applies Keccak-f[800] (22 rounds) to a 100-byte state.
+func PermuteF800(in []byte) ([]byte, error) {
+	return permuteF(in, 100)
+}
+
+func permuteF(in []byte, size int) ([]byte, error) {
+	if len(in) != size {
+		return nil, fmt.Errorf("keccak: input must be %d bytes", size)
+	}
+	w, _ := laneWidth(size)
+	return PermuteP(in, fullRounds(w))
+}
+
+// laneWidth returns the lane width in bits for a state of size bytes.
+func laneWidth(size int) (uint, error) {
+	switch size {
+	case 25, 50, 100, 200:
+		return uint(size / 25 * 8), nil
+	default:
+		return 0, fmt.Errorf("keccak: input must be 25, 50, 100 or 200 bytes, got %d", size)
+	}
+}
+
+// fullRounds returns 12+2l, the number of rounds of Keccak-f with lanes of
+// w = 2^l bits.
+func fullRounds(w uint) int {
+	return 12 + 2*bits.TrailingZeros(w)
+}
+
+func checkRounds(w uint, rounds int) error {
+	if n := fullRounds(w); rounds < 1 || rounds > n {
+		return fmt.Errorf("keccak: %d rounds outside 1..%d for Keccak-p[%d]", rounds, n, 25*w)
+	}
+	return nil
+}
+
+// loadLanes reads lane i from bytes [i*w/8, (i+1)*w/8), little endian.
+func loadLanes(in []byte, w uint) [25]uint64 {
+	var a [25]uint64
+	n := int(w / 8)
+	for i := range a {
+		for j := n - 1; j >= 0; j-- {
+			a[i] = a[i]<<8 | uint64(in[i*n+j])
+		}
+	}
+	return a
+}
+
+func storeLanes(a *[25]uint64, w uint) []byte {
+	n := int(w / 8)
+	out := make([]byte, 25*n)
+	for i, lane := range a {
+		for j := 0; j < n; j++ {
+			out[i*n+j] = byte(lane >> (8 * j))
+		}
+	}
+	return out
+}
+
+// keccakP is keccakF1600 generalised to lanes of w bits held in the low
+// bits of each uint64, running the last rounds of Keccak-f[25w].
+func keccakP(a *[25]uint64, w uint, rounds int) {
+	mask := uint64(1)<<w - 1
+	if w == 64 {
+		mask = ^uint64(0)
+	}
+	rotl := func(v uint64, n uint64) uint64 {
+		r := uint(n) % w
+		if r == 0 {
+			return v
+		}
+		return (v<<r | v>>(w-r)) & mask
+	}
+
+	var bc [5]uint64
+	last := fullRounds(w)
+	for round := last - rounds; round < last; round++ {
+		for i := 0; i < 5; i++ {
+			bc[i] = a[i] ^ a[i+5] ^ a[i+10] ^ a[i+15] ^ a[i+20]
+		}
+		for i := 0; i < 5; i++ {
+			t := bc[(i+4)%5] ^ rotl(bc[(i+1)%5], 1)
+			a[i] ^= t
+			a[i+5] ^= t
+			a[i+10] ^= t
+			a[i+15] ^= t
+			a[i+20] ^= t
+		}
+
+		t := a[1]
+		for i := 0; i < 24; i++ {
+			j := keccakPiLane[i]
+			bc[0] = a[j]
+			a[j] = rotl(t, keccakRotc[i])
+			t = bc[0]
+		}
+
+		for j := 0; j < 25; j += 5 {
+			for i := 0; i < 5; i++ {
+				bc[i] = a[j+i]
+			}
+			for i := 0; i < 5; i++ {
+				a[j+i] ^= (^bc[(i+1)%5]) & bc[(i+2)%5] & mask
+			}
+		}
+
+		a[0] ^= keccakRoundConst[round] & mask
+	}
+}
@@ -0,0 +1,118 @@
+package keccak
+
+// referenceP applies Keccak-p[b, rounds] to s in place, b being 8*len(s)
+// (200, 400, 800 or 1600). Bit z of lane (x, y) is bit (w(5y+x)+z) mod 8 of
+// byte (w(5y+x)+z)/8, as in FIPS 202 3.1.2. It is evaluated bit by bit from
+// the FIPS 202 step mappings and shares no tables with the package.
+func referenceP(s []byte, rounds int) {
+	w := len(s) * 8 / 25
+	l := 0
+	for 1<<l < w {
+		l++
+	}
+	var a [5][5][64]byte
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < w; z++ {
+				i := w*(5*y+x) + z
+				a[x][y][z] = s[i/8] >> (i % 8) & 1
+			}
+		}
+	}
+	for round := 12 + 2*l - rounds; round < 12+2*l; round++ {
+		a = refTheta(a, w)
+		a = refRho(a, w)
+		a = refPi(a)
+		a = refChi(a, w)
+		a = refIotaStep(a, l, round)
+	}
+	for i := range s {
+		s[i] = 0
+	}
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < w; z++ {
+				i := w*(5*y+x) + z
+				s[i/8] |= a[x][y][z] << (i % 8)
+			}
+		}
+	}
+}
+
+func refTheta(a [5][5][64]byte, w int) [5][5][64]byte {
+	var c [5][64]byte
+	for x := 0; x < 5; x++ {
+		for z := 0; z < w; z++ {
+			c[x][z] = a[x][0][z] ^ a[x][1][z] ^ a[x][2][z] ^ a[x][3][z] ^ a[x][4][z]
+		}
+	}
+	var out [5][5][64]byte
+	for x := 0; x < 5; x++ {
+		for z := 0; z < w; z++ {
+			d := c[(x+4)%5][z] ^ c[(x+1)%5][(z+w-1)%w]
+			for y := 0; y < 5; y++ {
+				out[x][y][z] = a[x][y][z] ^ d
+			}
+		}
+	}
+	return out
+}
+
+func refRho(a [5][5][64]byte, w int) [5][5][64]byte {
+	out := a
+	x, y := 1, 0
+	for t := 0; t < 24; t++ {
+		offset := (t + 1) * (t + 2) / 2
+		for z := 0; z < w; z++ {
+			out[x][y][z] = a[x][y][((z-offset)%w+w)%w]
+		}
+		x, y = y, (2*x+3*y)%5
+	}
+	return out
+}
+
+func refPi(a [5][5][64]byte) [5][5][64]byte {
+	var out [5][5][64]byte
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			out[x][y] = a[(x+3*y)%5][x]
+		}
+	}
+	return out
+}
+
+func refChi(a [5][5][64]byte, w int) [5][5][64]byte {
+	var out [5][5][64]byte
+	for x := 0; x < 5; x++ {
+		for y := 0; y < 5; y++ {
+			for z := 0; z < w; z++ {
+				out[x][y][z] = a[x][y][z] ^ (a[(x+1)%5][y][z]^1)&a[(x+2)%5][y][z]
+			}
+		}
+	}
+	return out
+}
+
+func refIotaStep(a [5][5][64]byte, l, round int) [5][5][64]byte {
+	for j := 0; j <= l; j++ {
+		a[0][0][(1<<j)-1] ^= refRc(j + 7*round)
+	}
+	return a
+}
+
+// refRc is the round constant LFSR of FIPS 202 Algorithm 5.
+func refRc(t int) byte {
+	if t%255 == 0 {
+		return 1
+	}
+	r := [9]byte{1}
+	for i := 1; i <= t%255; i++ {
+		copy(r[1:], r[:8])
+		r[0] = 0
+		r[0] ^= r[8]
+		r[4] ^= r[8]
+		r[5] ^= r[8]
+		r[6] ^= r[8]
+	}
+	return r[0]
+}
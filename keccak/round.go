@@ -0,0 +1,137 @@
+package keccak
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// Step is one of the five step mappings of a Keccak round.
+type Step int
+
+// Step mappings in the order they are applied (FIPS 202 3.3).
+const (
+	StepTheta Step = iota
+	StepRho
+	StepPi
+	StepChi
+	StepIota
+)
+
+// String returns the step name, e.g. "theta".
+func (s Step) String() string {
+	switch s {
+	case StepTheta:
+		return "theta"
+	case StepRho:
+		return "rho"
+	case StepPi:
+		return "pi"
+	case StepChi:
+		return "chi"
+	case StepIota:
+		return "iota"
+	default:
+		return fmt.Sprintf("Step(%d)", int(s))
+	}
+}
+
+// RoundHook receives a copy of the 200-byte state after each step of each
+// round. Rounds are numbered by their index in Keccak-f[1600], 0 to 23.
+type RoundHook func(round int, step Step, state []byte)
+
+// PermuteF1600WithHook applies Keccak-f[1600] like PermuteF1600 and reports
+// the state after theta, rho, pi, chi and iota of all 24 rounds to h. The
+// steps are computed separately, so it is slower than PermuteF1600.
+func PermuteF1600WithHook(in []byte, h RoundHook) ([]byte, error) {
+	if h == nil {
+		return PermuteF1600(in)
+	}
+	return PermuteP1600WithHook(in, 24, h)
+}
+
+// PermuteP1600WithHook applies Keccak-p[1600, rounds] like PermuteP and
+// reports every step to h. The rounds run are 24-rounds to 23.
+func PermuteP1600WithHook(in []byte, rounds int, h RoundHook) ([]byte, error) {
+	if h == nil {
+		return PermuteP(in, rounds)
+	}
+	if len(in) != 200 {
+		return nil, errInvalidLength
+	}
+	if err := checkRounds(64, rounds); err != nil {
+		return nil, err
+	}
+	var a [25]uint64
+	for i := 0; i < 25; i++ {
+		a[i] = binary.LittleEndian.Uint64(in[i*8 : i*8+8])
+	}
+	report := func(round int, step Step) {
+		buf := make([]byte, 200)
+		for i := 0; i < 25; i++ {
+			binary.LittleEndian.PutUint64(buf[i*8:i*8+8], a[i])
+		}
+		h(round, step, buf)
+	}
+
+	for round := 24 - rounds; round < 24; round++ {
+		var c [5]uint64
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[y+x] ^= d
+			}
+		}
+		report(round, StepTheta)
+
+		for i := range a {
+			a[i] = bits.RotateLeft64(a[i], keccakRho[i])
+		}
+		report(round, StepRho)
+
+		var b [25]uint64
+		b[0] = a[0]
+		for i := 0; i < 24; i++ {
+			b[keccakPiLane[i]] = a[keccakPiSource[i]]
+		}
+		a = b
+		report(round, StepPi)
+
+		for y := 0; y < 25; y += 5 {
+			var row [5]uint64
+			copy(row[:], a[y:y+5])
+			for x := 0; x < 5; x++ {
+				a[y+x] = row[x] ^ (^row[(x+1)%5] & row[(x+2)%5])
+			}
+		}
+		report(round, StepChi)
+
+		a[0] ^= keccakRoundConst[round]
+		report(round, StepIota)
+	}
+
+	out := make([]byte, 200)
+	for i := 0; i < 25; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:i*8+8], a[i])
+	}
+	return out, nil
+}
+
+// keccakPiSource and keccakRho unfold the combined rho-pi loop of
+// keccakF1600: step i moves lane keccakPiSource[i] to keccakPiLane[i]
+// after rotating it by keccakRotc[i].
+var keccakPiSource, keccakRho = func() ([24]int, [25]int) {
+	var src [24]int
+	var rho [25]int
+	src[0] = 1
+	for i := 1; i < 24; i++ {
+		src[i] = keccakPiLane[i-1]
+	}
+	for i, s := range src {
+		rho[s] = int(keccakRotc[i])
+	}
+	return src, rho
+}()
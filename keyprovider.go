@@ -0,0 +1,20 @@
+package tuak
+
+// KeyProvider supplies K and TOPc to a single computation. Implementations
+// may unwrap or fetch the keys on demand and should erase them when fn
+// returns. TOPc may be nil, in which case it is derived from the TOP given
+// to New.
+type KeyProvider interface {
+	UseKeys(fn func(k, topc []byte) error) error
+}
+
+// StaticKeys is a KeyProvider holding K and TOPc in clear.
+type StaticKeys struct {
+	K    []byte
+	TOPc []byte
+}
+
+// UseKeys implements KeyProvider.
+func (s StaticKeys) UseKeys(fn func(k, topc []byte) error) error {
+	return fn(s.K, s.TOPc)
+}
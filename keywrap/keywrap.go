@@ -0,0 +1,186 @@
+// Package keywrap protects K and TOPc at rest under a transport or storage
+// key using AES Key Wrap (RFC 3394, RFC 5649) or AES-CBC.
+package keywrap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrUnwrap is returned when the integrity check of a wrapped key fails.
+var ErrUnwrap = errors.New("keywrap: integrity check failed")
+
+// Scheme identifies a wrapping algorithm.
+type Scheme string
+
+const (
+	// SchemeAESKW is AES Key Wrap (RFC 3394).
+	SchemeAESKW Scheme = "aes-kw"
+	// SchemeAESKWP is AES Key Wrap with Padding (RFC 5649).
+	SchemeAESKWP Scheme = "aes-kwp"
+	// SchemeAESCBC is AES-CBC with a zero IV and no padding, as commonly
+	// used for SIM vendor transport keys.
+	SchemeAESCBC Scheme = "aes-cbc"
+)
+
+var (
+	defaultIV = [8]byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+	aivPrefix = [4]byte{0xA6, 0x59, 0x59, 0xA6}
+)
+
+// Wrap encrypts key under kek with the given scheme.
+func Wrap(scheme Scheme, kek, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	switch scheme {
+	case SchemeAESKW:
+		if len(key) < 16 || len(key)%8 != 0 {
+			return nil, fmt.Errorf("keywrap: aes-kw key length %d bytes", len(key))
+		}
+		return wrap(block, defaultIV, key), nil
+	case SchemeAESKWP:
+		if len(key) == 0 {
+			return nil, fmt.Errorf("keywrap: empty key")
+		}
+		var iv [8]byte
+		copy(iv[:], aivPrefix[:])
+		binary.BigEndian.PutUint32(iv[4:], uint32(len(key)))
+		padded := make([]byte, (len(key)+7)/8*8)
+		copy(padded, key)
+		if len(padded) == 8 {
+			out := make([]byte, 16)
+			copy(out, iv[:])
+			copy(out[8:], padded)
+			block.Encrypt(out, out)
+			return out, nil
+		}
+		return wrap(block, iv, padded), nil
+	case SchemeAESCBC:
+		if len(key)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("keywrap: aes-cbc key length %d bytes", len(key))
+		}
+		out := make([]byte, len(key))
+		cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(out, key)
+		return out, nil
+	default:
+		return nil, fmt.Errorf("keywrap: unknown scheme %q", scheme)
+	}
+}
+
+// Unwrap decrypts and verifies a key wrapped with Wrap. The caller should
+// erase the result after use.
+func Unwrap(scheme Scheme, kek, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	switch scheme {
+	case SchemeAESKW:
+		if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+			return nil, fmt.Errorf("keywrap: aes-kw wrapped length %d bytes", len(wrapped))
+		}
+		iv, key := unwrap(block, wrapped)
+		if subtle.ConstantTimeCompare(iv[:], defaultIV[:]) != 1 {
+			clear(key)
+			return nil, ErrUnwrap
+		}
+		return key, nil
+	case SchemeAESKWP:
+		if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+			return nil, fmt.Errorf("keywrap: aes-kwp wrapped length %d bytes", len(wrapped))
+		}
+		var iv [8]byte
+		var padded []byte
+		if len(wrapped) == 16 {
+			buf := make([]byte, 16)
+			block.Decrypt(buf, wrapped)
+			copy(iv[:], buf[:8])
+			padded = buf[8:]
+		} else {
+			iv, padded = unwrap(block, wrapped)
+		}
+		n := int(binary.BigEndian.Uint32(iv[4:]))
+		ok := subtle.ConstantTimeCompare(iv[:4], aivPrefix[:]) == 1 &&
+			n <= len(padded) && n > len(padded)-8
+		if ok {
+			for _, b := range padded[n:] {
+				ok = ok && b == 0
+			}
+		}
+		if !ok {
+			clear(padded)
+			return nil, ErrUnwrap
+		}
+		return padded[:n], nil
+	case SchemeAESCBC:
+		if len(wrapped) == 0 || len(wrapped)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("keywrap: aes-cbc wrapped length %d bytes", len(wrapped))
+		}
+		out := make([]byte, len(wrapped))
+		cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(out, wrapped)
+		return out, nil
+	default:
+		return nil, fmt.Errorf("keywrap: unknown scheme %q", scheme)
+	}
+}
+
+// Rewrap unwraps with one key and scheme and wraps with another, without
+// returning the clear key.
+func Rewrap(fromScheme Scheme, fromKEK []byte, toScheme Scheme, toKEK, wrapped []byte) ([]byte, error) {
+	key, err := Unwrap(fromScheme, fromKEK, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	return Wrap(toScheme, toKEK, key)
+}
+
+// wrap is the RFC 3394 wrapping process W with initial value iv.
+func wrap(block cipher.Block, iv [8]byte, key []byte) []byte {
+	n := len(key) / 8
+	out := make([]byte, 8+len(key))
+	copy(out[8:], key)
+	a := iv
+	var buf [16]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf[:8], a[:])
+			copy(buf[8:], out[i*8:i*8+8])
+			block.Encrypt(buf[:], buf[:])
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(a[:], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[i*8:], buf[8:])
+		}
+	}
+	copy(out, a[:])
+	clear(buf[:])
+	return out
+}
+
+// unwrap is the RFC 3394 unwrapping process W^-1.
+func unwrap(block cipher.Block, wrapped []byte) ([8]byte, []byte) {
+	n := len(wrapped)/8 - 1
+	var a [8]byte
+	copy(a[:], wrapped[:8])
+	r := make([]byte, n*8)
+	copy(r, wrapped[8:])
+	var buf [16]byte
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a[:])^t)
+			copy(buf[8:], r[(i-1)*8:i*8])
+			block.Decrypt(buf[:], buf[:])
+			copy(a[:], buf[:8])
+			copy(r[(i-1)*8:], buf[8:])
+		}
+	}
+	clear(buf[:])
+	return a, r
+}
@@ -0,0 +1,140 @@
+package keywrap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"tuak"
+	"tuak/testvectors"
+)
+
+func TestWrapVectors(t *testing.T) {
+	cases := []struct {
+		name    string
+		scheme  Scheme
+		kek     string
+		key     string
+		wrapped string
+	}{
+		// RFC 3394 4.1
+		{"rfc3394-4.1", SchemeAESKW, "000102030405060708090a0b0c0d0e0f",
+			"00112233445566778899aabbccddeeff",
+			"1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5"},
+		// RFC 3394 4.6
+		{"rfc3394-4.6", SchemeAESKW, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f",
+			"28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21"},
+		// RFC 5649 6
+		{"rfc5649-20", SchemeAESKWP, "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			"c37b7e6492584340bed12207808941155068f738",
+			"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a"},
+		{"rfc5649-7", SchemeAESKWP, "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			"466f7250617369",
+			"afbeb0f07dfbf5419200f2ccb50bb24f"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kek := decodeHex(t, c.kek)
+			key := decodeHex(t, c.key)
+			want := decodeHex(t, c.wrapped)
+
+			got, err := Wrap(c.scheme, kek, key)
+			if err != nil {
+				t.Fatalf("Wrap: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("Wrap = %x, want %x", got, want)
+			}
+			back, err := Unwrap(c.scheme, kek, got)
+			if err != nil {
+				t.Fatalf("Unwrap: %v", err)
+			}
+			if !bytes.Equal(back, key) {
+				t.Fatalf("Unwrap = %x, want %x", back, key)
+			}
+
+			got[len(got)-1] ^= 0x01
+			if _, err := Unwrap(c.scheme, kek, got); !errors.Is(err, ErrUnwrap) {
+				t.Fatalf("Unwrap tampered: err = %v, want ErrUnwrap", err)
+			}
+		})
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	kek1 := bytes.Repeat([]byte{0x01}, 16)
+	kek2 := bytes.Repeat([]byte{0x02}, 32)
+	key := bytes.Repeat([]byte{0xab}, 16)
+
+	cbc, err := Wrap(SchemeAESCBC, kek1, key)
+	if err != nil {
+		t.Fatalf("Wrap: %v", err)
+	}
+	kw, err := Rewrap(SchemeAESCBC, kek1, SchemeAESKW, kek2, cbc)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	got, err := Unwrap(SchemeAESKW, kek2, kw)
+	if err != nil {
+		t.Fatalf("Unwrap: %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Fatalf("round trip = %x, want %x", got, key)
+	}
+}
+
+func TestProviderVectors(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	kek := bytes.Repeat([]byte{0x42}, 32)
+	for _, v := range data.Tests {
+		wk, err := Wrap(SchemeAESKW, kek, decodeHex(t, v.K))
+		if err != nil {
+			t.Fatalf("Wrap K: %v", err)
+		}
+		wtopc, err := Wrap(SchemeAESKW, kek, decodeHex(t, v.Topc))
+		if err != nil {
+			t.Fatalf("Wrap TOPc: %v", err)
+		}
+		p := &Provider{Scheme: SchemeAESKW, KEK: kek, WrappedK: wk, WrappedTOPc: wtopc}
+
+		tk, err := tuak.NewWithTOPc(nil, nil, decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF),
+			tuak.WithKeyProvider(p),
+			tuak.WithMACLength(v.MAClength),
+			tuak.WithRESLength(v.RESLength),
+			tuak.WithCKLength(v.CKlength),
+			tuak.WithIKLength(v.IKlength),
+			tuak.WithKeccakIterations(v.KeccakIterations),
+		)
+		if err != nil {
+			t.Fatalf("NewWithTOPc: %v", err)
+		}
+		mac, err := tk.F1()
+		if err != nil {
+			t.Fatalf("F1: %v", err)
+		}
+		if !bytes.Equal(mac, decodeHex(t, v.F1)) {
+			t.Fatalf("vector %d f1 mismatch", v.ID)
+		}
+		res, _, _, _, err := tk.F2345()
+		if err != nil {
+			t.Fatalf("F2345: %v", err)
+		}
+		if !bytes.Equal(res, decodeHex(t, v.F2)) {
+			t.Fatalf("vector %d f2 mismatch", v.ID)
+		}
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
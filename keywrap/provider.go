@@ -0,0 +1,47 @@
+package keywrap
+
+import (
+	"tuak"
+	"tuak/store"
+)
+
+// Provider is a tuak.KeyProvider holding K and TOPc wrapped under a KEK.
+// The keys are unwrapped for each computation and erased afterwards.
+type Provider struct {
+	Scheme Scheme
+	KEK    []byte
+	// WrappedK is required; WrappedTOPc may be nil when TOPc is derived from TOP.
+	WrappedK    []byte
+	WrappedTOPc []byte
+}
+
+// UseKeys implements tuak.KeyProvider.
+func (p *Provider) UseKeys(fn func(k, topc []byte) error) error {
+	k, err := Unwrap(p.Scheme, p.KEK, p.WrappedK)
+	if err != nil {
+		return err
+	}
+	defer clear(k)
+	var topc []byte
+	if p.WrappedTOPc != nil {
+		topc, err = Unwrap(p.Scheme, p.KEK, p.WrappedTOPc)
+		if err != nil {
+			return err
+		}
+		defer clear(topc)
+	}
+	return fn(k, topc)
+}
+
+// SubscriberProvider returns a function building a Provider for stored
+// subscribers whose KeyWrap names a Scheme, for use with udm.WithKeyProvider.
+func SubscriberProvider(kek []byte) func(sub *store.Subscriber) (tuak.KeyProvider, error) {
+	return func(sub *store.Subscriber) (tuak.KeyProvider, error) {
+		return &Provider{
+			Scheme:      Scheme(sub.KeyWrap),
+			KEK:         kek,
+			WrappedK:    sub.K,
+			WrappedTOPc: sub.TOPc,
+		}, nil
+	}
+}
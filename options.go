@@ -1,14 +1,24 @@
 package tuak
 
-// Options configures TUAK parameters.
+import "log/slog"
+
+// Options configures TUAK parameters. The JSON and text forms carry the
+// length fields only, named as in testvectors.TUAKVector.
 type Options struct {
-	KLength          int
-	MACLength        int
-	RESLength        int
-	CKLength         int
-	IKLength         int
-	KeccakIterations int
-	DebugHook        DebugHook
+	KLength          int         `json:"klength,omitempty"`
+	MACLength        int         `json:"maclength,omitempty"`
+	RESLength        int         `json:"reslength,omitempty"`
+	CKLength         int         `json:"cklength,omitempty"`
+	IKLength         int         `json:"iklength,omitempty"`
+	KeccakIterations int         `json:"keccak_iterations,omitempty"`
+	DebugHook        DebugHook   `json:"-"`
+	RoundHook        RoundHook   `json:"-"`
+	KeyProvider      KeyProvider `json:"-"`
+	Tracer           *Tracer     `json:"-"`
+
+	// ExperimentalKeccakRounds, when non-zero, replaces Keccak-f[1600]
+	// with Keccak-p[1600, n]. See WithExperimentalKeccakRounds.
+	ExperimentalKeccakRounds int `json:"-"`
 }
 
 // Option configures TUAK parameters.
@@ -63,6 +73,50 @@ func WithDebugHook(h DebugHook) Option {
 	}
 }
 
+// WithRoundHook reports the state after theta, rho, pi, chi and iota of
+// all 24 rounds of every Keccak permutation. It slows the permutation
+// down considerably.
+func WithRoundHook(h RoundHook) Option {
+	return func(o *Options) {
+		o.RoundHook = h
+	}
+}
+
+// WithExperimentalKeccakRounds runs every TUAK function with the
+// reduced-round permutation Keccak-p[1600, n], the last n of the 24 rounds,
+// for cryptanalysis experiments. The results are NOT TUAK: they do not
+// conform to TS 35.231 and must never be used for authentication. n must be
+// 1 to 24; zero restores Keccak-f[1600]. The setting is not encoded by
+// MarshalJSON or MarshalText.
+func WithExperimentalKeccakRounds(n int) Option {
+	return func(o *Options) {
+		o.ExperimentalKeccakRounds = n
+	}
+}
+
+// WithTracer logs every stage through l with the state decoded into named
+// fields, masking them according to r.
+func WithTracer(l *slog.Logger, r Redaction) Option {
+	return func(o *Options) {
+		o.Tracer = &Tracer{logger: l, redaction: r}
+	}
+}
+
+// WithKeyProvider obtains K and TOPc from p for each computation instead
+// of the values passed to the constructor.
+func WithKeyProvider(p KeyProvider) Option {
+	return func(o *Options) {
+		o.KeyProvider = p
+	}
+}
+
+// WithOptions copies every field of o, e.g. options loaded from storage.
+func WithOptions(o Options) Option {
+	return func(out *Options) {
+		*out = o
+	}
+}
+
 func applyOptions(k []byte, opts []Option) Options {
 	out := Options{
 		KeccakIterations: 1,
@@ -0,0 +1,310 @@
+// Package perso derives per-card TUAK credentials during SIM
+// personalisation: K from a batch master key and the card's ICCID and IMSI,
+// and TOPc from K and the operator TOP. The derivation is the NIST
+// SP 800-108 KDF in counter mode,
+//
+//	K = PRF(master, [1]_32 || Label || 0x00 || Context || [L]_32) || ...
+//
+// truncated to L bits, with HMAC-SHA-256 or AES-CMAC as the PRF. Context
+// is the ASCII ICCID, the ASCII IMSI, or both joined by "/".
+package perso
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"tuak"
+	"tuak/simfile"
+)
+
+// KDF selects the SP 800-108 pseudorandom function.
+type KDF string
+
+const (
+	// HMACSHA256 uses HMAC-SHA-256; the master key must be at least 16
+	// bytes.
+	HMACSHA256 KDF = "hmac-sha256"
+	// AESCMAC uses AES-CMAC (SP 800-38B); the master key must be a 16-,
+	// 24- or 32-byte AES key.
+	AESCMAC KDF = "aes-cmac"
+)
+
+// Context selects the card identifiers the derivation is bound to.
+type Context string
+
+const (
+	ContextICCID     Context = "iccid"
+	ContextIMSI      Context = "imsi"
+	ContextICCIDIMSI Context = "iccid/imsi"
+)
+
+// DefaultLabel is the SP 800-108 Label used unless WithLabel is given.
+const DefaultLabel = "TUAK K"
+
+// Card identifies one card of a batch. Extra columns (PIN1, PUK1, ...)
+// are copied to the output record.
+type Card struct {
+	ICCID string
+	IMSI  string
+	Extra map[string]string
+}
+
+// Personaliser derives the credentials of the cards of one batch.
+type Personaliser struct {
+	master   []byte
+	top      []byte
+	kdf      KDF
+	label    string
+	context  Context
+	kLength  int
+	tuakOpts []tuak.Option
+}
+
+// Option configures a Personaliser.
+type Option func(*Personaliser)
+
+// WithKDF selects the PRF; the default is HMACSHA256.
+func WithKDF(k KDF) Option {
+	return func(p *Personaliser) {
+		p.kdf = k
+	}
+}
+
+// WithLabel sets the SP 800-108 Label; the default is DefaultLabel.
+func WithLabel(label string) Option {
+	return func(p *Personaliser) {
+		p.label = label
+	}
+}
+
+// WithContext selects the identifiers bound into the derivation; the
+// default is ContextICCIDIMSI.
+func WithContext(c Context) Option {
+	return func(p *Personaliser) {
+		p.context = c
+	}
+}
+
+// WithKLength sets the K length in bits, 128 (default) or 256.
+func WithKLength(bits int) Option {
+	return func(p *Personaliser) {
+		p.kLength = bits
+	}
+}
+
+// WithTUAKOptions passes options (e.g. tuak.WithKeccakIterations) to
+// tuak.ComputeTOPc.
+func WithTUAKOptions(opts ...tuak.Option) Option {
+	return func(p *Personaliser) {
+		p.tuakOpts = opts
+	}
+}
+
+// New returns a Personaliser for a batch with the given master key and
+// operator TOP (32 bytes).
+func New(master, top []byte, opts ...Option) (*Personaliser, error) {
+	p := &Personaliser{
+		master:  append([]byte(nil), master...),
+		top:     append([]byte(nil), top...),
+		kdf:     HMACSHA256,
+		label:   DefaultLabel,
+		context: ContextICCIDIMSI,
+		kLength: 128,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	switch p.kdf {
+	case HMACSHA256:
+		if len(p.master) < 16 {
+			return nil, fmt.Errorf("perso: master key length %d bytes, want at least 16", len(p.master))
+		}
+	case AESCMAC:
+		if _, err := aes.NewCipher(p.master); err != nil {
+			return nil, fmt.Errorf("perso: master key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("perso: unknown KDF %q", p.kdf)
+	}
+	switch p.context {
+	case ContextICCID, ContextIMSI, ContextICCIDIMSI:
+	default:
+		return nil, fmt.Errorf("perso: unknown context %q", p.context)
+	}
+	if p.kLength != 128 && p.kLength != 256 {
+		return nil, fmt.Errorf("perso: invalid K length %d bits", p.kLength)
+	}
+	if len(p.top) != 32 {
+		return nil, fmt.Errorf("perso: invalid TOP length %d bytes", len(p.top))
+	}
+	return p, nil
+}
+
+// DeriveK derives the K of c.
+func (p *Personaliser) DeriveK(c Card) ([]byte, error) {
+	ctx, err := p.contextOf(c)
+	if err != nil {
+		return nil, err
+	}
+	return p.derive([]byte(p.label), ctx, p.kLength), nil
+}
+
+// Personalise derives K and TOPc for c.
+func (p *Personaliser) Personalise(c Card) (simfile.Record, error) {
+	k, err := p.DeriveK(c)
+	if err != nil {
+		return simfile.Record{}, err
+	}
+	topc, err := tuak.ComputeTOPc(k, p.top, p.tuakOpts...)
+	if err != nil {
+		return simfile.Record{}, fmt.Errorf("perso: IMSI %s: %w", c.IMSI, err)
+	}
+	extra := make(map[string]string, len(c.Extra))
+	for name, v := range c.Extra {
+		extra[name] = v
+	}
+	return simfile.Record{ICCID: c.ICCID, IMSI: c.IMSI, K: k, TOPc: topc, Extra: extra}, nil
+}
+
+// Batch personalises cards into a vendor file whose header records the
+// derivation parameters (never the master key or TOP); write it with
+// simfile.WriteOut or simfile.WriteCSV.
+func (p *Personaliser) Batch(cards []Card) (*simfile.File, error) {
+	f := &simfile.File{
+		Header: map[string]string{
+			"Quantity":    strconv.Itoa(len(cards)),
+			"KDF":         "SP800-108-CTR-" + string(p.kdf),
+			"KDF_Label":   p.label,
+			"KDF_Context": string(p.context),
+			"K_Length":    strconv.Itoa(p.kLength),
+		},
+		Records: make([]simfile.Record, 0, len(cards)),
+	}
+	for _, c := range cards {
+		r, err := p.Personalise(c)
+		if err != nil {
+			return nil, err
+		}
+		f.Records = append(f.Records, r)
+	}
+	return f, nil
+}
+
+// Verify reports whether r holds the K and TOPc derived for its ICCID and
+// IMSI, e.g. to check a file returned by the factory.
+func (p *Personaliser) Verify(r simfile.Record) error {
+	want, err := p.Personalise(Card{ICCID: r.ICCID, IMSI: r.IMSI})
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(r.K, want.K) != 1 || subtle.ConstantTimeCompare(r.TOPc, want.TOPc) != 1 {
+		return fmt.Errorf("perso: IMSI %s: K or TOPc does not match the derivation", r.IMSI)
+	}
+	return nil
+}
+
+func (p *Personaliser) contextOf(c Card) ([]byte, error) {
+	if err := checkDigits("IMSI", c.IMSI, 6, 15); err != nil {
+		return nil, err
+	}
+	if p.context != ContextIMSI {
+		if err := checkDigits("ICCID", c.ICCID, 18, 20); err != nil {
+			return nil, err
+		}
+	}
+	switch p.context {
+	case ContextICCID:
+		return []byte(c.ICCID), nil
+	case ContextIMSI:
+		return []byte(c.IMSI), nil
+	default:
+		return []byte(c.ICCID + "/" + c.IMSI), nil
+	}
+}
+
+func checkDigits(name, s string, lo, hi int) error {
+	if len(s) < lo || len(s) > hi {
+		return fmt.Errorf("perso: %s %q has %d digits, want %d to %d", name, s, len(s), lo, hi)
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("perso: %s %q is not all digits", name, s)
+		}
+	}
+	return nil
+}
+
+// derive is the SP 800-108 counter mode KDF with an 8-bit separator and
+// 32-bit counter and length fields.
+func (p *Personaliser) derive(label, context []byte, bits int) []byte {
+	fixed := append(append([]byte(nil), label...), 0x00)
+	fixed = append(fixed, context...)
+	fixed = binary.BigEndian.AppendUint32(fixed, uint32(bits))
+	var out []byte
+	for i := uint32(1); len(out) < bits/8; i++ {
+		in := binary.BigEndian.AppendUint32(nil, i)
+		in = append(in, fixed...)
+		out = append(out, p.prf(in)...)
+	}
+	return out[:bits/8]
+}
+
+func (p *Personaliser) prf(in []byte) []byte {
+	if p.kdf == AESCMAC {
+		block, _ := aes.NewCipher(p.master)
+		return cmac(block, in)
+	}
+	mac := hmac.New(sha256.New, p.master)
+	mac.Write(in)
+	return mac.Sum(nil)
+}
+
+// cmac computes CMAC (SP 800-38B, RFC 4493) of msg.
+func cmac(block cipher.Block, msg []byte) []byte {
+	const bs = aes.BlockSize
+	k1 := make([]byte, bs)
+	block.Encrypt(k1, k1)
+	k1 = dbl(k1)
+	k2 := dbl(k1)
+
+	n := (len(msg) + bs - 1) / bs
+	last := make([]byte, bs)
+	if n > 0 && len(msg)%bs == 0 {
+		subtle.XORBytes(last, msg[(n-1)*bs:], k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		copy(last, msg[(n-1)*bs:])
+		last[len(msg)-(n-1)*bs] = 0x80
+		subtle.XORBytes(last, last, k2)
+	}
+	x := make([]byte, bs)
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x, x, msg[i*bs:(i+1)*bs])
+		block.Encrypt(x, x)
+	}
+	subtle.XORBytes(x, x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// dbl doubles b in GF(2^128).
+func dbl(b []byte) []byte {
+	out := make([]byte, len(b))
+	var carry byte
+	for i := len(b) - 1; i >= 0; i-- {
+		out[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	if carry != 0 {
+		out[len(out)-1] ^= 0x87
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package perso
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"tuak"
+	"tuak/simfile"
+)
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493 section 4 and SP 800-38B D.3 (AES-256) examples.
+	msg := decodeHex(t, "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	for _, tc := range []struct {
+		key  string
+		n    int
+		want string
+	}{
+		{"2b7e151628aed2a6abf7158809cf4f3c", 0, "bb1d6929e95937287fa37d129b756746"},
+		{"2b7e151628aed2a6abf7158809cf4f3c", 16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{"2b7e151628aed2a6abf7158809cf4f3c", 40, "dfa66747de9ae63030ca32611497c827"},
+		{"2b7e151628aed2a6abf7158809cf4f3c", 64, "51f0bebf7e3b9d92fc49741779363cfe"},
+		{"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4", 0, "028962f61b7bf89efc6b551f4667d983"},
+		{"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4", 16, "28a7023f452e8f82bd4bf28d8c37c35c"},
+	} {
+		block, err := aes.NewCipher(decodeHex(t, tc.key))
+		if err != nil {
+			t.Fatalf("NewCipher: %v", err)
+		}
+		if got := hex.EncodeToString(cmac(block, msg[:tc.n])); got != tc.want {
+			t.Fatalf("CMAC(%s, %d bytes) = %s, want %s", tc.key, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestDeriveK(t *testing.T) {
+	master := make([]byte, 32)
+	for i := range master {
+		master[i] = byte(i)
+	}
+	top := bytes.Repeat([]byte{0x5a}, 32)
+	card := Card{ICCID: "8981000000000000001", IMSI: "001010000000001"}
+	for _, tc := range []struct {
+		opts []Option
+		want string
+	}{
+		{nil, "a708ba508cf7c982939c144ce0e8ae44"},
+		{[]Option{WithKLength(256)}, "cee3f27b223b6ff4ff5b5e8bbced9a672cc38db7d8652e51fe8122c7cc20de10"},
+		{[]Option{WithKDF(AESCMAC)}, "461ade750dd94838c919aab69c569241"},
+		{[]Option{WithKDF(AESCMAC), WithKLength(256)}, "8f4f59610a581d86e8b16daaae6ddf38db5ca70ed0478ec9c611149a1f105b5e"},
+	} {
+		p, err := New(master, top, tc.opts...)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		k, err := p.DeriveK(card)
+		if err != nil {
+			t.Fatalf("DeriveK: %v", err)
+		}
+		if got := hex.EncodeToString(k); got != tc.want {
+			t.Fatalf("%s/%d K = %s, want %s", p.kdf, p.kLength, got, tc.want)
+		}
+	}
+
+	p, err := New(master[:16], top, WithKDF(AESCMAC), WithContext(ContextIMSI), WithKLength(256))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	k, err := p.DeriveK(Card{IMSI: card.IMSI})
+	if want := "b75e6b289032326227293a035b49d435fd540276c79cc9fbd9f78366c7457b61"; err != nil || hex.EncodeToString(k) != want {
+		t.Fatalf("IMSI-bound K = %x, %v; want %s", k, err, want)
+	}
+	if _, err := p.DeriveK(Card{IMSI: "00101x"}); err == nil {
+		t.Fatalf("DeriveK accepted a non-digit IMSI")
+	}
+
+	for _, opts := range [][]Option{
+		{WithKDF("sha1")},
+		{WithKLength(192)},
+		{WithContext("msisdn")},
+		{WithKDF(AESCMAC), func(p *Personaliser) { p.master = p.master[:20] }},
+	} {
+		if _, err := New(master, top, opts...); err == nil {
+			t.Fatalf("New accepted invalid options")
+		}
+	}
+	if _, err := New(master[:15], top); err == nil {
+		t.Fatalf("New accepted a 15-byte HMAC master key")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	master := bytes.Repeat([]byte{0x42}, 32)
+	top := bytes.Repeat([]byte{0x5a}, 32)
+	p, err := New(master, top, WithKLength(256), WithTUAKOptions(tuak.WithKeccakIterations(2)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	cards := []Card{
+		{ICCID: "8981000000000000001", IMSI: "001010000000001", Extra: map[string]string{"PIN1": "1234"}},
+		{ICCID: "8981000000000000002", IMSI: "001010000000002", Extra: map[string]string{"PIN1": "5678"}},
+	}
+	f, err := p.Batch(cards)
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	var out bytes.Buffer
+	if err := simfile.WriteOut(&out, f); err != nil {
+		t.Fatalf("WriteOut: %v", err)
+	}
+	if strings.Contains(out.String(), hex.EncodeToString(master)) || strings.Contains(out.String(), hex.EncodeToString(top)) {
+		t.Fatalf("output file leaks the master key or TOP:\n%s", out.String())
+	}
+
+	// The factory file parses back and its TOPc values cross-check.
+	parsed, err := simfile.ParseOut(&out, simfile.WithTOP(top, tuak.WithKeccakIterations(2)))
+	if err != nil {
+		t.Fatalf("ParseOut: %v", err)
+	}
+	if parsed.Header["KDF"] != "SP800-108-CTR-hmac-sha256" || parsed.Header["K_Length"] != "256" || len(parsed.Records) != 2 {
+		t.Fatalf("parsed = %+v", parsed)
+	}
+	for i, r := range parsed.Records {
+		if len(r.K) != 32 || r.Extra["PIN1"] != cards[i].Extra["PIN1"] {
+			t.Fatalf("record %d = %+v", i, r)
+		}
+		if err := p.Verify(r); err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+	}
+	if bytes.Equal(parsed.Records[0].K, parsed.Records[1].K) {
+		t.Fatalf("cards share K")
+	}
+	parsed.Records[1].K[0] ^= 1
+	if err := p.Verify(parsed.Records[1]); err == nil {
+		t.Fatalf("Verify accepted a modified K")
+	}
+	if _, err := p.Batch([]Card{{IMSI: "001010000000003"}}); err == nil {
+		t.Fatalf("Batch accepted a card without ICCID")
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
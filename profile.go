@@ -0,0 +1,148 @@
+package tuak
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownProfile is returned by LookupProfile for unregistered names.
+var ErrUnknownProfile = errors.New("tuak: unknown profile")
+
+// Profile is a named set of TUAK parameters fixed per deployment. It only
+// holds serialisable fields; field names follow testvectors.TUAKVector.
+type Profile struct {
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// KLength is optional; zero derives it from K.
+	KLength          int `json:"klength,omitempty" yaml:"klength,omitempty" toml:"klength,omitempty"`
+	MACLength        int `json:"maclength" yaml:"maclength" toml:"maclength"`
+	RESLength        int `json:"reslength" yaml:"reslength" toml:"reslength"`
+	CKLength         int `json:"cklength" yaml:"cklength" toml:"cklength"`
+	IKLength         int `json:"iklength" yaml:"iklength" toml:"iklength"`
+	KeccakIterations int `json:"keccak_iterations" yaml:"keccak_iterations" toml:"keccak_iterations"`
+}
+
+// Validate checks every length against the values accepted by the INSTANCE
+// encodings of f1 and f2345 and requires at least one Keccak iteration.
+func (p Profile) Validate() error {
+	kLen := p.KLength
+	if kLen == 0 {
+		kLen = 128
+	}
+	if _, err := (Instance{Function: FunctionF1, MACLength: p.MACLength, KLength: kLen}).Byte(); err != nil {
+		return p.wrap(err)
+	}
+	f2345 := Instance{Function: FunctionF2345, RESLength: p.RESLength, CKLength: p.CKLength, IKLength: p.IKLength, KLength: kLen}
+	if _, err := f2345.Byte(); err != nil {
+		return p.wrap(err)
+	}
+	if p.KeccakIterations < 1 {
+		return p.wrap(fmt.Errorf("tuak: invalid Keccak iterations %d", p.KeccakIterations))
+	}
+	return nil
+}
+
+func (p Profile) wrap(err error) error {
+	return fmt.Errorf("%w (profile %q)", err, p.Name)
+}
+
+// Options returns the profile as Options.
+func (p Profile) Options() Options {
+	return Options{
+		KLength:          p.KLength,
+		MACLength:        p.MACLength,
+		RESLength:        p.RESLength,
+		CKLength:         p.CKLength,
+		IKLength:         p.IKLength,
+		KeccakIterations: p.KeccakIterations,
+	}
+}
+
+// ProfileFromOptions returns the serialisable fields of o as a named profile.
+func ProfileFromOptions(name string, o Options) Profile {
+	return Profile{
+		Name:             name,
+		KLength:          o.KLength,
+		MACLength:        o.MACLength,
+		RESLength:        o.RESLength,
+		CKLength:         o.CKLength,
+		IKLength:         o.IKLength,
+		KeccakIterations: o.KeccakIterations,
+	}
+}
+
+// WithProfile sets the length and iteration fields from p, leaving the
+// debug hook and key provider untouched.
+func WithProfile(p Profile) Option {
+	return func(o *Options) {
+		o.KLength = p.KLength
+		o.MACLength = p.MACLength
+		o.RESLength = p.RESLength
+		o.CKLength = p.CKLength
+		o.IKLength = p.IKLength
+		o.KeccakIterations = p.KeccakIterations
+	}
+}
+
+var profiles = struct {
+	sync.RWMutex
+	m map[string]Profile
+}{m: make(map[string]Profile)}
+
+func init() {
+	// Parameter sets of the TS 35.233 test data (see testvectors/testdata/ts35233_vectors.json).
+	for _, p := range []Profile{
+		{Name: "ts35233-set1", KLength: 128, MACLength: 64, RESLength: 32, CKLength: 128, IKLength: 128, KeccakIterations: 1},
+		{Name: "ts35233-set2", KLength: 256, MACLength: 128, RESLength: 64, CKLength: 128, IKLength: 128, KeccakIterations: 1},
+		{Name: "ts35233-set3", KLength: 256, MACLength: 256, RESLength: 64, CKLength: 128, IKLength: 256, KeccakIterations: 1},
+		{Name: "ts35233-set4", KLength: 128, MACLength: 128, RESLength: 128, CKLength: 128, IKLength: 128, KeccakIterations: 1},
+		{Name: "ts35233-set5", KLength: 256, MACLength: 64, RESLength: 256, CKLength: 256, IKLength: 128, KeccakIterations: 1},
+		{Name: "ts35233-set6", KLength: 256, MACLength: 256, RESLength: 256, CKLength: 256, IKLength: 256, KeccakIterations: 2},
+	} {
+		if err := RegisterProfile(p); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterProfile validates p and registers it under p.Name. Registering
+// an existing name is an error.
+func RegisterProfile(p Profile) error {
+	if p.Name == "" {
+		return errors.New("tuak: profile without name")
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	profiles.Lock()
+	defer profiles.Unlock()
+	if _, ok := profiles.m[p.Name]; ok {
+		return fmt.Errorf("tuak: profile %q already registered", p.Name)
+	}
+	profiles.m[p.Name] = p
+	return nil
+}
+
+// LookupProfile returns the registered profile with the given name.
+func LookupProfile(name string) (Profile, error) {
+	profiles.RLock()
+	defer profiles.RUnlock()
+	p, ok := profiles.m[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w %q", ErrUnknownProfile, name)
+	}
+	return p, nil
+}
+
+// ProfileNames returns the registered profile names in sorted order.
+func ProfileNames() []string {
+	profiles.RLock()
+	defer profiles.RUnlock()
+	names := make([]string, 0, len(profiles.m))
+	for name := range profiles.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
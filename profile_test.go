@@ -0,0 +1,88 @@
+package tuak
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"tuak/testvectors"
+)
+
+func TestBuiltinProfilesVectors(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		p, err := LookupProfile(fmt.Sprintf("ts35233-set%d", v.ID))
+		if err != nil {
+			t.Fatalf("LookupProfile: %v", err)
+		}
+		tk, err := NewWithTOPc(decodeHex(t, v.K), decodeHex(t, v.Topc), decodeHex(t, v.Rand),
+			decodeHex(t, v.SQN), decodeHex(t, v.AMF), WithProfile(p))
+		if err != nil {
+			t.Fatalf("NewWithTOPc: %v", err)
+		}
+		mac, err := tk.F1()
+		if err != nil {
+			t.Fatalf("vector %d F1: %v", v.ID, err)
+		}
+		res, ck, ik, _, err := tk.F2345()
+		if err != nil {
+			t.Fatalf("vector %d F2345: %v", v.ID, err)
+		}
+		if !bytes.Equal(mac, decodeHex(t, v.F1)) || !bytes.Equal(res, decodeHex(t, v.F2)) ||
+			!bytes.Equal(ck, decodeHex(t, v.F3)) || !bytes.Equal(ik, decodeHex(t, v.F4)) {
+			t.Fatalf("profile %s does not reproduce vector %d", p.Name, v.ID)
+		}
+	}
+}
+
+func TestProfileValidate(t *testing.T) {
+	base := Profile{Name: "x", MACLength: 64, RESLength: 64, CKLength: 128, IKLength: 128, KeccakIterations: 1}
+	if err := base.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	for _, mutate := range []func(*Profile){
+		func(p *Profile) { p.KLength = 192 },
+		func(p *Profile) { p.MACLength = 32 },
+		func(p *Profile) { p.RESLength = 16 },
+		func(p *Profile) { p.CKLength = 64 },
+		func(p *Profile) { p.IKLength = 512 },
+		func(p *Profile) { p.KeccakIterations = 0 },
+	} {
+		p := base
+		mutate(&p)
+		if err := p.Validate(); err == nil {
+			t.Fatalf("Validate accepted %+v", p)
+		}
+	}
+}
+
+func TestRegisterProfile(t *testing.T) {
+	p := Profile{Name: "test-register", KLength: 128, MACLength: 128, RESLength: 64, CKLength: 128, IKLength: 128, KeccakIterations: 3}
+	if err := RegisterProfile(p); err != nil {
+		t.Fatalf("RegisterProfile: %v", err)
+	}
+	if err := RegisterProfile(p); err == nil {
+		t.Fatalf("RegisterProfile accepted a duplicate name")
+	}
+	if err := RegisterProfile(Profile{MACLength: 64}); err == nil {
+		t.Fatalf("RegisterProfile accepted an unnamed profile")
+	}
+	got, err := LookupProfile(p.Name)
+	if err != nil || got != p {
+		t.Fatalf("LookupProfile = %+v, %v", got, err)
+	}
+	if got := ProfileFromOptions(p.Name, p.Options()); got != p {
+		t.Fatalf("ProfileFromOptions round trip = %+v", got)
+	}
+	if _, err := LookupProfile("missing"); !errors.Is(err, ErrUnknownProfile) {
+		t.Fatalf("LookupProfile(missing): err = %v", err)
+	}
+	names := ProfileNames()
+	if len(names) < 7 || names[0] > names[len(names)-1] {
+		t.Fatalf("ProfileNames = %v", names)
+	}
+}
@@ -0,0 +1,417 @@
+// Package rotate re-derives the TOPc of a whole subscriber base when the
+// operator rotates its TOP. Each subscriber's TOPc is checked against K
+// and the old TOP and replaced by ComputeTOPc(K, new TOP); the old value
+// is kept in PreviousTOPc until Finish ends the transition.
+//
+// Subscribers are streamed from a store.Ranger, computed in parallel and
+// written to the destination in input order. The returned Report carries
+// counts and SHA-256 digests of the subscriber base before and after, and
+// Verify checks a rotated store against it.
+package rotate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"hash"
+	"runtime"
+
+	"tuak"
+	"tuak/keywrap"
+	"tuak/store"
+)
+
+// Sink receives rotated subscribers; store.SubscriberStore and
+// store.FileWriter implement it. Put is never called concurrently.
+type Sink interface {
+	Put(ctx context.Context, sub *store.Subscriber) error
+}
+
+// Report summarises a rotation. Digests are SHA-256 over one line
+//
+//	id k topc topc_previous key_wrap\n
+//
+// per subscriber in input order, with the byte fields in lowercase hex
+// (wrapped values as stored). OutputDigest covers every subscriber as it
+// is after the rotation, whether or not it was written. TOP check values
+// are the first 8 bytes of SHA-256(TOP) and identify a TOP without
+// revealing it.
+type Report struct {
+	OldTOPCheck  tuak.Hex  `json:"old_top_check"`
+	NewTOPCheck  tuak.Hex  `json:"new_top_check"`
+	Total        int       `json:"total"`
+	Rotated      int       `json:"rotated"`
+	Unchanged    int       `json:"unchanged"`
+	Failed       int       `json:"failed"`
+	Failures     []Failure `json:"failures,omitempty"`
+	InputDigest  tuak.Hex  `json:"input_sha256"`
+	OutputDigest tuak.Hex  `json:"output_sha256"`
+}
+
+// Failure records a subscriber that could not be rotated and was left as
+// it was.
+type Failure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+type config struct {
+	workers     int
+	kek         []byte
+	changedOnly bool
+}
+
+// Option configures Rotate, Verify and Finish.
+type Option func(*config)
+
+// WithWorkers sets the number of subscribers computed in parallel; the
+// default is runtime.GOMAXPROCS(0).
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		c.workers = n
+	}
+}
+
+// WithKEK unwraps and rewraps subscribers whose KeyWrap names a keywrap
+// scheme. Without it such subscribers fail.
+func WithKEK(kek []byte) Option {
+	return func(c *config) {
+		c.kek = append([]byte(nil), kek...)
+	}
+}
+
+// WithChangedOnly writes only the subscribers Rotate or Finish changes,
+// for updating a store in place. By default every subscriber is written,
+// as needed to produce a complete copy.
+func WithChangedOnly() Option {
+	return func(c *config) {
+		c.changedOnly = true
+	}
+}
+
+func newConfig(opts []Option) (*config, error) {
+	c := &config{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.workers < 1 {
+		return nil, fmt.Errorf("rotate: invalid worker count %d", c.workers)
+	}
+	return c, nil
+}
+
+type outcome int
+
+const (
+	rotated outcome = iota
+	unchanged
+	failed
+)
+
+// result is the outcome for the seq-th subscriber of the input.
+type result struct {
+	seq     int
+	in, out *store.Subscriber
+	outcome outcome
+	err     error
+}
+
+// Rotate rotates every subscriber of src from oldTOP to newTOP and writes
+// the result to dst, which may be src itself (with WithChangedOnly) or
+// nil for a dry run. Subscribers whose TOPc already derives from newTOP
+// are counted as unchanged, so an interrupted rotation can be run again.
+// Subscribers whose TOPc derives from neither TOP are reported as
+// failures and written unchanged. In-place rotation rewrites the whole
+// record, so a concurrent SQN update of a rotated subscriber may be lost;
+// rotate while the store is not serving.
+func Rotate(ctx context.Context, src store.Ranger, dst Sink, oldTOP, newTOP []byte, opts ...Option) (*Report, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(oldTOP) != 32 || len(newTOP) != 32 {
+		return nil, fmt.Errorf("rotate: invalid TOP length %d/%d bytes, want 32", len(oldTOP), len(newTOP))
+	}
+	rep := &Report{OldTOPCheck: checkValue(oldTOP), NewTOPCheck: checkValue(newTOP)}
+	in, out := sha256.New(), sha256.New()
+	err = c.run(ctx, src, func(sub *store.Subscriber) result {
+		return c.rotate(sub, oldTOP, newTOP)
+	}, func(r result) error {
+		rep.Total++
+		writeDigest(in, r.in)
+		switch r.outcome {
+		case rotated:
+			rep.Rotated++
+		case unchanged:
+			rep.Unchanged++
+		case failed:
+			rep.Failed++
+			rep.Failures = append(rep.Failures, Failure{ID: r.in.ID, Error: r.err.Error()})
+		}
+		writeDigest(out, r.out)
+		if dst == nil || (c.changedOnly && r.outcome != rotated) {
+			return nil
+		}
+		if err := dst.Put(ctx, r.out); err != nil {
+			return fmt.Errorf("rotate: %s: %w", r.out.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rep.InputDigest = in.Sum(nil)
+	rep.OutputDigest = out.Sum(nil)
+	return rep, nil
+}
+
+// Verify checks that r holds the subscriber base rep describes: the same
+// number of subscribers with the same output digest, and as many TOPc
+// values derived from newTOP as rep counts as rotated or unchanged.
+func Verify(ctx context.Context, r store.Ranger, rep *Report, newTOP []byte, opts ...Option) error {
+	c, err := newConfig(opts)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(checkValue(newTOP), rep.NewTOPCheck) {
+		return fmt.Errorf("rotate: TOP check value %x does not match the report's %x", checkValue(newTOP), rep.NewTOPCheck)
+	}
+	digest := sha256.New()
+	var total, current int
+	err = c.run(ctx, r, func(sub *store.Subscriber) result {
+		res := result{in: sub, out: sub, outcome: failed}
+		if ok, err := c.derivesFrom(sub, newTOP); err == nil && ok {
+			res.outcome = unchanged
+		}
+		return res
+	}, func(res result) error {
+		total++
+		if res.outcome == unchanged {
+			current++
+		}
+		writeDigest(digest, res.out)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	switch {
+	case total != rep.Total:
+		return fmt.Errorf("rotate: %d subscribers, report has %d", total, rep.Total)
+	case current != rep.Rotated+rep.Unchanged:
+		return fmt.Errorf("rotate: %d subscribers have TOPc from the new TOP, report has %d", current, rep.Rotated+rep.Unchanged)
+	case !bytes.Equal(digest.Sum(nil), rep.OutputDigest):
+		return fmt.Errorf("rotate: output digest %x does not match the report's %x", digest.Sum(nil), rep.OutputDigest)
+	}
+	return nil
+}
+
+// Finish ends a transition by clearing PreviousTOPc once every card uses
+// the new TOPc. It writes src to dst and returns the number of
+// subscribers that had a previous TOPc.
+func Finish(ctx context.Context, src store.Ranger, dst Sink, opts ...Option) (int, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = src.Range(ctx, func(sub *store.Subscriber) error {
+		if sub.PreviousTOPc != nil {
+			sub.PreviousTOPc = nil
+			n++
+		} else if c.changedOnly {
+			return nil
+		}
+		if err := dst.Put(ctx, sub); err != nil {
+			return fmt.Errorf("rotate: %s: %w", sub.ID, err)
+		}
+		return nil
+	})
+	return n, err
+}
+
+// run streams src through c.workers goroutines calling compute and passes
+// the results to emit one at a time in input order. The first error from
+// src or emit stops the pipeline and is returned.
+func (c *config) run(ctx context.Context, src store.Ranger, compute func(*store.Subscriber) result, emit func(result) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		seq int
+		sub *store.Subscriber
+	}
+	jobs := make(chan job, c.workers)
+	results := make(chan result, c.workers)
+	// window bounds the subscribers in flight, and so the reorder buffer.
+	window := make(chan struct{}, 4*c.workers)
+
+	var rangeErr error
+	go func() {
+		defer close(jobs)
+		seq := 0
+		rangeErr = src.Range(ctx, func(sub *store.Subscriber) error {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			jobs <- job{seq, sub}
+			seq++
+			return nil
+		})
+	}()
+
+	done := make(chan struct{})
+	for i := 0; i < c.workers; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for j := range jobs {
+				r := compute(j.sub)
+				r.seq = j.seq
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < c.workers; i++ {
+			<-done
+		}
+		close(results)
+	}()
+
+	var emitErr error
+	pending := map[int]result{}
+	next := 0
+	for r := range results {
+		if emitErr != nil {
+			continue
+		}
+		pending[r.seq] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-window
+			if err := emit(r); err != nil {
+				emitErr = err
+				cancel()
+				break
+			}
+		}
+	}
+	if emitErr != nil {
+		return emitErr
+	}
+	return rangeErr
+}
+
+// rotate computes the result for one subscriber.
+func (c *config) rotate(sub *store.Subscriber, oldTOP, newTOP []byte) result {
+	res := result{in: sub, out: sub}
+	fail := func(err error) result {
+		res.outcome, res.err = failed, err
+		return res
+	}
+	k, topc, err := c.unwrap(sub)
+	if err != nil {
+		return fail(err)
+	}
+	defer c.erase(sub, k, topc)
+	opts := sub.TUAKOptions()
+	newTOPc, err := tuak.ComputeTOPc(k, newTOP, opts...)
+	if err != nil {
+		return fail(err)
+	}
+	defer clear(newTOPc)
+	if subtle.ConstantTimeCompare(topc, newTOPc) == 1 {
+		res.outcome = unchanged
+		return res
+	}
+	oldTOPc, err := tuak.ComputeTOPc(k, oldTOP, opts...)
+	if err != nil {
+		return fail(err)
+	}
+	defer clear(oldTOPc)
+	if subtle.ConstantTimeCompare(topc, oldTOPc) != 1 {
+		return fail(fmt.Errorf("rotate: %s: TOPc derives from neither the old nor the new TOP", sub.ID))
+	}
+	stored, err := c.wrap(sub, newTOPc)
+	if err != nil {
+		return fail(err)
+	}
+	out := *sub
+	out.PreviousTOPc = sub.TOPc
+	out.TOPc = stored
+	res.out, res.outcome = &out, rotated
+	return res
+}
+
+// derivesFrom reports whether the TOPc of sub derives from top.
+func (c *config) derivesFrom(sub *store.Subscriber, top []byte) (bool, error) {
+	k, topc, err := c.unwrap(sub)
+	if err != nil {
+		return false, err
+	}
+	defer c.erase(sub, k, topc)
+	want, err := tuak.ComputeTOPc(k, top, sub.TUAKOptions()...)
+	if err != nil {
+		return false, err
+	}
+	defer clear(want)
+	return subtle.ConstantTimeCompare(topc, want) == 1, nil
+}
+
+func (c *config) unwrap(sub *store.Subscriber) ([]byte, []byte, error) {
+	if sub.KeyWrap == "" {
+		return sub.K, sub.TOPc, nil
+	}
+	if c.kek == nil {
+		return nil, nil, fmt.Errorf("rotate: %s: keys wrapped with %s and no KEK given", sub.ID, sub.KeyWrap)
+	}
+	scheme := keywrap.Scheme(sub.KeyWrap)
+	k, err := keywrap.Unwrap(scheme, c.kek, sub.K)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rotate: %s: unwrap K: %w", sub.ID, err)
+	}
+	topc, err := keywrap.Unwrap(scheme, c.kek, sub.TOPc)
+	if err != nil {
+		clear(k)
+		return nil, nil, fmt.Errorf("rotate: %s: unwrap TOPc: %w", sub.ID, err)
+	}
+	return k, topc, nil
+}
+
+// erase clears keys returned by unwrap, which are copies only for wrapped
+// subscribers.
+func (c *config) erase(sub *store.Subscriber, k, topc []byte) {
+	if sub.KeyWrap != "" {
+		clear(k)
+		clear(topc)
+	}
+}
+
+func (c *config) wrap(sub *store.Subscriber, topc []byte) ([]byte, error) {
+	if sub.KeyWrap == "" {
+		return append([]byte(nil), topc...), nil
+	}
+	b, err := keywrap.Wrap(keywrap.Scheme(sub.KeyWrap), c.kek, topc)
+	if err != nil {
+		return nil, fmt.Errorf("rotate: %s: wrap TOPc: %w", sub.ID, err)
+	}
+	return b, nil
+}
+
+func writeDigest(h hash.Hash, sub *store.Subscriber) {
+	fmt.Fprintf(h, "%s %x %x %x %s\n", sub.ID, sub.K, sub.TOPc, sub.PreviousTOPc, sub.KeyWrap)
+}
+
+func checkValue(top []byte) tuak.Hex {
+	sum := sha256.Sum256(top)
+	return sum[:8]
+}
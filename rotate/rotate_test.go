@@ -0,0 +1,243 @@
+package rotate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"tuak"
+	"tuak/keywrap"
+	"tuak/store"
+)
+
+var (
+	oldTOP = bytes.Repeat([]byte{0x11}, 32)
+	newTOP = bytes.Repeat([]byte{0x22}, 32)
+	kek    = bytes.Repeat([]byte{0x33}, 16)
+)
+
+func mustTOPc(t *testing.T, k, top []byte, opts ...tuak.Option) []byte {
+	t.Helper()
+	topc, err := tuak.ComputeTOPc(k, top, opts...)
+	if err != nil {
+		t.Fatalf("ComputeTOPc: %v", err)
+	}
+	return topc
+}
+
+// subscriberBase returns a store with two subscribers under oldTOP, one
+// already under newTOP, one under neither and one wrapped under kek.
+func subscriberBase(t *testing.T) *store.Memory {
+	t.Helper()
+	ctx := context.Background()
+	m := store.NewMemory()
+	k1 := bytes.Repeat([]byte{0x01}, 16)
+	k2 := bytes.Repeat([]byte{0x02}, 32)
+	k3 := bytes.Repeat([]byte{0x03}, 16)
+	k5 := bytes.Repeat([]byte{0x05}, 16)
+	opts2 := tuak.Options{KLength: 256, KeccakIterations: 2}
+	wk5, err := keywrap.Wrap(keywrap.SchemeAESKW, kek, k5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wtopc5, err := keywrap.Wrap(keywrap.SchemeAESKW, kek, mustTOPc(t, k5, oldTOP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []*store.Subscriber{
+		{ID: "imsi-1", K: k1, TOPc: mustTOPc(t, k1, oldTOP)},
+		{ID: "imsi-2", K: k2, TOPc: mustTOPc(t, k2, oldTOP, tuak.WithOptions(opts2)), Options: opts2},
+		{ID: "imsi-3", K: k3, TOPc: mustTOPc(t, k3, newTOP)},
+		{ID: "imsi-4", K: k3, TOPc: bytes.Repeat([]byte{0x44}, 32)},
+		{ID: "imsi-5", K: wk5, TOPc: wtopc5, KeyWrap: string(keywrap.SchemeAESKW)},
+	} {
+		if err := m.Put(ctx, sub); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	return m
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	src := subscriberBase(t)
+	dst := store.NewMemory()
+	rep, err := Rotate(ctx, src, dst, oldTOP, newTOP, WithKEK(kek), WithWorkers(3))
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rep.Total != 5 || rep.Rotated != 3 || rep.Unchanged != 1 || rep.Failed != 1 {
+		t.Fatalf("report counts = %d/%d/%d/%d, want 5/3/1/1", rep.Total, rep.Rotated, rep.Unchanged, rep.Failed)
+	}
+	if len(rep.Failures) != 1 || rep.Failures[0].ID != "imsi-4" {
+		t.Fatalf("Failures = %+v, want imsi-4", rep.Failures)
+	}
+
+	for _, id := range []string{"imsi-1", "imsi-2"} {
+		before, _ := src.Get(ctx, id)
+		after, err := dst.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if want := mustTOPc(t, after.K, newTOP, after.TUAKOptions()...); !bytes.Equal(after.TOPc, want) {
+			t.Errorf("%s: TOPc = %x, want %x", id, after.TOPc, want)
+		}
+		if !bytes.Equal(after.PreviousTOPc, before.TOPc) {
+			t.Errorf("%s: PreviousTOPc = %x, want the old TOPc %x", id, after.PreviousTOPc, before.TOPc)
+		}
+	}
+	wrapped, _ := dst.Get(ctx, "imsi-5")
+	topc, err := keywrap.Unwrap(keywrap.SchemeAESKW, kek, wrapped.TOPc)
+	if err != nil {
+		t.Fatalf("Unwrap: %v", err)
+	}
+	if want := mustTOPc(t, bytes.Repeat([]byte{0x05}, 16), newTOP); !bytes.Equal(topc, want) {
+		t.Errorf("wrapped TOPc = %x, want %x", topc, want)
+	}
+	for _, id := range []string{"imsi-3", "imsi-4"} {
+		before, _ := src.Get(ctx, id)
+		after, _ := dst.Get(ctx, id)
+		if !bytes.Equal(after.TOPc, before.TOPc) || after.PreviousTOPc != nil {
+			t.Errorf("%s changed", id)
+		}
+	}
+
+	if err := Verify(ctx, dst, rep, newTOP, WithKEK(kek)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := Verify(ctx, src, rep, newTOP, WithKEK(kek)); err == nil {
+		t.Fatalf("Verify accepted the unrotated store")
+	}
+	if err := Verify(ctx, dst, rep, oldTOP, WithKEK(kek)); err == nil {
+		t.Fatalf("Verify accepted the wrong TOP")
+	}
+
+	// Rotating again is a no-op, and the report does not depend on the
+	// number of workers.
+	again, err := Rotate(ctx, dst, nil, oldTOP, newTOP, WithKEK(kek), WithWorkers(1))
+	if err != nil {
+		t.Fatalf("Rotate again: %v", err)
+	}
+	if again.Rotated != 0 || again.Unchanged != 4 || again.Failed != 1 {
+		t.Fatalf("second rotation counts = %d/%d/%d, want 0/4/1", again.Rotated, again.Unchanged, again.Failed)
+	}
+	if !bytes.Equal(again.InputDigest, rep.OutputDigest) || !bytes.Equal(again.OutputDigest, rep.OutputDigest) {
+		t.Fatalf("second rotation digests differ from the first output digest")
+	}
+}
+
+func TestRotateWithoutKEK(t *testing.T) {
+	rep, err := Rotate(context.Background(), subscriberBase(t), nil, oldTOP, newTOP)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rep.Failed != 2 || rep.Failures[1].ID != "imsi-5" {
+		t.Fatalf("Failures = %+v, want imsi-4 and imsi-5", rep.Failures)
+	}
+}
+
+func TestRotateInPlaceAndFinish(t *testing.T) {
+	ctx := context.Background()
+	m := subscriberBase(t)
+	rec := &recorder{Sink: m}
+	rep, err := Rotate(ctx, m, rec, oldTOP, newTOP, WithKEK(kek), WithChangedOnly())
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if want := []string{"imsi-1", "imsi-2", "imsi-5"}; !reflect.DeepEqual(rec.ids, want) {
+		t.Fatalf("Put %q, want %q", rec.ids, want)
+	}
+	if err := Verify(ctx, m, rep, newTOP, WithKEK(kek)); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	rec.ids = nil
+	n, err := Finish(ctx, m, rec, WithChangedOnly())
+	if err != nil || n != 3 {
+		t.Fatalf("Finish = %d, %v, want 3", n, err)
+	}
+	if want := []string{"imsi-1", "imsi-2", "imsi-5"}; !reflect.DeepEqual(rec.ids, want) {
+		t.Fatalf("Finish put %q, want %q", rec.ids, want)
+	}
+	err = m.Range(ctx, func(sub *store.Subscriber) error {
+		if sub.PreviousTOPc != nil {
+			t.Errorf("%s: PreviousTOPc kept after Finish", sub.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+}
+
+type recorder struct {
+	Sink
+	ids []string
+}
+
+func (r *recorder) Put(ctx context.Context, sub *store.Subscriber) error {
+	r.ids = append(r.ids, sub.ID)
+	return r.Sink.Put(ctx, sub)
+}
+
+type failingSink struct{ after int }
+
+func (f *failingSink) Put(context.Context, *store.Subscriber) error {
+	if f.after == 0 {
+		return errors.New("disk full")
+	}
+	f.after--
+	return nil
+}
+
+func TestRotateSinkError(t *testing.T) {
+	_, err := Rotate(context.Background(), subscriberBase(t), &failingSink{after: 2}, oldTOP, newTOP, WithKEK(kek))
+	if err == nil || err.Error() != "rotate: imsi-3: disk full" {
+		t.Fatalf("err = %v, want the Put error of the third subscriber", err)
+	}
+}
+
+func TestRotateFileStream(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.json"), filepath.Join(dir, "out.json")
+	var subs []*store.Subscriber
+	for i := 0; i < 100; i++ {
+		k := bytes.Repeat([]byte{byte(i)}, 16)
+		subs = append(subs, &store.Subscriber{ID: fmt.Sprintf("imsi-%03d", i), K: k, TOPc: mustTOPc(t, k, oldTOP)})
+	}
+	if err := store.WriteFile(in, subs); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	fw, err := store.CreateFile(out)
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	rep, err := Rotate(ctx, store.NewFileScanner(in), fw, oldTOP, newTOP, WithWorkers(8))
+	if err != nil {
+		fw.Discard()
+		t.Fatalf("Rotate: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if rep.Total != 100 || rep.Rotated != 100 {
+		t.Fatalf("report counts = %d/%d, want 100/100", rep.Total, rep.Rotated)
+	}
+	if err := Verify(ctx, store.NewFileScanner(out), rep, newTOP); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	rotated, err := store.ReadFile(out)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	for i, sub := range rotated {
+		if sub.ID != subs[i].ID || !bytes.Equal(sub.PreviousTOPc, subs[i].TOPc) {
+			t.Fatalf("subscriber %d = %s, want %s with the old TOPc kept", i, sub.ID, subs[i].ID)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package tuak
+
+import (
+	"bytes"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+
+	"tuak/keccak"
+)
+
+// ErrSelfTestFailed is returned by SelfTest, and latched for every later
+// ComputeTOPc and F* call, when a known-answer test fails.
+var ErrSelfTestFailed = errors.New("tuak: self test failed")
+
+//go:embed testvectors/testdata/ts35232_keccak.json testvectors/testdata/ts35233_vectors.json
+var selfTestFixtures embed.FS
+
+// selfTestFS holds the known answers; tests substitute corrupted copies.
+var selfTestFS fs.FS = selfTestFixtures
+
+var (
+	selfTestLatch   atomic.Pointer[error]
+	selfTestOnUse   atomic.Bool
+	selfTestOnUseMu sync.Mutex
+	// selfTestOnUseOK is set, under selfTestOnUseMu, once the first-use
+	// check has run; later calls test it without locking.
+	selfTestOnUseOK atomic.Bool
+)
+
+// SelfTest runs the power-on known-answer tests: Keccak-f[1600] test set 1
+// of TS 35.232 and TS 35.233 test set 1 through TOPc, f1, f1*, f2-f5 and
+// f5*. A failure is latched: from then on SelfTest, ComputeTOPc and every
+// F* method return an error wrapping ErrSelfTestFailed for the life of the
+// process.
+func SelfTest() error {
+	if err := selfTestLatch.Load(); err != nil {
+		return *err
+	}
+	if err := runSelfTest(); err != nil {
+		err = fmt.Errorf("%w: %v", ErrSelfTestFailed, err)
+		selfTestLatch.CompareAndSwap(nil, &err)
+		return *selfTestLatch.Load()
+	}
+	return nil
+}
+
+// SetSelfTestOnFirstUse makes the first ComputeTOPc or F* call run
+// SelfTest before computing anything. It is off by default.
+func SetSelfTestOnFirstUse(enabled bool) {
+	selfTestOnUse.Store(enabled)
+}
+
+// checkSelfTest returns the latched self-test failure, running SelfTest
+// first when the first-use check is enabled and has not run yet.
+func checkSelfTest() error {
+	if selfTestOnUse.Load() && !selfTestOnUseOK.Load() {
+		selfTestOnUseMu.Lock()
+		if !selfTestOnUseOK.Load() {
+			_ = SelfTest()
+			selfTestOnUseOK.Store(true)
+		}
+		selfTestOnUseMu.Unlock()
+	}
+	if err := selfTestLatch.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+func runSelfTest() error {
+	var kf struct {
+		KeccakF1600 []struct {
+			In  string `json:"in"`
+			Out string `json:"out"`
+		} `json:"keccak_f1600"`
+	}
+	if err := readSelfTestJSON("ts35232_keccak.json", &kf); err != nil {
+		return err
+	}
+	if len(kf.KeccakF1600) == 0 {
+		return errors.New("no Keccak known answer")
+	}
+	d := katDecoder{}
+	in, want := d.hex(kf.KeccakF1600[0].In), d.hex(kf.KeccakF1600[0].Out)
+	if d.err != nil {
+		return d.err
+	}
+	out, err := keccak.PermuteF1600(in)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(out, want) {
+		return errors.New("Keccak-f[1600] known answer mismatch")
+	}
+
+	var tf struct {
+		Tests []struct {
+			K                string `json:"k"`
+			Rand             string `json:"rand"`
+			SQN              string `json:"sqn"`
+			AMF              string `json:"amf"`
+			Top              string `json:"top"`
+			Topc             string `json:"topc"`
+			F1               string `json:"f1"`
+			F1Star           string `json:"f1_star"`
+			F2               string `json:"f2"`
+			F3               string `json:"f3"`
+			F4               string `json:"f4"`
+			F5               string `json:"f5"`
+			F5Star           string `json:"f5_star"`
+			MAClength        int    `json:"maclength"`
+			CKlength         int    `json:"cklength"`
+			IKlength         int    `json:"iklength"`
+			RESLength        int    `json:"reslength"`
+			KeccakIterations int    `json:"keccak_iterations"`
+		} `json:"tests"`
+	}
+	if err := readSelfTestJSON("ts35233_vectors.json", &tf); err != nil {
+		return err
+	}
+	if len(tf.Tests) == 0 {
+		return errors.New("no TUAK known answer")
+	}
+	v := tf.Tests[0]
+	t := &TUAK{
+		k:    d.hex(v.K),
+		rand: d.hex(v.Rand),
+		sqn:  d.hex(v.SQN),
+		amf:  d.hex(v.AMF),
+		opts: Options{
+			MACLength:        v.MAClength,
+			RESLength:        v.RESLength,
+			CKLength:         v.CKlength,
+			IKLength:         v.IKlength,
+			KeccakIterations: v.KeccakIterations,
+		},
+	}
+	top, topc := d.hex(v.Top), d.hex(v.Topc)
+	if d.err != nil {
+		return d.err
+	}
+
+	got, err := computeTOPc(t.k, top, t.opts)
+	if err := katCheck("TOPc", got, topc, err); err != nil {
+		return err
+	}
+	got, err = t.f1(t.k, topc, false)
+	if err := katCheck("f1", got, d.hex(v.F1), err); err != nil {
+		return err
+	}
+	got, err = t.f1(t.k, topc, true)
+	if err := katCheck("f1*", got, d.hex(v.F1Star), err); err != nil {
+		return err
+	}
+	res, ck, ik, ak, err := t.f2345(t.k, topc)
+	if err := katCheck("f2-f5", bytes.Join([][]byte{res, ck, ik, ak}, nil), d.hex(v.F2+v.F3+v.F4+v.F5), err); err != nil {
+		return err
+	}
+	got, err = t.f5Star(t.k, topc)
+	if err := katCheck("f5*", got, d.hex(v.F5Star), err); err != nil {
+		return err
+	}
+	return d.err
+}
+
+func readSelfTestJSON(name string, v interface{}) error {
+	b, err := fs.ReadFile(selfTestFS, "testvectors/testdata/"+name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("decode %s: %w", name, err)
+	}
+	return nil
+}
+
+func katCheck(name string, got, want []byte, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if len(want) == 0 || !bytes.Equal(got, want) {
+		return fmt.Errorf("%s known answer mismatch", name)
+	}
+	return nil
+}
+
+// katDecoder decodes known-answer hex and keeps the first error.
+type katDecoder struct {
+	err error
+}
+
+func (d *katDecoder) hex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil && d.err == nil {
+		d.err = fmt.Errorf("decode known answer: %w", err)
+	}
+	return b
+}
@@ -0,0 +1,86 @@
+package tuak
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("SelfTest: %v", err)
+	}
+}
+
+func TestSelfTestLatch(t *testing.T) {
+	corruptSelfTest(t)
+	if err := SelfTest(); !errors.Is(err, ErrSelfTestFailed) || !strings.Contains(err.Error(), "f1 known answer") {
+		t.Fatalf("SelfTest: err = %v, want f1 failure", err)
+	}
+	selfTestFS = selfTestFixtures
+	if err := SelfTest(); !errors.Is(err, ErrSelfTestFailed) {
+		t.Fatalf("SelfTest after repair: err = %v, want latched failure", err)
+	}
+	assertLatched(t)
+}
+
+func TestSelfTestOnFirstUse(t *testing.T) {
+	corruptSelfTest(t)
+	SetSelfTestOnFirstUse(true)
+	t.Cleanup(func() {
+		SetSelfTestOnFirstUse(false)
+		selfTestOnUseOK.Store(false)
+	})
+	assertLatched(t)
+}
+
+// corruptSelfTest flips the expected f1 of the known answers and clears
+// the latch when the test ends.
+func corruptSelfTest(t *testing.T) {
+	t.Helper()
+	const name = "testvectors/testdata/ts35233_vectors.json"
+	b, err := fs.ReadFile(selfTestFixtures, name)
+	if err != nil {
+		t.Fatalf("read known answers: %v", err)
+	}
+	vectors := loadTUAKVector(t, 1)
+	corrupted := strings.Replace(string(b), `"f1": "`+vectors.F1+`"`, `"f1": "`+strings.Repeat("0", len(vectors.F1))+`"`, 1)
+	if corrupted == string(b) {
+		t.Fatalf("f1 of test set 1 not found")
+	}
+	keccak, err := fs.ReadFile(selfTestFixtures, "testvectors/testdata/ts35232_keccak.json")
+	if err != nil {
+		t.Fatalf("read known answers: %v", err)
+	}
+	selfTestFS = fstest.MapFS{
+		name: {Data: []byte(corrupted)},
+		"testvectors/testdata/ts35232_keccak.json": {Data: keccak},
+	}
+	t.Cleanup(func() {
+		selfTestFS = selfTestFixtures
+		selfTestLatch.Store(nil)
+	})
+}
+
+func assertLatched(t *testing.T) {
+	t.Helper()
+	data := loadTUAKVector(t, 1)
+	if _, err := ComputeTOPc(decodeHex(t, data.K), decodeHex(t, data.Top), optionsFromVector(data)...); !errors.Is(err, ErrSelfTestFailed) {
+		t.Fatalf("ComputeTOPc: err = %v, want ErrSelfTestFailed", err)
+	}
+	tk, err := newTUAKFromVector(t, data)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	if _, err := tk.F1(); !errors.Is(err, ErrSelfTestFailed) {
+		t.Fatalf("F1: err = %v, want ErrSelfTestFailed", err)
+	}
+	if _, _, _, _, err := tk.F2345(); !errors.Is(err, ErrSelfTestFailed) {
+		t.Fatalf("F2345: err = %v, want ErrSelfTestFailed", err)
+	}
+	if _, err := tk.F5Star(); !errors.Is(err, ErrSelfTestFailed) {
+		t.Fatalf("F5Star: err = %v, want ErrSelfTestFailed", err)
+	}
+}
@@ -0,0 +1,255 @@
+// Package simfile parses and writes SIM vendor personalisation output
+// files (".out" text and CSV) of TUAK subscriber records.
+package simfile
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tuak"
+	"tuak/keywrap"
+	"tuak/store"
+)
+
+// ErrTOPcMismatch is returned when a vendor TOPc differs from the value
+// recomputed from TOP.
+var ErrTOPcMismatch = errors.New("simfile: TOPc does not match TOP")
+
+// Record is one card from a vendor file with K and TOPc in clear.
+type Record struct {
+	ICCID string
+	IMSI  string
+	K     []byte
+	TOPc  []byte
+	// Extra holds the remaining columns (PIN1, PUK1, ADM1, ...) by upper-case name.
+	Extra map[string]string
+}
+
+// File is a parsed vendor file.
+type File struct {
+	// Header holds "Name : value" lines of a .out file header.
+	Header  map[string]string
+	Records []Record
+}
+
+// Option configures parsing.
+type Option func(*config)
+
+type config struct {
+	scheme   keywrap.Scheme
+	kek      []byte
+	top      []byte
+	tuakOpts []tuak.Option
+}
+
+// WithTransportKey decrypts encrypted K/TOPc columns (EKI, EOPC, ETOPC)
+// with the given scheme and transport key.
+func WithTransportKey(scheme keywrap.Scheme, key []byte) Option {
+	return func(c *config) {
+		c.scheme = scheme
+		c.kek = key
+	}
+}
+
+// WithTOP recomputes TOPc from TOP for every record. Vendor TOPc values
+// are cross-checked (ErrTOPcMismatch); missing ones are filled in.
+func WithTOP(top []byte, opts ...tuak.Option) Option {
+	return func(c *config) {
+		c.top = top
+		c.tuakOpts = opts
+	}
+}
+
+// Subscriber converts the record to a store.Subscriber keyed by "imsi-<IMSI>".
+func (r *Record) Subscriber(opts tuak.Options) *store.Subscriber {
+	return &store.Subscriber{
+		ID:      "imsi-" + r.IMSI,
+		K:       r.K,
+		TOPc:    r.TOPc,
+		Options: opts,
+	}
+}
+
+// NewTUAK creates a TUAK context for the record.
+func (r *Record) NewTUAK(rand, sqn, amf []byte, opts ...tuak.Option) (*tuak.TUAK, error) {
+	return tuak.NewWithTOPc(r.K, r.TOPc, rand, sqn, amf, opts...)
+}
+
+// ReadFile parses path as CSV for a .csv extension and as .out text otherwise.
+func ReadFile(path string, opts ...Option) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		records, err := ParseCSV(f, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &File{Records: records}, nil
+	}
+	return ParseOut(f, opts...)
+}
+
+// ParseOut parses a vendor .out file. The output section starts with a
+// "Var_Out:" line naming the slash-separated columns, followed by one
+// whitespace-separated line per card.
+func ParseOut(r io.Reader, opts ...Option) (*File, error) {
+	c := newConfig(opts)
+	out := &File{Header: make(map[string]string)}
+	var columns []string
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "*") {
+			continue
+		}
+		if name, value, ok := strings.Cut(s, ":"); ok && columns == nil {
+			name = strings.TrimSpace(name)
+			if strings.EqualFold(name, "Var_Out") {
+				columns = strings.Split(strings.TrimSpace(value), "/")
+				continue
+			}
+			out.Header[name] = strings.TrimSpace(value)
+			continue
+		}
+		if columns == nil {
+			return nil, fmt.Errorf("simfile: line %d: data before Var_Out", line)
+		}
+		rec, err := c.record(columns, strings.Fields(s))
+		if err != nil {
+			return nil, fmt.Errorf("simfile: line %d: %w", line, err)
+		}
+		out.Records = append(out.Records, *rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return nil, fmt.Errorf("simfile: missing Var_Out line")
+	}
+	return out, nil
+}
+
+// ParseCSV parses a CSV file whose first row names the columns.
+func ParseCSV(r io.Reader, opts ...Option) ([]Record, error) {
+	c := newConfig(opts)
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	columns, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("simfile: read header: %w", err)
+	}
+	var out []Record
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("simfile: %w", err)
+		}
+		rec, err := c.record(columns, fields)
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("simfile: line %d: %w", line, err)
+		}
+		out = append(out, *rec)
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) record(columns, fields []string) (*Record, error) {
+	if len(fields) != len(columns) {
+		return nil, fmt.Errorf("%d fields, want %d", len(fields), len(columns))
+	}
+	rec := &Record{Extra: make(map[string]string)}
+	for i, name := range columns {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value := strings.TrimSpace(fields[i])
+		var err error
+		switch name {
+		case "ICCID":
+			rec.ICCID = value
+		case "IMSI":
+			rec.IMSI = value
+		case "KI", "K":
+			rec.K, err = hex.DecodeString(value)
+		case "OPC", "TOPC":
+			rec.TOPc, err = hex.DecodeString(value)
+		case "EKI", "EK":
+			rec.K, err = c.decrypt(value)
+		case "EOPC", "ETOPC":
+			rec.TOPc, err = c.decrypt(value)
+		default:
+			rec.Extra[name] = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if err := c.check(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (c *config) decrypt(value string) ([]byte, error) {
+	if c.kek == nil {
+		return nil, fmt.Errorf("encrypted column without transport key")
+	}
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return keywrap.Unwrap(c.scheme, c.kek, b)
+}
+
+func (c *config) check(rec *Record) error {
+	if rec.IMSI == "" {
+		return fmt.Errorf("missing IMSI")
+	}
+	if len(rec.K) != 16 && len(rec.K) != 32 {
+		return fmt.Errorf("IMSI %s: invalid K length %d bytes", rec.IMSI, len(rec.K))
+	}
+	if rec.TOPc != nil && len(rec.TOPc) != 32 {
+		return fmt.Errorf("IMSI %s: invalid TOPc length %d bytes", rec.IMSI, len(rec.TOPc))
+	}
+	if c.top == nil {
+		if rec.TOPc == nil {
+			return fmt.Errorf("IMSI %s: missing TOPc", rec.IMSI)
+		}
+		return nil
+	}
+	topc, err := tuak.ComputeTOPc(rec.K, c.top, c.tuakOpts...)
+	if err != nil {
+		return fmt.Errorf("IMSI %s: %w", rec.IMSI, err)
+	}
+	if rec.TOPc == nil {
+		rec.TOPc = topc
+		return nil
+	}
+	if subtle.ConstantTimeCompare(rec.TOPc, topc) != 1 {
+		return fmt.Errorf("IMSI %s: %w", rec.IMSI, ErrTOPcMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package simfile
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"tuak"
+	"tuak/keywrap"
+	"tuak/testvectors"
+)
+
+func TestParseOut(t *testing.T) {
+	v1, v2 := loadVector(t, 2), loadVector(t, 3)
+	text := fmt.Sprintf(`*HEADER DESCRIPTION
+***************************************
+Customer        : Example Operator
+Quantity        : 2
+Type            : USIM
+*
+*OUTPUT VARIABLES
+***************************************
+Var_Out: ICCID/IMSI/PIN1/PUK1/KI/OPC
+8981000000000000001 001010000000001 1234 12345678 %s %s
+8981000000000000002 001010000000002 1234 87654321 %s %s
+`, v1.K, v1.Topc, v2.K, v2.Topc)
+
+	f, err := ParseOut(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseOut: %v", err)
+	}
+	if f.Header["Quantity"] != "2" || len(f.Records) != 2 {
+		t.Fatalf("header=%v records=%d", f.Header, len(f.Records))
+	}
+	r := f.Records[1]
+	if r.IMSI != "001010000000002" || r.Extra["PUK1"] != "87654321" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	if !bytes.Equal(r.K, decodeHex(t, v2.K)) || !bytes.Equal(r.TOPc, decodeHex(t, v2.Topc)) {
+		t.Fatalf("K/TOPc mismatch")
+	}
+
+	// Both vectors share TOP, so the vendor TOPc values cross-check.
+	if _, err := ParseOut(strings.NewReader(text), WithTOP(decodeHex(t, v1.Top))); err != nil {
+		t.Fatalf("ParseOut WithTOP: %v", err)
+	}
+	_, err = ParseOut(strings.NewReader(text), WithTOP(bytes.Repeat([]byte{0x01}, 32)))
+	if !errors.Is(err, ErrTOPcMismatch) {
+		t.Fatalf("ParseOut with wrong TOP: err = %v, want ErrTOPcMismatch", err)
+	}
+}
+
+func TestParseCSVEncrypted(t *testing.T) {
+	v := loadVector(t, 1)
+	transport := bytes.Repeat([]byte{0x11}, 16)
+	ek, err := keywrap.Wrap(keywrap.SchemeAESCBC, transport, decodeHex(t, v.K))
+	if err != nil {
+		t.Fatalf("Wrap: %v", err)
+	}
+	text := fmt.Sprintf("imsi,iccid,eki\n001010000000001,8981000000000000001,%x\n", ek)
+
+	if _, err := ParseCSV(strings.NewReader(text)); err == nil {
+		t.Fatalf("ParseCSV accepted encrypted K without transport key")
+	}
+	records, err := ParseCSV(strings.NewReader(text),
+		WithTransportKey(keywrap.SchemeAESCBC, transport),
+		WithTOP(decodeHex(t, v.Top)),
+	)
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	r := records[0]
+	if !bytes.Equal(r.K, decodeHex(t, v.K)) || !bytes.Equal(r.TOPc, decodeHex(t, v.Topc)) {
+		t.Fatalf("K/TOPc mismatch")
+	}
+
+	tk, err := r.NewTUAK(decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF), tuak.WithMACLength(v.MAClength))
+	if err != nil {
+		t.Fatalf("NewTUAK: %v", err)
+	}
+	mac, err := tk.F1()
+	if err != nil {
+		t.Fatalf("F1: %v", err)
+	}
+	if !bytes.Equal(mac, decodeHex(t, v.F1)) {
+		t.Fatalf("f1 mismatch")
+	}
+	if sub := r.Subscriber(tuak.Options{MACLength: v.MAClength}); sub.ID != "imsi-001010000000001" || sub.Validate() != nil {
+		t.Fatalf("unexpected subscriber %+v", sub)
+	}
+}
+
+func TestParseRejectsBadLengths(t *testing.T) {
+	text := "Var_Out: IMSI/KI/OPC\n001010000000001 0011 " + strings.Repeat("00", 32) + "\n"
+	if _, err := ParseOut(strings.NewReader(text)); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("err = %v, want K length error on line 2", err)
+	}
+}
+
+func loadVector(t *testing.T, id int) testvectors.TUAKVector {
+	t.Helper()
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		if v.ID == id {
+			return v
+		}
+	}
+	t.Fatalf("test set %d not found", id)
+	return testvectors.TUAKVector{}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	v1, v2 := loadVector(t, 1), loadVector(t, 2)
+	f := &File{
+		Header: map[string]string{"Customer": "Example Operator", "Quantity": "2"},
+		Records: []Record{
+			{ICCID: "8981000000000000001", IMSI: "001010000000001", K: decodeHex(t, v1.K), TOPc: decodeHex(t, v1.Topc), Extra: map[string]string{"PIN1": "1234"}},
+			{ICCID: "8981000000000000002", IMSI: "001010000000002", K: decodeHex(t, v2.K), TOPc: decodeHex(t, v2.Topc), Extra: map[string]string{"PIN1": "4321"}},
+		},
+	}
+	transport := bytes.Repeat([]byte{0x22}, 16)
+	for _, opts := range [][]Option{nil, {WithTransportKey(keywrap.SchemeAESKW, transport)}} {
+		var out bytes.Buffer
+		if err := WriteOut(&out, f, opts...); err != nil {
+			t.Fatalf("WriteOut: %v", err)
+		}
+		encrypted := strings.Contains(out.String(), "Var_Out: ICCID/IMSI/EKI/ETOPC/PIN1")
+		if encrypted != (opts != nil) || strings.Contains(out.String(), v1.K) == (opts != nil) {
+			t.Fatalf("WriteOut with %d options:\n%s", len(opts), out.String())
+		}
+		got, err := ParseOut(&out, opts...)
+		if err != nil {
+			t.Fatalf("ParseOut(WriteOut): %v", err)
+		}
+		if got.Header["Customer"] != "Example Operator" || len(got.Records) != 2 {
+			t.Fatalf("round trip = %+v", got)
+		}
+		for i, r := range got.Records {
+			want := f.Records[i]
+			if r.ICCID != want.ICCID || !bytes.Equal(r.K, want.K) || !bytes.Equal(r.TOPc, want.TOPc) || r.Extra["PIN1"] != want.Extra["PIN1"] {
+				t.Fatalf("record %d = %+v, want %+v", i, r, want)
+			}
+		}
+
+		out.Reset()
+		if err := WriteCSV(&out, f.Records, opts...); err != nil {
+			t.Fatalf("WriteCSV: %v", err)
+		}
+		records, err := ParseCSV(&out, opts...)
+		if err != nil || len(records) != 2 || !bytes.Equal(records[1].K, f.Records[1].K) {
+			t.Fatalf("ParseCSV(WriteCSV) = %+v, %v", records, err)
+		}
+	}
+
+	f.Records[1].Extra["PIN1"] = "12 34"
+	if err := WriteOut(&bytes.Buffer{}, f); err == nil {
+		t.Fatalf("WriteOut accepted a value with a space")
+	}
+	f.Records[1].TOPc = nil
+	if err := WriteCSV(&bytes.Buffer{}, f.Records); err == nil {
+		t.Fatalf("WriteCSV accepted a record without TOPc")
+	}
+}
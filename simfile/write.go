@@ -0,0 +1,149 @@
+package simfile
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"tuak/keywrap"
+)
+
+// WriteOut writes f in the .out layout ParseOut reads: the header as
+// "Name : value" lines in name order, then a Var_Out line and one line per
+// record. With WithTransportKey, K and TOPc are written encrypted as EKI
+// and ETOPC instead of KI and TOPC.
+func WriteOut(w io.Writer, f *File, opts ...Option) error {
+	c := newConfig(opts)
+	columns, rows, err := c.table(f.Records)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("*HEADER DESCRIPTION\n")
+	b.WriteString("***************************************\n")
+	names := make([]string, 0, len(f.Header))
+	for name := range f.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.ContainsAny(name, ":\n") || strings.Contains(f.Header[name], "\n") {
+			return fmt.Errorf("simfile: invalid header %q", name)
+		}
+		fmt.Fprintf(&b, "%-16s: %s\n", name, f.Header[name])
+	}
+	b.WriteString("*\n*OUTPUT VARIABLES\n")
+	b.WriteString("***************************************\n")
+	fmt.Fprintf(&b, "Var_Out: %s\n", strings.Join(columns, "/"))
+	for i, row := range rows {
+		for j, v := range row {
+			if v == "" || strings.ContainsAny(v, " \t\r\n") {
+				return fmt.Errorf("simfile: IMSI %s: %s value %q cannot be written to a .out file", f.Records[i].IMSI, columns[j], v)
+			}
+		}
+		b.WriteString(strings.Join(row, " "))
+		b.WriteByte('\n')
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes records in the CSV layout ParseCSV reads, with the same
+// columns as WriteOut.
+func WriteCSV(w io.Writer, records []Record, opts ...Option) error {
+	c := newConfig(opts)
+	columns, rows, err := c.table(records)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("simfile: %w", err)
+	}
+	return nil
+}
+
+// table lays records out as ICCID (when any record has one), IMSI, K,
+// TOPc and the Extra columns in name order.
+func (c *config) table(records []Record) ([]string, [][]string, error) {
+	var iccid bool
+	extra := map[string]bool{}
+	for _, r := range records {
+		iccid = iccid || r.ICCID != ""
+		for name := range r.Extra {
+			extra[name] = true
+		}
+	}
+	kName, topcName := "KI", "TOPC"
+	if c.kek != nil {
+		kName, topcName = "EKI", "ETOPC"
+	}
+	var columns []string
+	if iccid {
+		columns = append(columns, "ICCID")
+	}
+	columns = append(columns, "IMSI", kName, topcName)
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	columns = append(columns, names...)
+
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		if err := checkWrite(&r); err != nil {
+			return nil, nil, err
+		}
+		k, err := c.encrypt(r.K)
+		if err != nil {
+			return nil, nil, fmt.Errorf("simfile: IMSI %s: K: %w", r.IMSI, err)
+		}
+		topc, err := c.encrypt(r.TOPc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("simfile: IMSI %s: TOPc: %w", r.IMSI, err)
+		}
+		var row []string
+		if iccid {
+			row = append(row, r.ICCID)
+		}
+		row = append(row, r.IMSI, k, topc)
+		for _, name := range names {
+			row = append(row, r.Extra[name])
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+// checkWrite applies the record checks of the parsers, so that whatever
+// is written can be read back.
+func checkWrite(r *Record) error {
+	if r.IMSI == "" {
+		return fmt.Errorf("simfile: record without IMSI")
+	}
+	if len(r.K) != 16 && len(r.K) != 32 {
+		return fmt.Errorf("simfile: IMSI %s: invalid K length %d bytes", r.IMSI, len(r.K))
+	}
+	if len(r.TOPc) != 32 {
+		return fmt.Errorf("simfile: IMSI %s: invalid TOPc length %d bytes", r.IMSI, len(r.TOPc))
+	}
+	return nil
+}
+
+func (c *config) encrypt(key []byte) (string, error) {
+	if c.kek == nil {
+		return hex.EncodeToString(key), nil
+	}
+	b, err := keywrap.Wrap(c.scheme, c.kek, key)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,240 @@
+package tuak
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Function is the TUAK function selected by an INSTANCE byte.
+type Function int
+
+// TUAK functions.
+const (
+	FunctionTOPc Function = iota
+	FunctionF1
+	FunctionF1Star
+	FunctionF2345
+	FunctionF5Star
+)
+
+// String returns the function name as used in debug labels.
+func (f Function) String() string {
+	switch f {
+	case FunctionTOPc:
+		return "topc"
+	case FunctionF1:
+		return "f1"
+	case FunctionF1Star:
+		return "f1star"
+	case FunctionF2345:
+		return "f2345"
+	case FunctionF5Star:
+		return "f5star"
+	default:
+		return fmt.Sprintf("Function(%d)", int(f))
+	}
+}
+
+func functionByName(name string) (Function, bool) {
+	for f := FunctionTOPc; f <= FunctionF5Star; f++ {
+		if f.String() == name {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// State is a TUAK IN buffer decoded per the TS 35.231 layout, with the
+// pushData byte reversal undone. Lengths are in bits; those not selected
+// by the function are zero, as are fields the function leaves empty.
+type State struct {
+	Function  Function
+	Instance  byte
+	MACLength int
+	RESLength int
+	CKLength  int
+	IKLength  int
+	KLength   int
+	// TOP holds TOP for FunctionTOPc and TOPc otherwise.
+	TOP      []byte
+	AlgoName string
+	RAND     []byte
+	AMF      []byte
+	SQN      []byte
+	K        []byte
+	// PaddingOK reports whether the 0x1F and 0x80 padding bytes are set.
+	PaddingOK bool
+}
+
+// DecodeState decodes a 200-byte IN buffer as passed to a DebugHook with
+// an ".in" label.
+func DecodeState(buf []byte) (*State, error) {
+	if len(buf) != inSize {
+		return nil, fmt.Errorf("tuak: state length %d bytes, want %d", len(buf), inSize)
+	}
+	s := &State{
+		Instance:  buf[offsetInst],
+		AlgoName:  string(pullData(buf, offsetAlgo, len(algoName))),
+		PaddingOK: buf[paddingByte96] == 0x1F && buf[paddingByte135] == 0x80,
+	}
+	if err := s.decodeInstance(); err != nil {
+		return nil, err
+	}
+	s.TOP = pullData(buf, offsetTOP, 32)
+	if s.Function != FunctionTOPc {
+		s.RAND = pullData(buf, offsetRAND, 16)
+	}
+	if s.Function == FunctionF1 || s.Function == FunctionF1Star {
+		s.AMF = pullData(buf, offsetAMF, 2)
+		s.SQN = pullData(buf, offsetSQN, 6)
+	}
+	s.K = pullData(buf, offsetK, s.KLength/8)
+	return s, nil
+}
+
+// decodeInstance fills the function and lengths from the INSTANCE byte.
+func (s *State) decodeInstance() error {
+	inst, err := DecodeInstance(s.Instance)
+	if err != nil {
+		return err
+	}
+	s.Function = inst.Function
+	s.MACLength, s.RESLength, s.CKLength, s.IKLength, s.KLength = inst.MACLength, inst.RESLength, inst.CKLength, inst.IKLength, inst.KLength
+	return nil
+}
+
+// decoded returns the Instance the state's lengths describe.
+func (s *State) decoded() Instance {
+	return Instance{
+		Function:  s.Function,
+		MACLength: s.MACLength,
+		RESLength: s.RESLength,
+		CKLength:  s.CKLength,
+		IKLength:  s.IKLength,
+		KLength:   s.KLength,
+	}
+}
+
+// fields returns the present fields in layout order.
+func (s *State) fields() []stateField {
+	top := "TOPc"
+	if s.Function == FunctionTOPc {
+		top = "TOP"
+	}
+	fields := []stateField{
+		{top, s.TOP, classKey},
+		{"INSTANCE", []byte{s.Instance}, classPublic},
+		{"ALGONAME", []byte(s.AlgoName), classPublic},
+	}
+	for _, f := range []stateField{
+		{"RAND", s.RAND, classPublic},
+		{"AMF", s.AMF, classPublic},
+		{"SQN", s.SQN, classPublic},
+	} {
+		if f.value != nil {
+			fields = append(fields, f)
+		}
+	}
+	return append(fields, stateField{"K", s.K, classKey})
+}
+
+// Summary describes the function and lengths selected by INSTANCE,
+// e.g. "f2345 RES=64 CK=128 IK=128 K=256".
+func (s *State) Summary() string {
+	return s.decoded().String()
+}
+
+// String pretty-prints the state one field per line, K and TOPc included.
+func (s *State) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-9s %s\n", "function", s.Summary())
+	for _, f := range s.fields() {
+		fmt.Fprintf(&b, "%-9s %s\n", f.name, f.text())
+	}
+	padding := "ok"
+	if !s.PaddingOK {
+		padding = "missing"
+	}
+	fmt.Fprintf(&b, "%-9s %s\n", "padding", padding)
+	return b.String()
+}
+
+func (f stateField) text() string {
+	if f.name == "ALGONAME" {
+		return string(f.value)
+	}
+	return hex.EncodeToString(f.value)
+}
+
+// FieldDiff is one field whose value differs between two states. A and B
+// are the printed values, empty where a state lacks the field.
+type FieldDiff struct {
+	Name string
+	A, B string
+}
+
+// String prints both values with carets under the differing characters.
+func (d FieldDiff) String() string {
+	n := len(d.A)
+	if len(d.B) > n {
+		n = len(d.B)
+	}
+	marks := make([]byte, n)
+	for i := range marks {
+		marks[i] = ' '
+		if i >= len(d.A) || i >= len(d.B) || d.A[i] != d.B[i] {
+			marks[i] = '^'
+		}
+	}
+	pad := strings.Repeat(" ", len(d.Name))
+	return fmt.Sprintf("%s a: %s\n%s b: %s\n%s    %s", d.Name, d.A, pad, d.B, pad, strings.TrimRight(string(marks), " "))
+}
+
+// DiffStates returns the fields, including the INSTANCE summary and
+// padding, that differ between a and b in layout order.
+func DiffStates(a, b *State) []FieldDiff {
+	type entry struct{ a, b string }
+	order := []string{"function"}
+	values := map[string]*entry{"function": {a.Summary(), b.Summary()}}
+	add := func(fields []stateField, side int) {
+		for _, f := range fields {
+			e, ok := values[f.name]
+			if !ok {
+				e = &entry{}
+				values[f.name] = e
+				order = append(order, f.name)
+			}
+			if side == 0 {
+				e.a = f.text()
+			} else {
+				e.b = f.text()
+			}
+		}
+	}
+	add(a.fields(), 0)
+	add(b.fields(), 1)
+	values["padding"] = &entry{fmt.Sprint(a.PaddingOK), fmt.Sprint(b.PaddingOK)}
+	order = append(order, "padding")
+
+	var diffs []FieldDiff
+	for _, name := range order {
+		if e := values[name]; e.a != e.b {
+			diffs = append(diffs, FieldDiff{Name: name, A: e.a, B: e.b})
+		}
+	}
+	return diffs
+}
+
+// DiffStateBuffers decodes two IN buffers and returns their differences.
+func DiffStateBuffers(a, b []byte) ([]FieldDiff, error) {
+	sa, err := DecodeState(a)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := DecodeState(b)
+	if err != nil {
+		return nil, err
+	}
+	return DiffStates(sa, sb), nil
+}
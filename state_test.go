@@ -0,0 +1,128 @@
+package tuak
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"tuak/testvectors"
+)
+
+func captureInputs(t *testing.T, v testvectors.TUAKVector, sqn []byte) map[string][]byte {
+	t.Helper()
+	bufs := make(map[string][]byte)
+	hook := func(label string, data []byte) {
+		if strings.HasSuffix(label, ".in") {
+			bufs[label] = data
+		}
+	}
+	k := decodeHex(t, v.K)
+	opts := append(optionsFromVector(v), WithDebugHook(hook))
+	if _, err := ComputeTOPc(k, decodeHex(t, v.Top), opts...); err != nil {
+		t.Fatalf("ComputeTOPc: %v", err)
+	}
+	tk, err := NewWithTOPc(k, decodeHex(t, v.Topc), decodeHex(t, v.Rand), sqn, decodeHex(t, v.AMF), opts...)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	if _, err := tk.F1(); err != nil {
+		t.Fatalf("F1: %v", err)
+	}
+	if _, err := tk.F1Star(); err != nil {
+		t.Fatalf("F1Star: %v", err)
+	}
+	if _, _, _, _, err := tk.F2345(); err != nil {
+		t.Fatalf("F2345: %v", err)
+	}
+	if _, err := tk.F5Star(); err != nil {
+		t.Fatalf("F5Star: %v", err)
+	}
+	return bufs
+}
+
+func TestDecodeStateVectors(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		bufs := captureInputs(t, v, decodeHex(t, v.SQN))
+		want := map[string]Function{
+			"topc.in": FunctionTOPc, "f1.in": FunctionF1, "f1star.in": FunctionF1Star,
+			"f2345.in": FunctionF2345, "f5star.in": FunctionF5Star,
+		}
+		for label, fn := range want {
+			s, err := DecodeState(bufs[label])
+			if err != nil {
+				t.Fatalf("vector %d %s: %v", v.ID, label, err)
+			}
+			if s.Function != fn || s.KLength != v.Klength || s.AlgoName != "TUAK1.0" || !s.PaddingOK {
+				t.Fatalf("vector %d %s: %s", v.ID, label, s)
+			}
+			if !bytes.Equal(s.K, decodeHex(t, v.K)) {
+				t.Fatalf("vector %d %s: K = %x", v.ID, label, s.K)
+			}
+			top := v.Topc
+			if fn == FunctionTOPc {
+				top = v.Top
+			}
+			if !bytes.Equal(s.TOP, decodeHex(t, top)) {
+				t.Fatalf("vector %d %s: TOP = %x", v.ID, label, s.TOP)
+			}
+			switch fn {
+			case FunctionF1, FunctionF1Star:
+				if s.MACLength != v.MAClength || !bytes.Equal(s.SQN, decodeHex(t, v.SQN)) || !bytes.Equal(s.AMF, decodeHex(t, v.AMF)) {
+					t.Fatalf("vector %d %s: %s", v.ID, label, s)
+				}
+			case FunctionF2345:
+				if s.RESLength != v.RESLength || s.CKLength != v.CKlength || s.IKLength != v.IKlength {
+					t.Fatalf("vector %d %s: %s", v.ID, label, s.Summary())
+				}
+			}
+			if fn != FunctionTOPc && !bytes.Equal(s.RAND, decodeHex(t, v.Rand)) {
+				t.Fatalf("vector %d %s: RAND = %x", v.ID, label, s.RAND)
+			}
+		}
+	}
+}
+
+func TestDecodeStateErrors(t *testing.T) {
+	if _, err := DecodeState(make([]byte, 199)); err == nil {
+		t.Fatalf("DecodeState accepted a short buffer")
+	}
+	buf := newState()
+	for _, inst := range []byte{0x38, 0x04, 0xC8, 0x5C} {
+		buf[offsetInst] = inst
+		if s, err := DecodeState(buf); err == nil {
+			t.Fatalf("INSTANCE %#02x decoded as %s", inst, s.Summary())
+		}
+	}
+}
+
+func TestDiffStates(t *testing.T) {
+	v := loadTUAKVector(t, 1)
+	a := captureInputs(t, v, decodeHex(t, "000000000001"))
+	b := captureInputs(t, v, decodeHex(t, "000000000002"))
+	diffs, err := DiffStateBuffers(a["f1.in"], b["f1.in"])
+	if err != nil {
+		t.Fatalf("DiffStateBuffers: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].Name != "SQN" {
+		t.Fatalf("diffs = %v", diffs)
+	}
+	if want := "SQN a: 000000000001\n    b: 000000000002\n                  ^"; diffs[0].String() != want {
+		t.Fatalf("diff =\n%s\nwant\n%s", diffs[0], want)
+	}
+
+	diffs, err = DiffStateBuffers(a["f1.in"], a["f2345.in"])
+	if err != nil {
+		t.Fatalf("DiffStateBuffers: %v", err)
+	}
+	var names []string
+	for _, d := range diffs {
+		names = append(names, d.Name)
+	}
+	if got := strings.Join(names, ","); got != "function,INSTANCE,AMF,SQN" {
+		t.Fatalf("f1/f2345 diff fields = %s", got)
+	}
+}
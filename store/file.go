@@ -0,0 +1,231 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileFormat selects the encoding of a subscriber file.
+type FileFormat int
+
+const (
+	// FormatJSON encodes subscribers as JSON.
+	FormatJSON FileFormat = iota
+	// FormatYAML encodes subscribers as YAML.
+	FormatYAML
+)
+
+// File is a SubscriberStore persisted to a JSON or YAML file. The whole
+// file is rewritten atomically after every change.
+type File struct {
+	mu   sync.Mutex
+	path string
+	subs map[string]*Subscriber
+}
+
+type fileContents struct {
+	Subscribers []Record `json:"subscribers" yaml:"subscribers"`
+}
+
+// OpenFile opens a subscriber file, creating an empty store if the file
+// does not exist. The format is chosen from the extension (.yaml/.yml or JSON).
+func OpenFile(path string) (*File, error) {
+	f := &File{
+		path: path,
+		subs: make(map[string]*Subscriber),
+	}
+	subs, err := ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		sub, err := normalize(sub)
+		if err != nil {
+			return nil, err
+		}
+		f.subs[sub.ID] = sub
+	}
+	return f, nil
+}
+
+// FormatForPath returns FormatYAML for .yaml/.yml paths and FormatJSON otherwise.
+func FormatForPath(path string) FileFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// ReadFile reads all subscribers from a JSON or YAML subscriber file.
+func ReadFile(path string) ([]*Subscriber, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c fileContents
+	if FormatForPath(path) == FormatYAML {
+		err = yaml.Unmarshal(b, &c)
+	} else {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: decode %s: %w", path, err)
+	}
+	out := make([]*Subscriber, 0, len(c.Subscribers))
+	for _, r := range c.Subscribers {
+		sub, err := r.Subscriber()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sub)
+	}
+	return out, nil
+}
+
+// WriteFile atomically writes subscribers to path, sorted by ID.
+func WriteFile(path string, subs []*Subscriber) error {
+	sorted := append([]*Subscriber(nil), subs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	c := fileContents{Subscribers: make([]Record, 0, len(sorted))}
+	for _, sub := range sorted {
+		c.Subscribers = append(c.Subscribers, NewRecord(sub))
+	}
+
+	var buf bytes.Buffer
+	if FormatForPath(path) == FormatYAML {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&c); err != nil {
+			return err
+		}
+	} else {
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(&c); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get implements SubscriberStore.
+func (f *File) Get(_ context.Context, id string) (*Subscriber, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sub, ok := f.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return sub.clone(), nil
+}
+
+// Put implements SubscriberStore.
+func (f *File) Put(_ context.Context, sub *Subscriber) error {
+	sub, err := normalize(sub)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prev := f.subs[sub.ID]
+	f.subs[sub.ID] = sub
+	if err := f.flush(); err != nil {
+		if prev == nil {
+			delete(f.subs, sub.ID)
+		} else {
+			f.subs[sub.ID] = prev
+		}
+		return err
+	}
+	return nil
+}
+
+// NextSQN implements SubscriberStore.
+func (f *File) NextSQN(_ context.Context, id string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sub, ok := f.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	next, err := IncrementSQN(sub.SQN)
+	if err != nil {
+		return nil, err
+	}
+	prev := sub.SQN
+	sub.SQN = next
+	if err := f.flush(); err != nil {
+		sub.SQN = prev
+		return nil, err
+	}
+	return cloneBytes(next), nil
+}
+
+// SetSQN implements SubscriberStore.
+func (f *File) SetSQN(_ context.Context, id string, sqn []byte) error {
+	if err := validateSQN(sqn); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sub, ok := f.subs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	prev := sub.SQN
+	sub.SQN = cloneBytes(sqn)
+	if err := f.flush(); err != nil {
+		sub.SQN = prev
+		return err
+	}
+	return nil
+}
+
+// Range implements Ranger.
+func (f *File) Range(ctx context.Context, fn func(*Subscriber) error) error {
+	f.mu.Lock()
+	subs := sortedClones(f.subs)
+	f.mu.Unlock()
+	return rangeSubscribers(ctx, subs, fn)
+}
+
+func (f *File) flush() error {
+	subs := make([]*Subscriber, 0, len(f.subs))
+	for _, sub := range f.subs {
+		subs = append(subs, sub)
+	}
+	return WriteFile(f.path, subs)
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"tuak"
+)
+
+// Record is the serialised form of a Subscriber with lowercase hex fields.
+// Field names follow testvectors.TUAKVector.
+type Record struct {
+	ID      string `json:"id" yaml:"id"`
+	K       string `json:"k" yaml:"k"`
+	Topc    string `json:"topc" yaml:"topc"`
+	KeyWrap string `json:"key_wrap,omitempty" yaml:"key_wrap,omitempty"`
+	// TopcPrevious is Subscriber.PreviousTOPc.
+	TopcPrevious string `json:"topc_previous,omitempty" yaml:"topc_previous,omitempty"`
+	AMF          string `json:"amf,omitempty" yaml:"amf,omitempty"`
+	SQN          string `json:"sqn,omitempty" yaml:"sqn,omitempty"`
+	// Profile names a registered tuak.Profile supplying the lengths and
+	// Keccak iterations; explicit non-zero fields below override it.
+	Profile          string `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Klength          int    `json:"klength,omitempty" yaml:"klength,omitempty"`
+	MAClength        int    `json:"maclength,omitempty" yaml:"maclength,omitempty"`
+	RESLength        int    `json:"reslength,omitempty" yaml:"reslength,omitempty"`
+	CKlength         int    `json:"cklength,omitempty" yaml:"cklength,omitempty"`
+	IKlength         int    `json:"iklength,omitempty" yaml:"iklength,omitempty"`
+	KeccakIterations int    `json:"keccak_iterations,omitempty" yaml:"keccak_iterations,omitempty"`
+}
+
+// NewRecord converts a Subscriber to its serialised form.
+func NewRecord(sub *Subscriber) Record {
+	return Record{
+		ID:               sub.ID,
+		K:                hex.EncodeToString(sub.K),
+		Topc:             hex.EncodeToString(sub.TOPc),
+		KeyWrap:          sub.KeyWrap,
+		TopcPrevious:     hex.EncodeToString(sub.PreviousTOPc),
+		AMF:              hex.EncodeToString(sub.AMF),
+		SQN:              hex.EncodeToString(sub.SQN),
+		Klength:          sub.Options.KLength,
+		MAClength:        sub.Options.MACLength,
+		RESLength:        sub.Options.RESLength,
+		CKlength:         sub.Options.CKLength,
+		IKlength:         sub.Options.IKLength,
+		KeccakIterations: sub.Options.KeccakIterations,
+	}
+}
+
+// Subscriber decodes and validates the record.
+func (r Record) Subscriber() (*Subscriber, error) {
+	sub := &Subscriber{
+		ID:      r.ID,
+		KeyWrap: r.KeyWrap,
+	}
+	if r.Profile != "" {
+		p, err := tuak.LookupProfile(r.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("store: %s: %w", r.ID, err)
+		}
+		sub.Options = p.Options()
+	}
+	o := &sub.Options
+	for _, f := range []struct {
+		v   int
+		dst *int
+	}{
+		{r.Klength, &o.KLength},
+		{r.MAClength, &o.MACLength},
+		{r.RESLength, &o.RESLength},
+		{r.CKlength, &o.CKLength},
+		{r.IKlength, &o.IKLength},
+		{r.KeccakIterations, &o.KeccakIterations},
+	} {
+		if f.v != 0 {
+			*f.dst = f.v
+		}
+	}
+	fields := []struct {
+		name string
+		hex  string
+		dst  *[]byte
+	}{
+		{"k", r.K, &sub.K},
+		{"topc", r.Topc, &sub.TOPc},
+		{"topc_previous", r.TopcPrevious, &sub.PreviousTOPc},
+		{"amf", r.AMF, &sub.AMF},
+		{"sqn", r.SQN, &sub.SQN},
+	}
+	for _, f := range fields {
+		if f.hex == "" {
+			continue
+		}
+		b, err := hex.DecodeString(f.hex)
+		if err != nil {
+			return nil, fmt.Errorf("store: %s: decode %s: %w", r.ID, f.name, err)
+		}
+		*f.dst = b
+	}
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
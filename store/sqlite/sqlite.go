@@ -0,0 +1,224 @@
+// Package sqlite implements store.SubscriberStore on SQLite using the
+// pure-Go modernc.org/sqlite driver.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"tuak/store"
+
+	// Registers the "sqlite" database/sql driver.
+	_ "modernc.org/sqlite"
+)
+
+const columns = `k, topc, key_wrap, topc_previous, amf, sqn, k_length, mac_length, res_length, ck_length, ik_length, keccak_iterations`
+
+const schema = `CREATE TABLE IF NOT EXISTS subscribers (
+	id                TEXT PRIMARY KEY,
+	k                 BLOB NOT NULL,
+	topc              BLOB NOT NULL,
+	key_wrap          TEXT NOT NULL DEFAULT '',
+	topc_previous     BLOB,
+	amf               BLOB,
+	sqn               INTEGER NOT NULL DEFAULT 0,
+	k_length          INTEGER NOT NULL DEFAULT 0,
+	mac_length        INTEGER NOT NULL DEFAULT 0,
+	res_length        INTEGER NOT NULL DEFAULT 0,
+	ck_length         INTEGER NOT NULL DEFAULT 0,
+	ik_length         INTEGER NOT NULL DEFAULT 0,
+	keccak_iterations INTEGER NOT NULL DEFAULT 0
+)`
+
+// Store is a SQLite-backed store.SubscriberStore.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (and if needed creates) the database at path.
+func Open(path string) (*Store, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrations are the columns added to the schema since the first release,
+// with their definitions, in the order they were introduced.
+var migrations = []struct{ column, definition string }{
+	{"key_wrap", "TEXT NOT NULL DEFAULT ''"},
+	{"topc_previous", "BLOB"},
+}
+
+// New uses an existing SQLite handle and creates the schema if needed,
+// adding columns introduced since the database was created.
+func New(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("sqlite: create schema: %w", err)
+	}
+	if err := migrate(db); err != nil {
+		return nil, fmt.Errorf("sqlite: migrate schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// migrate adds the columns of migrations missing from the table.
+func migrate(db *sql.DB) error {
+	for _, m := range migrations {
+		var n int
+		err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('subscribers') WHERE name = ?`, m.column).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE subscribers ADD COLUMN ` + m.column + ` ` + m.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get implements store.SubscriberStore.
+func (s *Store) Get(ctx context.Context, id string) (*store.Subscriber, error) {
+	sub := &store.Subscriber{ID: id}
+	err := scan(s.db.QueryRowContext(ctx, `SELECT `+columns+` FROM subscribers WHERE id = ?`, id), sub)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// rangePage is the number of rows Range reads per query.
+const rangePage = 1000
+
+// Range implements store.Ranger. Rows are read in pages by ID, so fn may
+// write to the store while Range is in progress.
+func (s *Store) Range(ctx context.Context, fn func(*store.Subscriber) error) error {
+	last := ""
+	for {
+		page, err := s.page(ctx, last)
+		if err != nil {
+			return err
+		}
+		for _, sub := range page {
+			if err := fn(sub); err != nil {
+				return err
+			}
+		}
+		if len(page) < rangePage {
+			return nil
+		}
+		last = page[len(page)-1].ID
+	}
+}
+
+func (s *Store) page(ctx context.Context, after string) ([]*store.Subscriber, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, `+columns+`
+		FROM subscribers WHERE id > ? ORDER BY id LIMIT ?`, after, rangePage)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var page []*store.Subscriber
+	for rows.Next() {
+		sub := &store.Subscriber{}
+		if err := scan(rows, sub, &sub.ID); err != nil {
+			return nil, err
+		}
+		page = append(page, sub)
+	}
+	return page, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scan reads columns into sub, after scanning lead.
+func scan(r scanner, sub *store.Subscriber, lead ...any) error {
+	var sqn int64
+	err := r.Scan(append(lead,
+		&sub.K, &sub.TOPc, &sub.KeyWrap, &sub.PreviousTOPc, &sub.AMF, &sqn,
+		&sub.Options.KLength, &sub.Options.MACLength, &sub.Options.RESLength,
+		&sub.Options.CKLength, &sub.Options.IKLength, &sub.Options.KeccakIterations,
+	)...)
+	if err != nil {
+		return err
+	}
+	sub.SQN = store.SQNFromUint64(uint64(sqn))
+	return nil
+}
+
+// Put implements store.SubscriberStore.
+func (s *Store) Put(ctx context.Context, sub *store.Subscriber) error {
+	if err := sub.Validate(); err != nil {
+		return err
+	}
+	o := sub.Options
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO subscribers
+		(id, `+columns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sub.ID, sub.K, sub.TOPc, sub.KeyWrap, sub.PreviousTOPc, sub.AMF, int64(store.SQNToUint64(sub.SQN)),
+		o.KLength, o.MACLength, o.RESLength, o.CKLength, o.IKLength, o.KeccakIterations,
+	)
+	return err
+}
+
+// NextSQN implements store.SubscriberStore. The increment is a single
+// UPDATE statement, so concurrent callers never observe the same SQN.
+func (s *Store) NextSQN(ctx context.Context, id string) ([]byte, error) {
+	var sqn int64
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE subscribers SET sqn = sqn + 1 WHERE id = ? AND sqn < ? RETURNING sqn`,
+		id, int64(store.MaxSQN),
+	).Scan(&sqn)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, gerr := s.Get(ctx, id); gerr != nil {
+			return nil, gerr
+		}
+		return nil, store.ErrSQNExhausted
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store.SQNFromUint64(uint64(sqn)), nil
+}
+
+// SetSQN implements store.SubscriberStore.
+func (s *Store) SetSQN(ctx context.Context, id string, sqn []byte) error {
+	if len(sqn) != 6 {
+		return fmt.Errorf("sqlite: invalid SQN length %d bytes", len(sqn))
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE subscribers SET sqn = ? WHERE id = ?`,
+		int64(store.SQNToUint64(sqn)), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tuak/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "subscribers.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	storetest.Run(t, s)
+}
+
+// TestMigrate opens a database created with the schema of the first
+// release, before any of the migrated columns existed.
+func TestMigrate(t *testing.T) {
+	var lines []string
+	for _, line := range strings.Split(schema, "\n") {
+		migrated := false
+		for _, m := range migrations {
+			migrated = migrated || strings.HasPrefix(strings.TrimSpace(line), m.column+" ")
+		}
+		if !migrated {
+			lines = append(lines, line)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(strings.Join(lines, "\n")); err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+	db.Close()
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	storetest.Run(t, s)
+}
@@ -0,0 +1,213 @@
+// Package store provides subscriber storage for TUAK consumers.
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"tuak"
+)
+
+// MaxSQN is the largest 48-bit sequence number.
+const MaxSQN = 1<<48 - 1
+
+var (
+	// ErrNotFound is returned for unknown subscriber IDs.
+	ErrNotFound = errors.New("store: subscriber not found")
+	// ErrSQNExhausted is returned when SQN cannot be incremented further.
+	ErrSQNExhausted = errors.New("store: SQN exhausted")
+)
+
+// Subscriber holds the TUAK credentials and state of one subscriber.
+type Subscriber struct {
+	// ID is the subscriber key, typically the SUPI (e.g. "imsi-001010000000001").
+	ID string
+	// K and TOPc are in clear unless KeyWrap names the wrapping scheme
+	// (see package keywrap) under which they are stored.
+	K       []byte
+	TOPc    []byte
+	KeyWrap string
+	// PreviousTOPc is TOPc under the operator's previous TOP, kept while a
+	// TOP rotation is in progress (see package rotate); nil otherwise.
+	PreviousTOPc []byte
+	// AMF is optional; consumers pick a default when nil.
+	AMF []byte
+	// SQN is the last SQN handed out (6 bytes).
+	SQN     []byte
+	Options tuak.Options
+}
+
+// TUAKOptions returns the subscriber options for use with tuak.New*.
+func (s *Subscriber) TUAKOptions() []tuak.Option {
+	return []tuak.Option{tuak.WithOptions(s.Options)}
+}
+
+// SubscriberStore looks up subscribers and maintains their SQN.
+// Implementations must be safe for concurrent use.
+type SubscriberStore interface {
+	// Get returns a copy of the subscriber.
+	Get(ctx context.Context, id string) (*Subscriber, error)
+	// Put inserts or replaces a subscriber.
+	Put(ctx context.Context, sub *Subscriber) error
+	// NextSQN atomically increments the subscriber SQN and returns the new value.
+	NextSQN(ctx context.Context, id string) ([]byte, error)
+	// SetSQN stores SQN, e.g. SQN_MS after resynchronisation.
+	SetSQN(ctx context.Context, id string, sqn []byte) error
+}
+
+// Ranger is implemented by stores and files that can enumerate their
+// subscribers.
+type Ranger interface {
+	// Range calls fn with a copy of every subscriber, in ID order for
+	// stores, until fn returns an error, which Range returns. fn may
+	// modify the store.
+	Range(ctx context.Context, fn func(*Subscriber) error) error
+}
+
+// SQNToUint64 converts a 6-byte SQN to an integer.
+func SQNToUint64(sqn []byte) uint64 {
+	var v uint64
+	for _, b := range sqn {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// SQNFromUint64 converts an integer to a 6-byte SQN.
+func SQNFromUint64(v uint64) []byte {
+	out := make([]byte, 6)
+	for i := 5; i >= 0; i-- {
+		out[i] = byte(v)
+		v >>= 8
+	}
+	return out
+}
+
+// IncrementSQN returns sqn+1 or ErrSQNExhausted.
+func IncrementSQN(sqn []byte) ([]byte, error) {
+	v := SQNToUint64(sqn)
+	if v >= MaxSQN {
+		return nil, ErrSQNExhausted
+	}
+	return SQNFromUint64(v + 1), nil
+}
+
+// Memory is an in-memory SubscriberStore.
+type Memory struct {
+	mu   sync.Mutex
+	subs map[string]*Subscriber
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[string]*Subscriber)}
+}
+
+// Get implements SubscriberStore.
+func (m *Memory) Get(_ context.Context, id string) (*Subscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return sub.clone(), nil
+}
+
+// Put implements SubscriberStore.
+func (m *Memory) Put(_ context.Context, sub *Subscriber) error {
+	sub, err := normalize(sub)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs[sub.ID] = sub
+	return nil
+}
+
+// NextSQN implements SubscriberStore.
+func (m *Memory) NextSQN(_ context.Context, id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	next, err := IncrementSQN(sub.SQN)
+	if err != nil {
+		return nil, err
+	}
+	sub.SQN = next
+	return cloneBytes(next), nil
+}
+
+// SetSQN implements SubscriberStore.
+func (m *Memory) SetSQN(_ context.Context, id string, sqn []byte) error {
+	if err := validateSQN(sqn); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	sub.SQN = cloneBytes(sqn)
+	return nil
+}
+
+// Range implements Ranger.
+func (m *Memory) Range(ctx context.Context, fn func(*Subscriber) error) error {
+	m.mu.Lock()
+	subs := sortedClones(m.subs)
+	m.mu.Unlock()
+	return rangeSubscribers(ctx, subs, fn)
+}
+
+// sortedClones copies subs in ID order.
+func sortedClones(subs map[string]*Subscriber) []*Subscriber {
+	out := make([]*Subscriber, 0, len(subs))
+	for _, sub := range subs {
+		out = append(out, sub.clone())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func rangeSubscribers(ctx context.Context, subs []*Subscriber, fn func(*Subscriber) error) error {
+	for _, sub := range subs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy of s. The hooks, tracer and key provider are
+// process-local handles, not subscriber data, so the copy drops them.
+func (s *Subscriber) clone() *Subscriber {
+	out := *s
+	out.K = cloneBytes(s.K)
+	out.TOPc = cloneBytes(s.TOPc)
+	out.PreviousTOPc = cloneBytes(s.PreviousTOPc)
+	out.AMF = cloneBytes(s.AMF)
+	out.SQN = cloneBytes(s.SQN)
+	out.Options.DebugHook = nil
+	out.Options.RoundHook = nil
+	out.Options.Tracer = nil
+	out.Options.KeyProvider = nil
+	return &out
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,125 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tuak"
+	"tuak/store"
+	"tuak/store/storetest"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, store.NewMemory())
+}
+
+func TestFileJSON(t *testing.T) {
+	testFile(t, filepath.Join(t.TempDir(), "subscribers.json"))
+}
+
+func TestFileYAML(t *testing.T) {
+	testFile(t, filepath.Join(t.TempDir(), "subscribers.yaml"))
+}
+
+func testFile(t *testing.T, path string) {
+	f, err := store.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	storetest.Run(t, f)
+
+	reopened, err := store.OpenFile(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	sub, err := reopened.Get(context.Background(), "imsi-001010000000001")
+	if err != nil {
+		t.Fatalf("Get after reopen: %v", err)
+	}
+	if store.SQNToUint64(sub.SQN) != store.MaxSQN {
+		t.Fatalf("SQN after reopen = %x", sub.SQN)
+	}
+}
+
+func TestIncrementSQN(t *testing.T) {
+	got, err := store.IncrementSQN([]byte{0, 0, 0, 0, 0, 0xff})
+	if err != nil {
+		t.Fatalf("IncrementSQN: %v", err)
+	}
+	if store.SQNToUint64(got) != 0x100 {
+		t.Fatalf("IncrementSQN = %x", got)
+	}
+}
+
+func TestRecordProfile(t *testing.T) {
+	r := store.Record{
+		ID:        "imsi-001010000000001",
+		K:         "000102030405060708090a0b0c0d0e0f",
+		Topc:      "000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f",
+		Profile:   "ts35233-set6",
+		RESLength: 64,
+	}
+	sub, err := r.Subscriber()
+	if err != nil {
+		t.Fatalf("Subscriber: %v", err)
+	}
+	if o := sub.Options; o.MACLength != 256 || o.RESLength != 64 || o.KeccakIterations != 2 {
+		t.Fatalf("options = %+v, want set6 with 64-bit RES", o)
+	}
+	r.Profile = "missing"
+	if _, err := r.Subscriber(); !errors.Is(err, tuak.ErrUnknownProfile) {
+		t.Fatalf("unknown profile: err = %v", err)
+	}
+}
+
+func TestFileStreaming(t *testing.T) {
+	for _, name := range []string{"subscribers.json", "subscribers.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			src, dst := filepath.Join(dir, "src-"+name), filepath.Join(dir, name)
+			subs := []*store.Subscriber{
+				{ID: "imsi-2", K: make([]byte, 16), TOPc: make([]byte, 32), PreviousTOPc: bytes.Repeat([]byte{1}, 32), SQN: make([]byte, 6)},
+				{ID: "imsi-1", K: make([]byte, 32), TOPc: make([]byte, 32), SQN: make([]byte, 6), Options: tuak.Options{KLength: 256}},
+			}
+			if err := store.WriteFile(src, subs); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			fw, err := store.CreateFile(dst)
+			if err != nil {
+				t.Fatalf("CreateFile: %v", err)
+			}
+			err = store.NewFileScanner(src).Range(ctx, func(sub *store.Subscriber) error {
+				return fw.Put(ctx, sub)
+			})
+			if err != nil {
+				t.Fatalf("Range: %v", err)
+			}
+			if err := fw.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			want, _ := os.ReadFile(src)
+			got, _ := os.ReadFile(dst)
+			if !bytes.Equal(got, want) {
+				t.Fatalf("streamed copy differs:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.json")
+	fw, err := store.CreateFile(empty)
+	if err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	subs, err := store.ReadFile(empty)
+	if err != nil || len(subs) != 0 {
+		t.Fatalf("ReadFile(empty) = %d subscribers, %v", len(subs), err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package storetest provides a behavioural test suite for
+// store.SubscriberStore implementations.
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"tuak"
+	"tuak/keccak"
+	"tuak/store"
+)
+
+// Run exercises s, which must be empty.
+func Run(t *testing.T, s store.SubscriberStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	sub := &store.Subscriber{
+		ID:   "imsi-001010000000001",
+		K:    bytes.Repeat([]byte{0xab}, 16),
+		TOPc: bytes.Repeat([]byte{0x55}, 32),
+		AMF:  []byte{0x80, 0x00},
+		Options: tuak.Options{
+			MACLength:        64,
+			RESLength:        32,
+			CKLength:         128,
+			IKLength:         128,
+			KeccakIterations: 1,
+		},
+	}
+	// Process-local handles are not stored with the subscriber.
+	for _, opt := range []tuak.Option{
+		tuak.WithDebugHook(func(string, []byte) {}),
+		tuak.WithRoundHook(func(string, int, keccak.Step, []byte) {}),
+		tuak.WithTracer(slog.New(slog.NewTextHandler(io.Discard, nil)), tuak.RedactKeys),
+		tuak.WithKeyProvider(tuak.StaticKeys{K: sub.K, TOPc: sub.TOPc}),
+	} {
+		opt(&sub.Options)
+	}
+	if err := s.Put(ctx, sub); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, err := s.Get(ctx, sub.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !bytes.Equal(got.K, sub.K) || !bytes.Equal(got.TOPc, sub.TOPc) || !bytes.Equal(got.AMF, sub.AMF) {
+		t.Fatalf("Get returned different credentials")
+	}
+	if got.Options.MACLength != sub.Options.MACLength || got.Options.RESLength != sub.Options.RESLength ||
+		got.Options.CKLength != sub.Options.CKLength || got.Options.IKLength != sub.Options.IKLength ||
+		got.Options.KeccakIterations != sub.Options.KeccakIterations {
+		t.Fatalf("Options = %+v, want %+v", got.Options, sub.Options)
+	}
+	if o := got.Options; o.DebugHook != nil || o.RoundHook != nil || o.Tracer != nil || o.KeyProvider != nil {
+		t.Fatalf("Get returned the hooks, tracer or key provider")
+	}
+	if !bytes.Equal(got.SQN, make([]byte, 6)) {
+		t.Fatalf("initial SQN = %x, want zero", got.SQN)
+	}
+
+	if _, err := s.Get(ctx, "imsi-999"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Get unknown: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.NextSQN(ctx, "imsi-999"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("NextSQN unknown: err = %v, want ErrNotFound", err)
+	}
+	if err := s.SetSQN(ctx, "imsi-999", make([]byte, 6)); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("SetSQN unknown: err = %v, want ErrNotFound", err)
+	}
+
+	const workers, perWorker = 8, 25
+	var (
+		mu   sync.Mutex
+		seen = make(map[uint64]bool)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				sqn, err := s.NextSQN(ctx, sub.ID)
+				if err != nil {
+					t.Errorf("NextSQN: %v", err)
+					return
+				}
+				mu.Lock()
+				seen[store.SQNToUint64(sqn)] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != workers*perWorker {
+		t.Fatalf("NextSQN returned %d distinct values, want %d", len(seen), workers*perWorker)
+	}
+	got, err = s.Get(ctx, sub.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if v := store.SQNToUint64(got.SQN); v != workers*perWorker {
+		t.Fatalf("SQN after increments = %d, want %d", v, workers*perWorker)
+	}
+
+	if err := s.SetSQN(ctx, sub.ID, store.SQNFromUint64(store.MaxSQN)); err != nil {
+		t.Fatalf("SetSQN: %v", err)
+	}
+	if _, err := s.NextSQN(ctx, sub.ID); !errors.Is(err, store.ErrSQNExhausted) {
+		t.Fatalf("NextSQN at max: err = %v, want ErrSQNExhausted", err)
+	}
+
+	if r, ok := s.(store.Ranger); ok {
+		runRange(t, s, r)
+	}
+}
+
+// runRange checks that Range visits every subscriber in ID order and
+// round-trips PreviousTOPc.
+func runRange(t *testing.T, s store.SubscriberStore, r store.Ranger) {
+	t.Helper()
+	ctx := context.Background()
+	prev := &store.Subscriber{
+		ID:           "imsi-001010000000000",
+		K:            bytes.Repeat([]byte{0xcd}, 32),
+		TOPc:         bytes.Repeat([]byte{0x66}, 32),
+		PreviousTOPc: bytes.Repeat([]byte{0x77}, 32),
+		Options:      tuak.Options{KLength: 256},
+	}
+	if err := s.Put(ctx, prev); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	var ids []string
+	err := r.Range(ctx, func(sub *store.Subscriber) error {
+		ids = append(ids, sub.ID)
+		if sub.ID == prev.ID && !bytes.Equal(sub.PreviousTOPc, prev.PreviousTOPc) {
+			t.Errorf("Range PreviousTOPc = %x, want %x", sub.PreviousTOPc, prev.PreviousTOPc)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != prev.ID || ids[1] != "imsi-001010000000001" {
+		t.Fatalf("Range visited %q, want both subscribers in ID order", ids)
+	}
+	stop := errors.New("stop")
+	if err := r.Range(ctx, func(*store.Subscriber) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("Range: err = %v, want the callback error", err)
+	}
+}
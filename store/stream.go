@@ -0,0 +1,189 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileScanner is a Ranger over a subscriber file that decodes JSON files
+// one record at a time instead of loading them whole, for subscriber bases
+// too large for OpenFile. YAML files are read whole. Subscribers are
+// visited in file order.
+type FileScanner struct {
+	path string
+}
+
+// NewFileScanner returns a scanner over the subscriber file at path.
+func NewFileScanner(path string) *FileScanner {
+	return &FileScanner{path: path}
+}
+
+// Range implements Ranger.
+func (s *FileScanner) Range(ctx context.Context, fn func(*Subscriber) error) error {
+	if FormatForPath(s.path) == FormatYAML {
+		subs, err := ReadFile(s.path)
+		if err != nil {
+			return err
+		}
+		return rangeSubscribers(ctx, subs, fn)
+	}
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	fail := func(err error) error {
+		return fmt.Errorf("store: decode %s: %w", s.path, err)
+	}
+	if err := expectDelim(dec, '{'); err != nil {
+		return fail(err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fail(err)
+		}
+		if tok != "subscribers" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+		if err := expectDelim(dec, '['); err != nil {
+			return fail(err)
+		}
+		for dec.More() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var r Record
+			if err := dec.Decode(&r); err != nil {
+				return fail(err)
+			}
+			sub, err := r.Subscriber()
+			if err != nil {
+				return err
+			}
+			if err := fn(sub); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return fail(err)
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("unexpected %v, want %v", tok, want)
+	}
+	return nil
+}
+
+// FileWriter writes a subscriber file one subscriber at a time, in the
+// order Put is called, in the format of WriteFile. JSON is streamed to a
+// temporary file; YAML is buffered until Close. The file only replaces
+// path when Close succeeds.
+type FileWriter struct {
+	path string
+	tmp  *os.File
+	w    *bufio.Writer
+	n    int
+	yaml []*Subscriber
+	err  error
+}
+
+// CreateFile starts writing a subscriber file at path.
+func CreateFile(path string) (*FileWriter, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, err
+	}
+	fw := &FileWriter{path: path, tmp: tmp, w: bufio.NewWriter(tmp)}
+	if FormatForPath(path) == FormatJSON {
+		_, fw.err = io.WriteString(fw.w, "{\n  \"subscribers\": [")
+	}
+	return fw, nil
+}
+
+// Put implements the Put method of SubscriberStore; it is not safe for
+// concurrent use.
+func (fw *FileWriter) Put(_ context.Context, sub *Subscriber) error {
+	if fw.err != nil {
+		return fw.err
+	}
+	sub, err := normalize(sub)
+	if err != nil {
+		return err
+	}
+	if FormatForPath(fw.path) == FormatYAML {
+		fw.yaml = append(fw.yaml, sub)
+		return nil
+	}
+	b, err := json.MarshalIndent(NewRecord(sub), "    ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if fw.n == 0 {
+		sep = ""
+	}
+	fw.n++
+	if _, err := fmt.Fprintf(fw.w, "%s\n    %s", sep, b); err != nil {
+		fw.err = err
+	}
+	return fw.err
+}
+
+// Close finishes the file and moves it into place. After a failed Put the
+// file is discarded and the Put error returned.
+func (fw *FileWriter) Close() error {
+	defer os.Remove(fw.tmp.Name())
+	if fw.err != nil {
+		fw.tmp.Close()
+		return fw.err
+	}
+	if FormatForPath(fw.path) == FormatYAML {
+		fw.tmp.Close()
+		return WriteFile(fw.path, fw.yaml)
+	}
+	end := "\n  ]\n}\n"
+	if fw.n == 0 {
+		end = "]\n}\n"
+	}
+	if _, err := io.WriteString(fw.w, end); err != nil {
+		fw.tmp.Close()
+		return err
+	}
+	if err := fw.w.Flush(); err != nil {
+		fw.tmp.Close()
+		return err
+	}
+	if err := fw.tmp.Sync(); err != nil {
+		fw.tmp.Close()
+		return err
+	}
+	if err := fw.tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(fw.tmp.Name(), fw.path)
+}
+
+// Discard abandons the file, leaving any existing file at path untouched.
+func (fw *FileWriter) Discard() {
+	fw.tmp.Close()
+	os.Remove(fw.tmp.Name())
+}
@@ -0,0 +1,52 @@
+package store
+
+import "fmt"
+
+// Validate checks the field lengths of s. A nil SQN is accepted and
+// treated as zero by the stores.
+func (s *Subscriber) Validate() error {
+	if s.ID == "" {
+		return fmt.Errorf("store: empty subscriber ID")
+	}
+	if s.KeyWrap != "" {
+		if len(s.K) == 0 || len(s.TOPc) == 0 {
+			return fmt.Errorf("store: %s: missing wrapped K or TOPc", s.ID)
+		}
+	} else {
+		if len(s.K) != 16 && len(s.K) != 32 {
+			return fmt.Errorf("store: %s: invalid K length %d bytes", s.ID, len(s.K))
+		}
+		if len(s.TOPc) != 32 {
+			return fmt.Errorf("store: %s: invalid TOPc length %d bytes", s.ID, len(s.TOPc))
+		}
+		if s.PreviousTOPc != nil && len(s.PreviousTOPc) != 32 {
+			return fmt.Errorf("store: %s: invalid previous TOPc length %d bytes", s.ID, len(s.PreviousTOPc))
+		}
+	}
+	if s.AMF != nil && len(s.AMF) != 2 {
+		return fmt.Errorf("store: %s: invalid AMF length %d bytes", s.ID, len(s.AMF))
+	}
+	if s.SQN != nil {
+		return validateSQN(s.SQN)
+	}
+	return nil
+}
+
+// normalize validates sub and returns a copy with SQN defaulted to zero.
+func normalize(sub *Subscriber) (*Subscriber, error) {
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+	out := sub.clone()
+	if out.SQN == nil {
+		out.SQN = make([]byte, 6)
+	}
+	return out, nil
+}
+
+func validateSQN(sqn []byte) error {
+	if len(sqn) != 6 {
+		return fmt.Errorf("store: invalid SQN length %d bytes", len(sqn))
+	}
+	return nil
+}
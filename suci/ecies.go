@@ -0,0 +1,185 @@
+package suci
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// ECIES parameters shared by Profile A and Profile B (TS 33.501 C.3.4).
+const (
+	encKeyLen = 16
+	icbLen    = 16
+	macKeyLen = 32
+	macTagLen = 8
+)
+
+// ErrMACFailure is returned when the MAC tag of a scheme output does not verify.
+var ErrMACFailure = errors.New("suci: MAC tag verification failed")
+
+// curve returns the ECDH curve of an ECIES profile.
+func (s Scheme) curve() (ecdh.Curve, error) {
+	switch s {
+	case SchemeProfileA:
+		return ecdh.X25519(), nil
+	case SchemeProfileB:
+		return ecdh.P256(), nil
+	default:
+		return nil, fmt.Errorf("suci: scheme %d is not an ECIES profile", s)
+	}
+}
+
+// ephemeralLen is the length of the ephemeral public key in the scheme output.
+func (s Scheme) ephemeralLen() int {
+	if s == SchemeProfileB {
+		return 33 // compressed P-256 point
+	}
+	return 32
+}
+
+// encodePublic returns the scheme output encoding of a public key.
+func (s Scheme) encodePublic(pub *ecdh.PublicKey) []byte {
+	b := pub.Bytes()
+	if s != SchemeProfileB {
+		return b
+	}
+	// Compress 04 || X || Y to (02|03) || X.
+	out := make([]byte, 33)
+	out[0] = 0x02 | b[64]&1
+	copy(out[1:], b[1:33])
+	return out
+}
+
+// decodePublic parses a public key, accepting compressed and uncompressed
+// P-256 points for Profile B.
+func (s Scheme) decodePublic(b []byte) (*ecdh.PublicKey, error) {
+	c, err := s.curve()
+	if err != nil {
+		return nil, err
+	}
+	if s == SchemeProfileB && len(b) == 33 {
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), b)
+		if x == nil {
+			return nil, errors.New("suci: invalid compressed P-256 point")
+		}
+		b = uncompressed(x, y)
+	}
+	pub, err := c.NewPublicKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("suci: %w", err)
+	}
+	return pub, nil
+}
+
+func uncompressed(x, y *big.Int) []byte {
+	out := make([]byte, 65)
+	out[0] = 0x04
+	x.FillBytes(out[1:33])
+	y.FillBytes(out[33:])
+	return out
+}
+
+// encrypt produces ephemeral public key || ciphertext || MAC tag.
+func encrypt(s Scheme, hnPublic []byte, plaintext []byte, rand io.Reader) ([]byte, error) {
+	c, err := s.curve()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := s.decodePublic(hnPublic)
+	if err != nil {
+		return nil, err
+	}
+	eph, err := c.GenerateKey(rand)
+	if err != nil {
+		return nil, fmt.Errorf("suci: ephemeral key: %w", err)
+	}
+	return encryptWith(s, eph, pub, plaintext)
+}
+
+func encryptWith(s Scheme, eph *ecdh.PrivateKey, pub *ecdh.PublicKey, plaintext []byte) ([]byte, error) {
+	z, err := eph.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("suci: %w", err)
+	}
+	ephPub := s.encodePublic(eph.PublicKey())
+	encKey, icb, macKey := deriveKeys(z, ephPub)
+	out := append([]byte(nil), ephPub...)
+	ct, err := ctr(encKey, icb, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, ct...)
+	return append(out, tag(macKey, ct)...), nil
+}
+
+// decrypt verifies and decrypts a scheme output with the home network private key.
+func decrypt(s Scheme, hnPrivate []byte, output []byte) ([]byte, error) {
+	c, err := s.curve()
+	if err != nil {
+		return nil, err
+	}
+	n := s.ephemeralLen()
+	if len(output) < n+macTagLen {
+		return nil, fmt.Errorf("suci: scheme output length %d bytes", len(output))
+	}
+	priv, err := c.NewPrivateKey(hnPrivate)
+	if err != nil {
+		return nil, fmt.Errorf("suci: %w", err)
+	}
+	ephPub := output[:n]
+	ct := output[n : len(output)-macTagLen]
+	mac := output[len(output)-macTagLen:]
+	pub, err := s.decodePublic(ephPub)
+	if err != nil {
+		return nil, err
+	}
+	z, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("suci: %w", err)
+	}
+	encKey, icb, macKey := deriveKeys(z, ephPub)
+	if subtle.ConstantTimeCompare(tag(macKey, ct), mac) != 1 {
+		return nil, ErrMACFailure
+	}
+	return ctr(encKey, icb, ct)
+}
+
+// deriveKeys runs the ANSI X9.63 KDF with SHA-256 and SharedInfo1 = the
+// ephemeral public key, and splits the output into enc key, ICB and MAC key.
+func deriveKeys(z, sharedInfo []byte) (encKey, icb, macKey []byte) {
+	const n = encKeyLen + icbLen + macKeyLen
+	out := make([]byte, 0, n+sha256.Size)
+	for counter := uint32(1); len(out) < n; counter++ {
+		h := sha256.New()
+		h.Write(z)
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(sharedInfo)
+		out = h.Sum(out)
+	}
+	return out[:encKeyLen], out[encKeyLen : encKeyLen+icbLen], out[encKeyLen+icbLen : n]
+}
+
+func ctr(key, icb, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, icb).XORKeyStream(out, in)
+	return out, nil
+}
+
+func tag(macKey, ct []byte) []byte {
+	m := hmac.New(sha256.New, macKey)
+	m.Write(ct)
+	return m.Sum(nil)[:macTagLen]
+}
@@ -0,0 +1,112 @@
+package suci
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownKey is returned when no home network private key matches a SUCI.
+var ErrUnknownKey = errors.New("suci: unknown home network public key id")
+
+// PublicKey is a home network public key as provisioned on the USIM.
+// Profile B keys may be compressed or uncompressed.
+type PublicKey struct {
+	Scheme Scheme
+	ID     uint8
+	Key    []byte
+}
+
+// PrivateKey is a home network private key (a 32-byte scalar).
+type PrivateKey struct {
+	Scheme Scheme
+	ID     uint8
+	Key    []byte
+}
+
+// Public returns the public key; Profile B keys are returned compressed.
+func (k PrivateKey) Public() (PublicKey, error) {
+	c, err := k.Scheme.curve()
+	if err != nil {
+		return PublicKey{}, err
+	}
+	priv, err := c.NewPrivateKey(k.Key)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("suci: %w", err)
+	}
+	return PublicKey{Scheme: k.Scheme, ID: k.ID, Key: k.Scheme.encodePublic(priv.PublicKey())}, nil
+}
+
+// KeyStore holds the home network private keys by public key identifier.
+// It is safe for concurrent use.
+type KeyStore struct {
+	mu   sync.RWMutex
+	keys map[uint8]PrivateKey
+}
+
+// NewKeyStore returns a key store holding keys.
+func NewKeyStore(keys ...PrivateKey) (*KeyStore, error) {
+	ks := &KeyStore{keys: make(map[uint8]PrivateKey)}
+	for _, k := range keys {
+		if err := ks.Add(k); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Add validates and stores a private key, replacing any key with the same ID.
+func (ks *KeyStore) Add(k PrivateKey) error {
+	if _, err := k.Public(); err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[k.ID] = PrivateKey{Scheme: k.Scheme, ID: k.ID, Key: append([]byte(nil), k.Key...)}
+	return nil
+}
+
+// lookup returns the private key for id; a nil store holds no keys.
+func (ks *KeyStore) lookup(id uint8) (PrivateKey, bool) {
+	if ks == nil {
+		return PrivateKey{}, false
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[id]
+	return k, ok
+}
+
+// Deconceal recovers the SUPI ("imsi-" followed by MCC, MNC and MSIN).
+// A nil store de-conceals null-scheme SUCIs only.
+func (ks *KeyStore) Deconceal(s *SUCI) (string, error) {
+	msin := s.SchemeOutput
+	if s.Scheme != SchemeNull {
+		k, ok := ks.lookup(s.KeyID)
+		if !ok || k.Scheme != s.Scheme {
+			return "", fmt.Errorf("%w %d for scheme %d", ErrUnknownKey, s.KeyID, s.Scheme)
+		}
+		var err error
+		if msin, err = decrypt(s.Scheme, k.Key, s.SchemeOutput); err != nil {
+			return "", err
+		}
+	}
+	digits := decodeTBCD(msin)
+	if digits == "" {
+		return "", fmt.Errorf("suci: scheme input %x is not a TBCD MSIN", msin)
+	}
+	return "imsi-" + s.MCC + s.MNC + digits, nil
+}
+
+// SUPI returns id unchanged when it is a SUPI and de-conceals it when it
+// is a SUCI string.
+func (ks *KeyStore) SUPI(id string) (string, error) {
+	if !IsSUCI(id) {
+		return id, nil
+	}
+	s, err := Parse(id)
+	if err != nil {
+		return "", err
+	}
+	return ks.Deconceal(s)
+}
@@ -0,0 +1,50 @@
+package suci
+
+import (
+	"context"
+	"fmt"
+
+	"tuak"
+	"tuak/store"
+)
+
+// Resolver maps a SUPI or SUCI to the subscriber and TUAK context used to
+// authenticate it.
+type Resolver struct {
+	Keys        *KeyStore
+	Subscribers store.SubscriberStore
+	// KeyProvider unwraps K and TOPc of subscribers stored with
+	// store.Subscriber.KeyWrap set; it may be nil if none are.
+	KeyProvider func(sub *store.Subscriber) (tuak.KeyProvider, error)
+}
+
+// Subscriber de-conceals id if needed and looks up the subscriber by SUPI.
+func (r *Resolver) Subscriber(ctx context.Context, id string) (*store.Subscriber, error) {
+	supi, err := r.Keys.SUPI(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.Subscribers.Get(ctx, supi)
+}
+
+// TUAK resolves id and returns a TUAK context for the subscriber with the
+// given RAND, SQN and AMF, together with the subscriber record.
+func (r *Resolver) TUAK(ctx context.Context, id string, rand, sqn, amf []byte) (*tuak.TUAK, *store.Subscriber, error) {
+	sub, err := r.Subscriber(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	k, topc, opts := sub.K, sub.TOPc, sub.TUAKOptions()
+	if sub.KeyWrap != "" {
+		if r.KeyProvider == nil {
+			return nil, nil, fmt.Errorf("suci: %s: no key provider for wrapped keys", sub.ID)
+		}
+		p, err := r.KeyProvider(sub)
+		if err != nil {
+			return nil, nil, err
+		}
+		k, topc, opts = nil, nil, append(opts, tuak.WithKeyProvider(p))
+	}
+	t, err := tuak.NewWithTOPc(k, topc, rand, sqn, amf, opts...)
+	return t, sub, err
+}
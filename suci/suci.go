@@ -0,0 +1,170 @@
+// Package suci implements SUCI concealment and de-concealment
+// (TS 33.501 Annex C, TS 23.003 2.2B): the null scheme and the ECIES
+// Profile A (X25519) and Profile B (P-256) protection schemes.
+package suci
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Scheme is a protection scheme identifier (TS 33.501 C.1).
+type Scheme uint8
+
+// Protection schemes.
+const (
+	SchemeNull     Scheme = 0
+	SchemeProfileA Scheme = 1
+	SchemeProfileB Scheme = 2
+)
+
+// ErrUnsupportedSUCI is returned for SUCIs this package cannot parse,
+// e.g. NAI-type SUPIs.
+var ErrUnsupportedSUCI = errors.New("suci: unsupported SUCI")
+
+// SUCI is an IMSI-type subscription concealed identifier.
+type SUCI struct {
+	MCC              string
+	MNC              string
+	RoutingIndicator string
+	Scheme           Scheme
+	// KeyID is the home network public key identifier (0 for the null scheme).
+	KeyID uint8
+	// SchemeOutput is the TBCD-encoded MSIN for the null scheme and
+	// ephemeral public key || ciphertext || MAC tag for the ECIES profiles.
+	SchemeOutput []byte
+}
+
+// Parse parses the SUCI NAI-less string form
+// "suci-0-<MCC>-<MNC>-<routing indicator>-<scheme>-<key id>-<scheme output>"
+// used by TS 29.503.
+func Parse(s string) (*SUCI, error) {
+	f := strings.Split(s, "-")
+	if len(f) != 8 || f[0] != "suci" {
+		return nil, fmt.Errorf("suci: malformed SUCI %q", s)
+	}
+	if f[1] != "0" {
+		return nil, fmt.Errorf("%w: SUPI type %s", ErrUnsupportedSUCI, f[1])
+	}
+	if !isDigits(f[2], 3, 3) || !isDigits(f[3], 2, 3) || !isDigits(f[4], 1, 4) {
+		return nil, fmt.Errorf("suci: malformed SUCI %q", s)
+	}
+	scheme, err := strconv.ParseUint(f[5], 10, 4)
+	if err != nil {
+		return nil, fmt.Errorf("suci: protection scheme %q", f[5])
+	}
+	keyID, err := strconv.ParseUint(f[6], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("suci: home network public key id %q", f[6])
+	}
+	out := &SUCI{
+		MCC:              f[2],
+		MNC:              f[3],
+		RoutingIndicator: f[4],
+		Scheme:           Scheme(scheme),
+		KeyID:            uint8(keyID),
+	}
+	if out.Scheme == SchemeNull {
+		if !isDigits(f[7], 1, 10) {
+			return nil, fmt.Errorf("suci: invalid MSIN %q", f[7])
+		}
+		out.SchemeOutput = encodeTBCD(f[7])
+		return out, nil
+	}
+	if out.SchemeOutput, err = hex.DecodeString(f[7]); err != nil {
+		return nil, fmt.Errorf("suci: scheme output: %w", err)
+	}
+	return out, nil
+}
+
+// String returns the string form accepted by Parse.
+func (s *SUCI) String() string {
+	output := hex.EncodeToString(s.SchemeOutput)
+	if s.Scheme == SchemeNull {
+		output = decodeTBCD(s.SchemeOutput)
+	}
+	return fmt.Sprintf("suci-0-%s-%s-%s-%d-%d-%s", s.MCC, s.MNC, s.RoutingIndicator, s.Scheme, s.KeyID, output)
+}
+
+// Conceal builds the SUCI of the IMSI MCC || MNC || MSIN under the home
+// network public key; a key with SchemeNull yields the null-scheme SUCI.
+// rand supplies the ephemeral key of the ECIES profiles.
+func Conceal(mcc, mnc, msin, routingIndicator string, key PublicKey, rand io.Reader) (*SUCI, error) {
+	if !isDigits(mcc, 3, 3) || !isDigits(mnc, 2, 3) || !isDigits(msin, 1, 10) {
+		return nil, fmt.Errorf("suci: invalid IMSI %s-%s-%s", mcc, mnc, msin)
+	}
+	if routingIndicator == "" {
+		routingIndicator = "0"
+	}
+	s := &SUCI{
+		MCC:              mcc,
+		MNC:              mnc,
+		RoutingIndicator: routingIndicator,
+		Scheme:           key.Scheme,
+		SchemeOutput:     encodeTBCD(msin),
+	}
+	if key.Scheme == SchemeNull {
+		return s, nil
+	}
+	out, err := encrypt(key.Scheme, key.Key, s.SchemeOutput, rand)
+	if err != nil {
+		return nil, err
+	}
+	s.KeyID = key.ID
+	s.SchemeOutput = out
+	return s, nil
+}
+
+// IsSUCI reports whether id is in SUCI string form rather than a SUPI.
+func IsSUCI(id string) bool {
+	return strings.HasPrefix(id, "suci-")
+}
+
+func isDigits(s string, min, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeTBCD packs decimal digits two per octet, first digit in the low
+// nibble, padding an odd count with 0xF.
+func encodeTBCD(digits string) []byte {
+	out := make([]byte, (len(digits)+1)/2)
+	for i := range out {
+		lo := digits[2*i] - '0'
+		hi := byte(0xF)
+		if 2*i+1 < len(digits) {
+			hi = digits[2*i+1] - '0'
+		}
+		out[i] = hi<<4 | lo
+	}
+	return out
+}
+
+// decodeTBCD reverses encodeTBCD. It returns "" if a nibble other than the
+// trailing filler is not a decimal digit.
+func decodeTBCD(b []byte) string {
+	var sb strings.Builder
+	for i, v := range b {
+		for j, d := range []byte{v & 0x0F, v >> 4} {
+			if d == 0xF && i == len(b)-1 && j == 1 {
+				break
+			}
+			if d > 9 {
+				return ""
+			}
+			sb.WriteByte('0' + d)
+		}
+	}
+	return sb.String()
+}
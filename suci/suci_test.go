@@ -0,0 +1,211 @@
+package suci
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"tuak"
+	"tuak/store"
+	"tuak/testvectors"
+)
+
+const annexSUPI = "imsi-274012001002086"
+
+func TestAnnexC4Deconceal(t *testing.T) {
+	for _, v := range loadVectors(t) {
+		ks := newKeyStore(t, v)
+		output := v.SchemeOutput
+		if Scheme(v.Scheme) == SchemeNull {
+			output = v.MSIN
+		}
+		id := fmt.Sprintf("suci-0-%s-%s-0-%d-%d-%s", v.MCC, v.MNC, v.Scheme, v.KeyID, output)
+		s, err := Parse(id)
+		if err != nil {
+			t.Fatalf("vector %d Parse: %v", v.ID, err)
+		}
+		if s.String() != id {
+			t.Fatalf("vector %d String = %q, want %q", v.ID, s.String(), id)
+		}
+		if !bytes.Equal(encodeTBCD(v.MSIN), decodeHex(t, v.SchemeInput)) {
+			t.Fatalf("vector %d scheme input mismatch", v.ID)
+		}
+		supi, err := ks.SUPI(id)
+		if err != nil {
+			t.Fatalf("vector %d SUPI: %v", v.ID, err)
+		}
+		if supi != annexSUPI {
+			t.Fatalf("vector %d SUPI = %q, want %q", v.ID, supi, annexSUPI)
+		}
+	}
+}
+
+func TestAnnexC4PublicKeys(t *testing.T) {
+	for _, v := range loadVectors(t) {
+		if Scheme(v.Scheme) == SchemeNull {
+			continue
+		}
+		pub, err := PrivateKey{Scheme: Scheme(v.Scheme), Key: decodeHex(t, v.HNPrivateKey)}.Public()
+		if err != nil {
+			t.Fatalf("vector %d Public: %v", v.ID, err)
+		}
+		if got := hex.EncodeToString(pub.Key); got != v.HNPublicKey {
+			t.Fatalf("vector %d public key = %s, want %s", v.ID, got, v.HNPublicKey)
+		}
+	}
+}
+
+func TestAnnexC4ProfileBConceal(t *testing.T) {
+	for _, v := range loadVectors(t) {
+		if v.EphPrivateKey == "" {
+			continue
+		}
+		s := Scheme(v.Scheme)
+		c, _ := s.curve()
+		eph, err := c.NewPrivateKey(decodeHex(t, v.EphPrivateKey))
+		if err != nil {
+			t.Fatalf("vector %d ephemeral key: %v", v.ID, err)
+		}
+		pub, err := s.decodePublic(decodeHex(t, v.HNPublicKey))
+		if err != nil {
+			t.Fatalf("vector %d home network key: %v", v.ID, err)
+		}
+		out, err := encryptWith(s, eph, pub, decodeHex(t, v.SchemeInput))
+		if err != nil {
+			t.Fatalf("vector %d encrypt: %v", v.ID, err)
+		}
+		if got := hex.EncodeToString(out); got != v.SchemeOutput {
+			t.Fatalf("vector %d scheme output = %s, want %s", v.ID, got, v.SchemeOutput)
+		}
+	}
+}
+
+func TestConcealRoundTrip(t *testing.T) {
+	for _, v := range loadVectors(t) {
+		ks := newKeyStore(t, v)
+		key := PublicKey{Scheme: Scheme(v.Scheme), ID: uint8(v.KeyID), Key: decodeHex(t, v.HNPublicKey)}
+		s, err := Conceal("001", "01", "0000000001", "0000", key, crand.Reader)
+		if err != nil {
+			t.Fatalf("vector %d Conceal: %v", v.ID, err)
+		}
+		parsed, err := Parse(s.String())
+		if err != nil {
+			t.Fatalf("vector %d Parse(%q): %v", v.ID, s, err)
+		}
+		supi, err := ks.Deconceal(parsed)
+		if err != nil || supi != "imsi-001010000000001" {
+			t.Fatalf("vector %d Deconceal = %q, %v", v.ID, supi, err)
+		}
+		if key.Scheme == SchemeNull {
+			continue
+		}
+
+		parsed.SchemeOutput[len(parsed.SchemeOutput)-1] ^= 0x01
+		if _, err := ks.Deconceal(parsed); !errors.Is(err, ErrMACFailure) {
+			t.Fatalf("vector %d tampered MAC: err = %v, want ErrMACFailure", v.ID, err)
+		}
+		parsed.KeyID++
+		if _, err := ks.Deconceal(parsed); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("vector %d unknown key: err = %v, want ErrUnknownKey", v.ID, err)
+		}
+		var none *KeyStore
+		if _, err := none.Deconceal(s); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("vector %d nil store: err = %v, want ErrUnknownKey", v.ID, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, id := range []string{
+		"imsi-001010000000001",
+		"suci-0-001-01-0-0-0",
+		"suci-0-01-01-0-0-0-0000000001",
+		"suci-0-001-01-0-0-0-12ab",
+		"suci-0-001-01-0-1-1-zz",
+		"suci-0-001-01-0-16-1-00",
+	} {
+		if _, err := Parse(id); err == nil {
+			t.Fatalf("Parse(%q) succeeded", id)
+		}
+	}
+	if _, err := Parse("suci-1-001-01-0-0-0-user"); !errors.Is(err, ErrUnsupportedSUCI) {
+		t.Fatalf("NAI-type SUCI: err = %v, want ErrUnsupportedSUCI", err)
+	}
+}
+
+func TestResolverTUAK(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	tv := data.Tests[0]
+	subs := store.NewMemory()
+	err = subs.Put(context.Background(), &store.Subscriber{
+		ID:   annexSUPI,
+		K:    decodeHex(t, tv.K),
+		TOPc: decodeHex(t, tv.Topc),
+		Options: tuak.Options{
+			MACLength:        tv.MAClength,
+			RESLength:        tv.RESLength,
+			CKLength:         tv.CKlength,
+			IKLength:         tv.IKlength,
+			KeccakIterations: tv.KeccakIterations,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	v := loadVectors(t)[1]
+	r := &Resolver{Keys: newKeyStore(t, v), Subscribers: subs}
+	id := fmt.Sprintf("suci-0-%s-%s-0-%d-%d-%s", v.MCC, v.MNC, v.Scheme, v.KeyID, v.SchemeOutput)
+
+	tk, sub, err := r.TUAK(context.Background(), id, decodeHex(t, tv.Rand), decodeHex(t, tv.SQN), decodeHex(t, tv.AMF))
+	if err != nil {
+		t.Fatalf("TUAK: %v", err)
+	}
+	if sub.ID != annexSUPI {
+		t.Fatalf("subscriber = %q, want %q", sub.ID, annexSUPI)
+	}
+	mac, err := tk.F1()
+	if err != nil {
+		t.Fatalf("F1: %v", err)
+	}
+	if !bytes.Equal(mac, decodeHex(t, tv.F1)) {
+		t.Fatalf("MAC-A = %x, want %s", mac, tv.F1)
+	}
+}
+
+func newKeyStore(t *testing.T, v testvectors.SUCIVector) *KeyStore {
+	t.Helper()
+	var keys []PrivateKey
+	if Scheme(v.Scheme) != SchemeNull {
+		keys = append(keys, PrivateKey{Scheme: Scheme(v.Scheme), ID: uint8(v.KeyID), Key: decodeHex(t, v.HNPrivateKey)})
+	}
+	ks, err := NewKeyStore(keys...)
+	if err != nil {
+		t.Fatalf("NewKeyStore: %v", err)
+	}
+	return ks
+}
+
+func loadVectors(t *testing.T) []testvectors.SUCIVector {
+	t.Helper()
+	data, err := testvectors.LoadSUCIVectors()
+	if err != nil {
+		t.Fatalf("LoadSUCIVectors: %v", err)
+	}
+	return data.Tests
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
@@ -0,0 +1,228 @@
+package testvectors
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// Keccak is a decoded Keccak-f[1600] test set.
+type Keccak struct {
+	ID  int
+	In  []byte
+	Out []byte
+}
+
+// Set is a decoded TUAK test set. Lengths are in bits. Values the source
+// document does not give are nil (or zero): the TS 35.232 f2-f5 sets have
+// no SQN, AMF, f1 or f1* and no MAC length.
+type Set struct {
+	// Source is "TS 35.233" or "TS 35.232".
+	Source string
+	ID     int
+
+	K, RAND, SQN, AMF, TOP, TOPc []byte
+
+	F1, F1Star, F2, F3, F4, F5, F5Star []byte
+
+	KLength, MACLength, RESLength, CKLength, IKLength, KeccakIterations int
+}
+
+// Name identifies the set in test output, e.g. "TS 35.233 set 1".
+func (s Set) Name() string {
+	return fmt.Sprintf("%s set %d", s.Source, s.ID)
+}
+
+// KeccakSets returns the decoded Keccak-f[1600] sets of TS 35.232.
+func KeccakSets() ([]Keccak, error) {
+	data, err := LoadKeccakVectors()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Keccak, 0, len(data.KeccakF1600))
+	for _, v := range data.KeccakF1600 {
+		k, err := v.Decode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// TUAKSets returns the decoded TUAK conformance sets of TS 35.233.
+func TUAKSets() ([]Set, error) {
+	data, err := LoadTUAKVectors()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Set, 0, len(data.Tests))
+	for _, v := range data.Tests {
+		s, err := v.Decode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// F2345Sets returns the decoded f2-f5 sets of TS 35.232.
+func F2345Sets() ([]Set, error) {
+	data, err := LoadF2345Vectors()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Set, 0, len(data.Tests))
+	for _, v := range data.Tests {
+		s, err := v.Decode()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// Decode returns the vector with its hex fields decoded.
+func (v KeccakVector) Decode() (Keccak, error) {
+	d := decoder{id: v.ID}
+	k := Keccak{ID: v.ID, In: d.hex("in", v.In), Out: d.hex("out", v.Out)}
+	return k, d.err
+}
+
+// Decode returns the vector with its hex fields decoded.
+func (v TUAKVector) Decode() (Set, error) {
+	d := decoder{id: v.ID}
+	s := Set{
+		Source:           "TS 35.233",
+		ID:               v.ID,
+		K:                d.hex("k", v.K),
+		RAND:             d.hex("rand", v.Rand),
+		SQN:              d.hex("sqn", v.SQN),
+		AMF:              d.hex("amf", v.AMF),
+		TOP:              d.hex("top", v.Top),
+		TOPc:             d.hex("topc", v.Topc),
+		F1:               d.hex("f1", v.F1),
+		F1Star:           d.hex("f1_star", v.F1Star),
+		F2:               d.hex("f2", v.F2),
+		F3:               d.hex("f3", v.F3),
+		F4:               d.hex("f4", v.F4),
+		F5:               d.hex("f5", v.F5),
+		F5Star:           d.hex("f5_star", v.F5Star),
+		KLength:          v.Klength,
+		MACLength:        v.MAClength,
+		RESLength:        v.RESLength,
+		CKLength:         v.CKlength,
+		IKLength:         v.IKlength,
+		KeccakIterations: v.KeccakIterations,
+	}
+	return s, d.err
+}
+
+// Decode returns the vector with its hex fields decoded. RESLength is
+// taken from the length of f2.
+func (v F2345Vector) Decode() (Set, error) {
+	d := decoder{id: v.ID}
+	s := Set{
+		Source:           "TS 35.232",
+		ID:               v.ID,
+		K:                d.hex("k", v.K),
+		RAND:             d.hex("rand", v.Rand),
+		TOP:              d.hex("top", v.Top),
+		TOPc:             d.hex("topc", v.Topc),
+		F2:               d.hex("f2", v.F2),
+		F3:               d.hex("f3", v.F3),
+		F4:               d.hex("f4", v.F4),
+		F5:               d.hex("f5", v.F5),
+		F5Star:           d.hex("f5_star", v.F5Star),
+		KLength:          v.Klength,
+		RESLength:        len(v.F2) * 4,
+		CKLength:         v.CKlength,
+		IKLength:         v.IKlength,
+		KeccakIterations: v.KeccakIterations,
+	}
+	return s, d.err
+}
+
+// decoder decodes hex fields of one vector and keeps the first error.
+type decoder struct {
+	id  int
+	err error
+}
+
+func (d *decoder) hex(name, s string) []byte {
+	if s == "" || d.err != nil {
+		return nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		d.err = fmt.Errorf("vector %d %s: %w", d.id, name, err)
+	}
+	return b
+}
+
+// Implementation is a TUAK implementation under test. Each method receives
+// the whole set and returns one function's output; adapters typically build
+// their context from the set's K (or TOPc), RAND, SQN, AMF and lengths.
+type Implementation interface {
+	TOPc(s Set) ([]byte, error)
+	F1(s Set) ([]byte, error)
+	F1Star(s Set) ([]byte, error)
+	F2345(s Set) (res, ck, ik, ak []byte, err error)
+	F5Star(s Set) ([]byte, error)
+}
+
+// RunConformance runs the TS 35.233 conformance sets and the TS 35.232
+// f2-f5 sets against impl, one subtest per set. Functions are only called
+// when the set gives their expected output.
+func RunConformance(t *testing.T, impl Implementation) {
+	t.Helper()
+	sets, err := TUAKSets()
+	if err != nil {
+		t.Fatalf("TUAKSets: %v", err)
+	}
+	f2345, err := F2345Sets()
+	if err != nil {
+		t.Fatalf("F2345Sets: %v", err)
+	}
+	for _, s := range append(sets, f2345...) {
+		t.Run(s.Name(), func(t *testing.T) {
+			if s.TOP != nil && s.TOPc != nil {
+				got, err := impl.TOPc(s)
+				check(t, "TOPc", got, s.TOPc, err)
+			}
+			if s.F1 != nil {
+				got, err := impl.F1(s)
+				check(t, "f1", got, s.F1, err)
+			}
+			if s.F1Star != nil {
+				got, err := impl.F1Star(s)
+				check(t, "f1*", got, s.F1Star, err)
+			}
+			if s.F2 != nil {
+				res, ck, ik, ak, err := impl.F2345(s)
+				check(t, "f2", res, s.F2, err)
+				check(t, "f3", ck, s.F3, err)
+				check(t, "f4", ik, s.F4, err)
+				check(t, "f5", ak, s.F5, err)
+			}
+			if s.F5Star != nil {
+				got, err := impl.F5Star(s)
+				check(t, "f5*", got, s.F5Star, err)
+			}
+		})
+	}
+}
+
+func check(t *testing.T, name string, got, want []byte, err error) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s = %x, want %x", name, got, want)
+	}
+}
@@ -1,15 +1,19 @@
-// Package testvectors loads JSON fixtures for TUAK tests.
+// Package testvectors provides the 3GPP TS 35.232/35.233 conformance test
+// sets and the TS 33.501 SUCI vectors. The JSON fixtures are embedded, so
+// the package works from the module cache and vendored trees.
 package testvectors
 
+//go:generate go run ../cmd/gentestdata -reference ../reference -testdata testdata
+
 import (
+	"embed"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"runtime"
 )
 
+//go:embed testdata/*.json
+var fixtures embed.FS
+
 // KeccakVector holds a single Keccak-f[1600] test case.
 type KeccakVector struct {
 	ID     int    `json:"id"`
@@ -55,21 +59,23 @@ type TUAKFile struct {
 	Tests  []TUAKVector `json:"tests"`
 }
 
-// F2345Vector holds f2-f5 vectors from TS 35.232.
+// F2345Vector holds f2-f5 vectors from TS 35.232. KeccakIterations is
+// needed to run the sets; the fixture records it for every set.
 type F2345Vector struct {
-	ID       int    `json:"id"`
-	K        string `json:"k"`
-	Rand     string `json:"rand"`
-	Top      string `json:"top"`
-	Topc     string `json:"topc"`
-	F2       string `json:"f2"`
-	F3       string `json:"f3"`
-	F4       string `json:"f4"`
-	F5       string `json:"f5"`
-	F5Star   string `json:"f5_star"`
-	Klength  int    `json:"klength"`
-	CKlength int    `json:"cklength"`
-	IKlength int    `json:"iklength"`
+	ID               int    `json:"id"`
+	K                string `json:"k"`
+	Rand             string `json:"rand"`
+	Top              string `json:"top"`
+	Topc             string `json:"topc"`
+	F2               string `json:"f2"`
+	F3               string `json:"f3"`
+	F4               string `json:"f4"`
+	F5               string `json:"f5"`
+	F5Star           string `json:"f5_star"`
+	Klength          int    `json:"klength"`
+	CKlength         int    `json:"cklength"`
+	IKlength         int    `json:"iklength"`
+	KeccakIterations int    `json:"keccak_iterations"`
 }
 
 // F2345File is the JSON container for f2-f5 vectors.
@@ -78,6 +84,32 @@ type F2345File struct {
 	Tests  []F2345Vector `json:"tests"`
 }
 
+// SUCIVector holds a SUCI protection scheme test case from TS 33.501 C.4.
+// Ephemeral private keys are only present where the specification
+// publishes them.
+type SUCIVector struct {
+	ID            int    `json:"id"`
+	Scheme        int    `json:"scheme"`
+	KeyID         int    `json:"key_id"`
+	MCC           string `json:"mcc"`
+	MNC           string `json:"mnc"`
+	MSIN          string `json:"msin"`
+	HNPrivateKey  string `json:"hn_private_key"`
+	HNPublicKey   string `json:"hn_public_key"`
+	EphPrivateKey string `json:"eph_private_key"`
+	EphPublicKey  string `json:"eph_public_key"`
+	SchemeInput   string `json:"scheme_input"`
+	Ciphertext    string `json:"ciphertext"`
+	MACTag        string `json:"mac_tag"`
+	SchemeOutput  string `json:"scheme_output"`
+}
+
+// SUCIFile is the JSON container for SUCI vectors.
+type SUCIFile struct {
+	Source string       `json:"source"`
+	Tests  []SUCIVector `json:"tests"`
+}
+
 // LoadKeccakVectors loads Keccak-f[1600] test vectors.
 func LoadKeccakVectors() (*KeccakFile, error) {
 	var data KeccakFile
@@ -114,45 +146,22 @@ func LoadF2345Vectors() (*F2345File, error) {
 	return &data, nil
 }
 
-func loadJSON(filename string, out interface{}) error {
-	path, err := testdataPath(filename)
-	if err != nil {
-		return err
-	}
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("read %s: %w", path, err)
-	}
-	if err := json.Unmarshal(b, out); err != nil {
-		return fmt.Errorf("decode %s: %w", path, err)
+// LoadSUCIVectors loads SUCI vectors from JSON fixtures.
+func LoadSUCIVectors() (*SUCIFile, error) {
+	var data SUCIFile
+	if err := loadJSON("ts33501_suci.json", &data); err != nil {
+		return nil, err
 	}
-	return nil
+	return &data, nil
 }
 
-func testdataPath(filename string) (string, error) {
-	root, err := repoRoot()
+func loadJSON(filename string, out interface{}) error {
+	b, err := fixtures.ReadFile("testdata/" + filename)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("read %s: %w", filename, err)
 	}
-	return filepath.Join(root, "testdata", filename), nil
-}
-
-func repoRoot() (string, error) {
-	_, file, _, ok := runtime.Caller(0)
-	if !ok {
-		return "", errors.New("runtime.Caller failed")
-	}
-
-	dir := filepath.Dir(file)
-	for {
-		candidate := filepath.Join(dir, "testdata", "ts35232_keccak.json")
-		if _, err := os.Stat(candidate); err == nil {
-			return dir, nil
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			return "", fmt.Errorf("testdata not found from %s", file)
-		}
-		dir = parent
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("decode %s: %w", filename, err)
 	}
+	return nil
 }
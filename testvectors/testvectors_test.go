@@ -1,6 +1,11 @@
 package testvectors
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"tuak"
+)
 
 func TestLoadKeccakVectors(t *testing.T) {
 	data, err := LoadKeccakVectors()
@@ -50,3 +55,113 @@ func checkLen(t *testing.T, id int, name, hex string, bits int) {
 		t.Fatalf("vector %d %s bit length = %d, want %d", id, name, len(hex)*4, bits)
 	}
 }
+
+func TestLoadSUCIVectors(t *testing.T) {
+	data, err := LoadSUCIVectors()
+	if err != nil {
+		t.Fatalf("LoadSUCIVectors: %v", err)
+	}
+	if got := len(data.Tests); got != 3 {
+		t.Fatalf("SUCI test count = %d, want 3", got)
+	}
+	for _, v := range data.Tests {
+		if v.Scheme == 0 {
+			continue
+		}
+		if v.SchemeOutput != v.EphPublicKey+v.Ciphertext+v.MACTag {
+			t.Fatalf("vector %d scheme output is not eph key || ciphertext || MAC tag", v.ID)
+		}
+	}
+}
+
+func TestDecodedSets(t *testing.T) {
+	keccak, err := KeccakSets()
+	if err != nil {
+		t.Fatalf("KeccakSets: %v", err)
+	}
+	for _, k := range keccak {
+		if len(k.In) != 200 || len(k.Out) != 200 {
+			t.Fatalf("Keccak set %d: %d/%d bytes", k.ID, len(k.In), len(k.Out))
+		}
+	}
+	sets, err := TUAKSets()
+	if err != nil {
+		t.Fatalf("TUAKSets: %v", err)
+	}
+	if len(sets) != 6 || len(sets[0].K)*8 != sets[0].KLength || len(sets[0].SQN) != 6 {
+		t.Fatalf("TUAKSets = %d sets, first %+v", len(sets), sets[0])
+	}
+	f2345, err := F2345Sets()
+	if err != nil {
+		t.Fatalf("F2345Sets: %v", err)
+	}
+	for _, s := range f2345 {
+		if s.SQN != nil || s.F1 != nil || len(s.F2)*8 != s.RESLength {
+			t.Fatalf("%s = %+v", s.Name(), s)
+		}
+	}
+	if _, err := (TUAKVector{ID: 9, K: "zz"}).Decode(); err == nil || !strings.Contains(err.Error(), "vector 9 k") {
+		t.Fatalf("Decode bad hex: err = %v", err)
+	}
+}
+
+func TestRunConformance(t *testing.T) {
+	RunConformance(t, tuakImpl{})
+}
+
+// tuakImpl runs the conformance sets against this module's TUAK.
+type tuakImpl struct{}
+
+func (tuakImpl) options(s Set) []tuak.Option {
+	opts := []tuak.Option{
+		tuak.WithKLength(s.KLength),
+		tuak.WithRESLength(s.RESLength),
+		tuak.WithCKLength(s.CKLength),
+		tuak.WithIKLength(s.IKLength),
+		tuak.WithKeccakIterations(s.KeccakIterations),
+	}
+	if s.MACLength != 0 {
+		opts = append(opts, tuak.WithMACLength(s.MACLength))
+	}
+	return opts
+}
+
+func (i tuakImpl) new(s Set) (*tuak.TUAK, error) {
+	return tuak.NewWithTOPc(s.K, s.TOPc, s.RAND, s.SQN, s.AMF, i.options(s)...)
+}
+
+func (i tuakImpl) TOPc(s Set) ([]byte, error) {
+	return tuak.ComputeTOPc(s.K, s.TOP, i.options(s)...)
+}
+
+func (i tuakImpl) F1(s Set) ([]byte, error) {
+	tk, err := i.new(s)
+	if err != nil {
+		return nil, err
+	}
+	return tk.F1()
+}
+
+func (i tuakImpl) F1Star(s Set) ([]byte, error) {
+	tk, err := i.new(s)
+	if err != nil {
+		return nil, err
+	}
+	return tk.F1Star()
+}
+
+func (i tuakImpl) F2345(s Set) (res, ck, ik, ak []byte, err error) {
+	tk, err := i.new(s)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return tk.F2345()
+}
+
+func (i tuakImpl) F5Star(s Set) ([]byte, error) {
+	tk, err := i.new(s)
+	if err != nil {
+		return nil, err
+	}
+	return tk.F5Star()
+}
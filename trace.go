@@ -0,0 +1,111 @@
+package tuak
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+)
+
+// Redaction selects which decoded state fields a Tracer masks.
+type Redaction int
+
+const (
+	// RedactKeys masks K, TOP and TOPc (the default).
+	RedactKeys Redaction = iota
+	// RedactSecrets additionally masks RES, CK, IK, AK and AK*.
+	RedactSecrets
+	// RedactNone logs every field and the raw state; for test data only.
+	RedactNone
+)
+
+// fieldClass orders state fields by sensitivity.
+type fieldClass int
+
+const (
+	classPublic fieldClass = iota
+	classDerived
+	classKey
+)
+
+func (r Redaction) masks(c fieldClass) bool {
+	switch r {
+	case RedactNone:
+		return false
+	case RedactSecrets:
+		return c >= classDerived
+	default:
+		return c == classKey
+	}
+}
+
+// Tracer logs TUAK stages ("topc.in", "f1.out", "f2345.out.2", ...) as
+// structured slog records at debug level. Create one with WithTracer.
+type Tracer struct {
+	logger    *slog.Logger
+	redaction Redaction
+}
+
+type stateField struct {
+	name  string
+	value []byte
+	class fieldClass
+}
+
+func (tr *Tracer) trace(label string, state []byte, o Options) {
+	ctx := context.Background()
+	if tr.logger == nil || !tr.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	fields := traceFields(label, state, o)
+	attrs := make([]any, 0, len(fields)+1)
+	for _, f := range fields {
+		if tr.redaction.masks(f.class) {
+			attrs = append(attrs, slog.String(f.name, "redacted"))
+		} else {
+			attrs = append(attrs, slog.String(f.name, f.text()))
+		}
+	}
+	if tr.redaction == RedactNone {
+		attrs = append(attrs, slog.String("raw", hex.EncodeToString(state)))
+	}
+	stage := []slog.Attr{slog.String("stage", label)}
+	if o.ExperimentalKeccakRounds != 0 {
+		// Reduced-round output is not TUAK; say so in every record.
+		stage = append(stage, slog.Int("experimental_keccak_rounds", o.ExperimentalKeccakRounds))
+	}
+	tr.logger.LogAttrs(ctx, slog.LevelDebug, "tuak stage",
+		append(stage, slog.Group("state", attrs...))...,
+	)
+}
+
+// traceFields decodes a stage buffer: the TS 35.231 IN layout for ".in"
+// labels and the output windows of the function for ".out" labels.
+func traceFields(label string, state []byte, o Options) []stateField {
+	fn, phase, _ := strings.Cut(label, ".")
+	if phase == "in" {
+		st, err := DecodeState(state)
+		if err != nil {
+			return nil
+		}
+		return st.fields()
+	}
+
+	f, ok := functionByName(fn)
+	if !ok {
+		return nil
+	}
+	inst := Instance{Function: f, MACLength: o.MACLength, RESLength: o.RESLength, CKLength: o.CKLength, IKLength: o.IKLength}
+	class := classDerived
+	switch f {
+	case FunctionTOPc:
+		class = classKey
+	case FunctionF1, FunctionF1Star:
+		class = classPublic
+	}
+	var fields []stateField
+	for _, w := range inst.Outputs() {
+		fields = append(fields, stateField{w.Name, pullData(state, w.Offset, w.Length), class})
+	}
+	return fields
+}
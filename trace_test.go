@@ -0,0 +1,100 @@
+package tuak
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"tuak/testvectors"
+)
+
+type traceRecord struct {
+	Stage string            `json:"stage"`
+	State map[string]string `json:"state"`
+}
+
+func runTraced(t *testing.T, v testvectors.TUAKVector, r Redaction) map[string]traceRecord {
+	t.Helper()
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tk, err := NewWithTOPc(decodeHex(t, v.K), decodeHex(t, v.Topc), decodeHex(t, v.Rand),
+		decodeHex(t, v.SQN), decodeHex(t, v.AMF), append(optionsFromVector(v), WithTracer(l, r))...)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	if _, err := tk.F1(); err != nil {
+		t.Fatalf("F1: %v", err)
+	}
+	if _, _, _, _, err := tk.F2345(); err != nil {
+		t.Fatalf("F2345: %v", err)
+	}
+	records := make(map[string]traceRecord)
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec traceRecord
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("decode log: %v", err)
+		}
+		records[rec.Stage] = rec
+	}
+	return records
+}
+
+func TestTracerDecodesStages(t *testing.T) {
+	v := loadTUAKVector(t, 6)
+	recs := runTraced(t, v, RedactKeys)
+	in := recs["f1.in"].State
+	if in["RAND"] != v.Rand || in["SQN"] != v.SQN || in["AMF"] != v.AMF || in["ALGONAME"] != "TUAK1.0" {
+		t.Fatalf("f1.in fields = %v", in)
+	}
+	inst, _ := Instance{Function: FunctionF1, MACLength: v.MAClength, KLength: v.Klength}.Byte()
+	if in["INSTANCE"] != hex.EncodeToString([]byte{inst}) {
+		t.Fatalf("INSTANCE = %s, want %02x", in["INSTANCE"], inst)
+	}
+	if in["K"] != "redacted" || in["TOPc"] != "redacted" {
+		t.Fatalf("keys not masked by default: %v", in)
+	}
+	if _, ok := recs["f1.out.1"]; !ok {
+		t.Fatalf("missing intermediate iteration record; stages: %v", recs)
+	}
+	if got := recs["f1.out.2"].State["MAC-A"]; got != v.F1 {
+		t.Fatalf("MAC-A = %s, want %s", got, v.F1)
+	}
+	if got := recs["f2345.out.2"].State["CK"]; got != v.F3 {
+		t.Fatalf("CK = %s, want %s", got, v.F3)
+	}
+}
+
+func TestTracerRedaction(t *testing.T) {
+	v := loadTUAKVector(t, 1)
+	recs := runTraced(t, v, RedactSecrets)
+	if out := recs["f2345.out"].State; out["CK"] != "redacted" || out["RES"] != "redacted" {
+		t.Fatalf("RedactSecrets left outputs visible: %v", out)
+	}
+	if got := recs["f1.out"].State["MAC-A"]; got != v.F1 {
+		t.Fatalf("MAC-A = %s, want %s", got, v.F1)
+	}
+
+	recs = runTraced(t, v, RedactNone)
+	in := recs["f2345.in"].State
+	if in["K"] != v.K || in["TOPc"] != v.Topc || len(in["raw"]) != 2*inSize {
+		t.Fatalf("RedactNone fields = %v", in)
+	}
+}
+
+func loadTUAKVector(t *testing.T, id int) testvectors.TUAKVector {
+	t.Helper()
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		if v.ID == id {
+			return v
+		}
+	}
+	t.Fatalf("test set %d not found", id)
+	return testvectors.TUAKVector{}
+}
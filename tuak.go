@@ -34,6 +34,7 @@ type TUAK struct {
 }
 
 // New creates a TUAK context using TOP (TOPc will be derived as needed).
+// With WithKeyProvider, k (and top when the provider supplies TOPc) may be nil.
 func New(k, top, rand, sqn, amf []byte, opts ...Option) (*TUAK, error) {
 	o := applyOptions(k, opts)
 	return &TUAK{
@@ -59,9 +60,26 @@ func NewWithTOPc(k, topc, rand, sqn, amf []byte, opts ...Option) (*TUAK, error)
 	}, nil
 }
 
-// ComputeTOPc derives TOPc from K and TOP.
+// ComputeTOPc derives TOPc from K and TOP. If k is nil and a KeyProvider
+// is configured, K is taken from the provider.
 func ComputeTOPc(k, top []byte, opts ...Option) ([]byte, error) {
+	if err := checkSelfTest(); err != nil {
+		return nil, err
+	}
 	o := applyOptions(k, opts)
+	if k == nil && o.KeyProvider != nil {
+		var topc []byte
+		err := o.KeyProvider.UseKeys(func(k, _ []byte) error {
+			var err error
+			topc, err = computeTOPc(k, top, o)
+			return err
+		})
+		return topc, err
+	}
+	return computeTOPc(k, top, o)
+}
+
+func computeTOPc(k, top []byte, o Options) ([]byte, error) {
 	kLenBits, err := resolveKLength(k, o)
 	if err != nil {
 		return nil, err
@@ -70,152 +88,136 @@ func ComputeTOPc(k, top []byte, opts ...Option) ([]byte, error) {
 		return nil, err
 	}
 
-	inst, err := instanceForTOPc(kLenBits)
+	out, err := compute(Instance{Function: FunctionTOPc, KLength: kLenBits}, Inputs{TOP: top, K: k}, o)
 	if err != nil {
 		return nil, err
 	}
-
-	state := newState()
-	pushData(state, offsetTOP, top)
-	state[offsetInst] = inst
-	pushData(state, offsetAlgo, algoName)
-	pushData(state, offsetK, k)
-
-	callDebug(o.DebugHook, "topc.in", state)
-	out, err := permute(state, o.KeccakIterations, o.DebugHook, "topc")
-	if err != nil {
-		return nil, err
-	}
-	return pullData(out, offsetTOP, 32), nil
+	return out[0], nil
 }
 
 // F1 computes MAC-A.
 func (t *TUAK) F1() ([]byte, error) {
-	topc, err := t.ensureTOPc()
-	if err != nil {
-		return nil, err
-	}
-	if err := validateF1Inputs(t); err != nil {
-		return nil, err
-	}
-
-	inst, err := instanceForF1(t.opts.MACLength, len(t.k)*8, false)
-	if err != nil {
-		return nil, err
-	}
-
-	state := newState()
-	pushData(state, offsetTOP, topc)
-	state[offsetInst] = inst
-	pushData(state, offsetAlgo, algoName)
-	pushData(state, offsetRAND, t.rand)
-	pushData(state, offsetAMF, t.amf)
-	pushData(state, offsetSQN, t.sqn)
-	pushData(state, offsetK, t.k)
-
-	callDebug(t.opts.DebugHook, "f1.in", state)
-	out, err := permute(state, t.opts.KeccakIterations, t.opts.DebugHook, "f1")
-	if err != nil {
-		return nil, err
-	}
-	return pullData(out, offsetTOP, t.opts.MACLength/8), nil
+	var mac []byte
+	err := t.withKeys(func(k, topc []byte) error {
+		var err error
+		mac, err = t.f1(k, topc, false)
+		return err
+	})
+	return mac, err
 }
 
 // F1Star computes MAC-S.
 func (t *TUAK) F1Star() ([]byte, error) {
-	topc, err := t.ensureTOPc()
-	if err != nil {
-		return nil, err
-	}
-	if err := validateF1Inputs(t); err != nil {
-		return nil, err
-	}
+	var mac []byte
+	err := t.withKeys(func(k, topc []byte) error {
+		var err error
+		mac, err = t.f1(k, topc, true)
+		return err
+	})
+	return mac, err
+}
 
-	inst, err := instanceForF1(t.opts.MACLength, len(t.k)*8, true)
-	if err != nil {
+func (t *TUAK) f1(k, topc []byte, star bool) ([]byte, error) {
+	if err := validateF1Inputs(t, k); err != nil {
 		return nil, err
 	}
 
-	state := newState()
-	pushData(state, offsetTOP, topc)
-	state[offsetInst] = inst
-	pushData(state, offsetAlgo, algoName)
-	pushData(state, offsetRAND, t.rand)
-	pushData(state, offsetAMF, t.amf)
-	pushData(state, offsetSQN, t.sqn)
-	pushData(state, offsetK, t.k)
-
-	callDebug(t.opts.DebugHook, "f1star.in", state)
-	out, err := permute(state, t.opts.KeccakIterations, t.opts.DebugHook, "f1star")
+	fn := FunctionF1
+	if star {
+		fn = FunctionF1Star
+	}
+	inst := Instance{Function: fn, MACLength: t.opts.MACLength, KLength: len(k) * 8}
+	out, err := compute(inst, Inputs{TOP: topc, RAND: t.rand, AMF: t.amf, SQN: t.sqn, K: k}, t.opts)
 	if err != nil {
 		return nil, err
 	}
-	return pullData(out, offsetTOP, t.opts.MACLength/8), nil
+	return out[0], nil
 }
 
 // F2345 computes RES, CK, IK and AK.
 func (t *TUAK) F2345() (res, ck, ik, ak []byte, err error) {
-	topc, err := t.ensureTOPc()
+	err = t.withKeys(func(k, topc []byte) error {
+		var err error
+		res, ck, ik, ak, err = t.f2345(k, topc)
+		return err
+	})
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
-	if err := validateF2345Inputs(t); err != nil {
-		return nil, nil, nil, nil, err
-	}
+	return res, ck, ik, ak, nil
+}
 
-	inst, err := instanceForF2345(t.opts.RESLength, t.opts.CKLength, t.opts.IKLength, len(t.k)*8)
-	if err != nil {
+func (t *TUAK) f2345(k, topc []byte) (res, ck, ik, ak []byte, err error) {
+	if err := validateF2345Inputs(t, k); err != nil {
 		return nil, nil, nil, nil, err
 	}
 
-	state := newState()
-	pushData(state, offsetTOP, topc)
-	state[offsetInst] = inst
-	pushData(state, offsetAlgo, algoName)
-	pushData(state, offsetRAND, t.rand)
-	pushData(state, offsetK, t.k)
-
-	callDebug(t.opts.DebugHook, "f2345.in", state)
-	out, err := permute(state, t.opts.KeccakIterations, t.opts.DebugHook, "f2345")
+	inst := Instance{
+		Function:  FunctionF2345,
+		RESLength: t.opts.RESLength,
+		CKLength:  t.opts.CKLength,
+		IKLength:  t.opts.IKLength,
+		KLength:   len(k) * 8,
+	}
+	out, err := compute(inst, Inputs{TOP: topc, RAND: t.rand, K: k}, t.opts)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
-
-	res = pullData(out, offsetTOP, t.opts.RESLength/8)
-	ck = pullData(out, 32, t.opts.CKLength/8)
-	ik = pullData(out, 64, t.opts.IKLength/8)
-	ak = pullData(out, 96, 6)
-	return res, ck, ik, ak, nil
+	return out[0], out[1], out[2], out[3], nil
 }
 
 // F5Star computes AK*.
 func (t *TUAK) F5Star() ([]byte, error) {
-	topc, err := t.ensureTOPc()
-	if err != nil {
-		return nil, err
-	}
-	if err := validateF5StarInputs(t); err != nil {
+	var akStar []byte
+	err := t.withKeys(func(k, topc []byte) error {
+		var err error
+		akStar, err = t.f5Star(k, topc)
+		return err
+	})
+	return akStar, err
+}
+
+func (t *TUAK) f5Star(k, topc []byte) ([]byte, error) {
+	if err := validateF5StarInputs(t, k); err != nil {
 		return nil, err
 	}
 
-	inst, err := instanceForF5Star(len(t.k) * 8)
+	inst := Instance{Function: FunctionF5Star, KLength: len(k) * 8}
+	out, err := compute(inst, Inputs{TOP: topc, RAND: t.rand, K: k}, t.opts)
 	if err != nil {
 		return nil, err
 	}
+	return out[0], nil
+}
 
-	state := newState()
-	pushData(state, offsetTOP, topc)
-	state[offsetInst] = inst
-	pushData(state, offsetAlgo, algoName)
-	pushData(state, offsetRAND, t.rand)
-	pushData(state, offsetK, t.k)
-
-	callDebug(t.opts.DebugHook, "f5star.in", state)
-	out, err := permute(state, t.opts.KeccakIterations, t.opts.DebugHook, "f5star")
-	if err != nil {
-		return nil, err
+// withKeys runs fn with K and TOPc, taken from the context or, when a
+// KeyProvider is configured, from the provider for the duration of fn. It
+// fails with the latched self-test error, if any.
+func (t *TUAK) withKeys(fn func(k, topc []byte) error) error {
+	if err := checkSelfTest(); err != nil {
+		return err
+	}
+	if t.opts.KeyProvider == nil {
+		topc, err := t.ensureTOPc()
+		if err != nil {
+			return err
+		}
+		return fn(t.k, topc)
 	}
-	return pullData(out, 96, 6), nil
+	return t.opts.KeyProvider.UseKeys(func(k, topc []byte) error {
+		if topc != nil {
+			return fn(k, topc)
+		}
+		if t.top == nil {
+			return fmt.Errorf("tuak: missing topc and top")
+		}
+		topc, err := computeTOPc(k, t.top, t.topcOptions())
+		if err != nil {
+			return err
+		}
+		defer clear(topc)
+		return fn(k, topc)
+	})
 }
 
 func (t *TUAK) ensureTOPc() ([]byte, error) {
@@ -225,7 +227,7 @@ func (t *TUAK) ensureTOPc() ([]byte, error) {
 	if t.top == nil {
 		return nil, fmt.Errorf("tuak: missing topc and top")
 	}
-	topc, err := ComputeTOPc(t.k, t.top, WithKLength(t.opts.KLength), WithKeccakIterations(t.opts.KeccakIterations))
+	topc, err := computeTOPc(t.k, t.top, t.topcOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -233,6 +235,17 @@ func (t *TUAK) ensureTOPc() ([]byte, error) {
 	return topc, nil
 }
 
+// topcOptions returns the options TOPc is derived under: the context's
+// K length, iterations and rounds, but none of its hooks or tracer, which
+// observe only the functions called on the context.
+func (t *TUAK) topcOptions() Options {
+	return Options{
+		KLength:                  t.opts.KLength,
+		KeccakIterations:         t.opts.KeccakIterations,
+		ExperimentalKeccakRounds: t.opts.ExperimentalKeccakRounds,
+	}
+}
+
 func newState() []byte {
 	state := make([]byte, inSize)
 	state[paddingByte96] = 0x1F
@@ -240,18 +253,34 @@ func newState() []byte {
 	return state
 }
 
-func permute(state []byte, iterations int, hook DebugHook, label string) ([]byte, error) {
+func permute(state []byte, o Options, label string) ([]byte, error) {
+	iterations := o.KeccakIterations
 	if iterations <= 0 {
 		iterations = 1
 	}
+	rounds := 24
+	if o.ExperimentalKeccakRounds != 0 {
+		rounds = o.ExperimentalKeccakRounds
+	}
 	out := state
 	var err error
 	for i := 0; i < iterations; i++ {
-		out, err = keccak.PermuteF1600(out)
+		outLabel := debugLabel(label, i, iterations)
+		switch {
+		case o.RoundHook != nil:
+			h := o.RoundHook
+			out, err = keccak.PermuteP1600WithHook(out, rounds, func(round int, step keccak.Step, state []byte) {
+				h(outLabel, round, step, state)
+			})
+		case rounds != 24:
+			out, err = keccak.PermuteP(out, rounds)
+		default:
+			out, err = keccak.PermuteF1600(out)
+		}
 		if err != nil {
 			return nil, err
 		}
-		callDebug(hook, debugLabel(label, i, iterations), out)
+		callDebug(o, outLabel, out)
 	}
 	return out, nil
 }
@@ -270,7 +299,11 @@ func pullData(state []byte, offset, length int) []byte {
 	return out
 }
 
-func callDebug(h DebugHook, label string, data []byte) {
+func callDebug(o Options, label string, data []byte) {
+	if o.Tracer != nil {
+		o.Tracer.trace(label, data, o)
+	}
+	h := o.DebugHook
 	if h == nil {
 		return
 	}
@@ -306,14 +339,14 @@ func requireLen(name string, b []byte, want int) error {
 	return nil
 }
 
-func validateF1Inputs(t *TUAK) error {
+func validateF1Inputs(t *TUAK, k []byte) error {
 	if t.opts.MACLength == 0 {
 		return fmt.Errorf("tuak: MAC length must be set")
 	}
 	if t.opts.MACLength != 64 && t.opts.MACLength != 128 && t.opts.MACLength != 256 {
 		return fmt.Errorf("tuak: invalid MAC length %d bits", t.opts.MACLength)
 	}
-	if _, err := resolveKLength(t.k, t.opts); err != nil {
+	if _, err := resolveKLength(k, t.opts); err != nil {
 		return err
 	}
 	if err := requireLen("rand", t.rand, 16); err != nil {
@@ -328,7 +361,7 @@ func validateF1Inputs(t *TUAK) error {
 	return nil
 }
 
-func validateF2345Inputs(t *TUAK) error {
+func validateF2345Inputs(t *TUAK, k []byte) error {
 	if t.opts.RESLength == 0 || t.opts.CKLength == 0 || t.opts.IKLength == 0 {
 		return fmt.Errorf("tuak: RES/CK/IK lengths must be set")
 	}
@@ -347,7 +380,7 @@ func validateF2345Inputs(t *TUAK) error {
 	default:
 		return fmt.Errorf("tuak: invalid IK length %d bits", t.opts.IKLength)
 	}
-	if _, err := resolveKLength(t.k, t.opts); err != nil {
+	if _, err := resolveKLength(k, t.opts); err != nil {
 		return err
 	}
 	if err := requireLen("rand", t.rand, 16); err != nil {
@@ -356,8 +389,8 @@ func validateF2345Inputs(t *TUAK) error {
 	return nil
 }
 
-func validateF5StarInputs(t *TUAK) error {
-	if _, err := resolveKLength(t.k, t.opts); err != nil {
+func validateF5StarInputs(t *TUAK, k []byte) error {
+	if _, err := resolveKLength(k, t.opts); err != nil {
 		return err
 	}
 	if err := requireLen("rand", t.rand, 16); err != nil {
@@ -366,109 +399,6 @@ func validateF5StarInputs(t *TUAK) error {
 	return nil
 }
 
-func instanceForTOPc(kLenBits int) (byte, error) {
-	if kLenBits != 128 && kLenBits != 256 {
-		return 0, fmt.Errorf("tuak: invalid K length %d bits", kLenBits)
-	}
-	var inst byte
-	if kLenBits == 256 {
-		inst = setInstanceBit(inst, 7, true)
-	}
-	return inst, nil
-}
-
-func instanceForF1(macLenBits, kLenBits int, star bool) (byte, error) {
-	if kLenBits != 128 && kLenBits != 256 {
-		return 0, fmt.Errorf("tuak: invalid K length %d bits", kLenBits)
-	}
-	var inst byte
-	inst = setInstanceBit(inst, 0, star)
-	inst = setInstanceBit(inst, 1, false)
-	switch macLenBits {
-	case 64:
-		inst = setInstanceBit(inst, 2, false)
-		inst = setInstanceBit(inst, 3, false)
-		inst = setInstanceBit(inst, 4, true)
-	case 128:
-		inst = setInstanceBit(inst, 2, false)
-		inst = setInstanceBit(inst, 3, true)
-		inst = setInstanceBit(inst, 4, false)
-	case 256:
-		inst = setInstanceBit(inst, 2, true)
-		inst = setInstanceBit(inst, 3, false)
-		inst = setInstanceBit(inst, 4, false)
-	default:
-		return 0, fmt.Errorf("tuak: invalid MAC length %d bits", macLenBits)
-	}
-	inst = setInstanceBit(inst, 5, false)
-	inst = setInstanceBit(inst, 6, false)
-	inst = setInstanceBit(inst, 7, kLenBits == 256)
-	return inst, nil
-}
-
-func instanceForF2345(resLenBits, ckLenBits, ikLenBits, kLenBits int) (byte, error) {
-	if kLenBits != 128 && kLenBits != 256 {
-		return 0, fmt.Errorf("tuak: invalid K length %d bits", kLenBits)
-	}
-	var inst byte
-	inst = setInstanceBit(inst, 0, false)
-	inst = setInstanceBit(inst, 1, true)
-	switch resLenBits {
-	case 32:
-		inst = setInstanceBit(inst, 2, false)
-		inst = setInstanceBit(inst, 3, false)
-		inst = setInstanceBit(inst, 4, false)
-	case 64:
-		inst = setInstanceBit(inst, 2, false)
-		inst = setInstanceBit(inst, 3, false)
-		inst = setInstanceBit(inst, 4, true)
-	case 128:
-		inst = setInstanceBit(inst, 2, false)
-		inst = setInstanceBit(inst, 3, true)
-		inst = setInstanceBit(inst, 4, false)
-	case 256:
-		inst = setInstanceBit(inst, 2, true)
-		inst = setInstanceBit(inst, 3, false)
-		inst = setInstanceBit(inst, 4, false)
-	default:
-		return 0, fmt.Errorf("tuak: invalid RES length %d bits", resLenBits)
-	}
-	switch ckLenBits {
-	case 128:
-		inst = setInstanceBit(inst, 5, false)
-	case 256:
-		inst = setInstanceBit(inst, 5, true)
-	default:
-		return 0, fmt.Errorf("tuak: invalid CK length %d bits", ckLenBits)
-	}
-	switch ikLenBits {
-	case 128:
-		inst = setInstanceBit(inst, 6, false)
-	case 256:
-		inst = setInstanceBit(inst, 6, true)
-	default:
-		return 0, fmt.Errorf("tuak: invalid IK length %d bits", ikLenBits)
-	}
-	inst = setInstanceBit(inst, 7, kLenBits == 256)
-	return inst, nil
-}
-
-func instanceForF5Star(kLenBits int) (byte, error) {
-	if kLenBits != 128 && kLenBits != 256 {
-		return 0, fmt.Errorf("tuak: invalid K length %d bits", kLenBits)
-	}
-	var inst byte
-	inst = setInstanceBit(inst, 0, true)
-	inst = setInstanceBit(inst, 1, true)
-	inst = setInstanceBit(inst, 2, false)
-	inst = setInstanceBit(inst, 3, false)
-	inst = setInstanceBit(inst, 4, false)
-	inst = setInstanceBit(inst, 5, false)
-	inst = setInstanceBit(inst, 6, false)
-	inst = setInstanceBit(inst, 7, kLenBits == 256)
-	return inst, nil
-}
-
 func setInstanceBit(inst byte, index int, value bool) byte {
 	if !value {
 		return inst
@@ -0,0 +1,93 @@
+package tuak
+
+import (
+	"bytes"
+	"testing"
+
+	"tuak/internal/tuakref"
+)
+
+// fuzzInputLen covers K (up to 32 bytes), TOP, RAND, SQN and AMF.
+const fuzzInputLen = 32 + 32 + 16 + 6 + 2
+
+// fuzzParams maps a selector byte onto K, MAC, RES, CK and IK lengths and
+// the Keccak iteration count, so every combination is reachable.
+func fuzzParams(sel uint8) (kLen, macLen, resLen, ckLen, ikLen, iterations int) {
+	kLen = []int{128, 256}[sel&1]
+	macLen = []int{64, 128, 256}[int(sel>>1&3)%3]
+	resLen = []int{32, 64, 128, 256}[sel>>3&3]
+	ckLen = []int{128, 256}[sel>>5&1]
+	ikLen = []int{128, 256}[sel>>6&1]
+	iterations = 1 + int(sel>>7)
+	return
+}
+
+func FuzzReference(f *testing.F) {
+	seed := make([]byte, fuzzInputLen)
+	for i := range seed {
+		seed[i] = byte(i * 7)
+	}
+	for sel := 0; sel < 256; sel++ {
+		if sel>>1&3 == 3 {
+			continue
+		}
+		f.Add(seed, uint8(sel))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, sel uint8) {
+		in := make([]byte, fuzzInputLen)
+		copy(in, data)
+		kLen, macLen, resLen, ckLen, ikLen, n := fuzzParams(sel)
+		k := in[:kLen/8]
+		top, rand, sqn, amf := in[32:64], in[64:80], in[80:86], in[86:88]
+		opts := []Option{
+			WithKLength(kLen),
+			WithMACLength(macLen),
+			WithRESLength(resLen),
+			WithCKLength(ckLen),
+			WithIKLength(ikLen),
+			WithKeccakIterations(n),
+		}
+
+		topc, err := ComputeTOPc(k, top, opts...)
+		if err != nil {
+			t.Fatalf("ComputeTOPc: %v", err)
+		}
+		if want := tuakref.TOPc(k, top, n); !bytes.Equal(topc, want) {
+			t.Fatalf("TOPc = %x, reference %x", topc, want)
+		}
+
+		tk, err := New(k, top, rand, sqn, amf, opts...)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		mac, err := tk.F1()
+		if err != nil {
+			t.Fatalf("F1: %v", err)
+		}
+		if want := tuakref.F1(topc, k, rand, sqn, amf, macLen, n, false); !bytes.Equal(mac, want) {
+			t.Fatalf("f1 = %x, reference %x", mac, want)
+		}
+		mac, err = tk.F1Star()
+		if err != nil {
+			t.Fatalf("F1*: %v", err)
+		}
+		if want := tuakref.F1(topc, k, rand, sqn, amf, macLen, n, true); !bytes.Equal(mac, want) {
+			t.Fatalf("f1* = %x, reference %x", mac, want)
+		}
+		res, ck, ik, ak, err := tk.F2345()
+		if err != nil {
+			t.Fatalf("F2345: %v", err)
+		}
+		wantRES, wantCK, wantIK, wantAK := tuakref.F2345(topc, k, rand, resLen, ckLen, ikLen, n)
+		if !bytes.Equal(res, wantRES) || !bytes.Equal(ck, wantCK) || !bytes.Equal(ik, wantIK) || !bytes.Equal(ak, wantAK) {
+			t.Fatalf("f2-f5 = %x %x %x %x, reference %x %x %x %x", res, ck, ik, ak, wantRES, wantCK, wantIK, wantAK)
+		}
+		akStar, err := tk.F5Star()
+		if err != nil {
+			t.Fatalf("F5*: %v", err)
+		}
+		if want := tuakref.F5Star(topc, k, rand, n); !bytes.Equal(akStar, want) {
+			t.Fatalf("f5* = %x, reference %x", akStar, want)
+		}
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/hex"
 	"errors"
+	"strings"
 	"testing"
 
+	"tuak/keccak"
 	"tuak/testvectors"
 )
 
@@ -129,6 +131,174 @@ func TestF5StarVectors(t *testing.T) {
 	}
 }
 
+func TestKeyProviderVectors(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	for _, v := range data.Tests {
+		p := StaticKeys{K: decodeHex(t, v.K)}
+		opts := append(optionsFromVector(v), WithKeyProvider(p))
+
+		topc, err := ComputeTOPc(nil, decodeHex(t, v.Top), opts...)
+		if err != nil {
+			t.Fatalf("ComputeTOPc: %v", err)
+		}
+		if !bytes.Equal(topc, decodeHex(t, v.Topc)) {
+			t.Fatalf("vector %d TOPc mismatch", v.ID)
+		}
+
+		tuak, err := New(nil, decodeHex(t, v.Top), decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF), opts...)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		got, err := tuak.F1Star()
+		if err != nil {
+			t.Fatalf("F1*: %v", err)
+		}
+		if !bytes.Equal(got, decodeHex(t, v.F1Star)) {
+			t.Fatalf("vector %d f1* mismatch", v.ID)
+		}
+		got, err = tuak.F5Star()
+		if err != nil {
+			t.Fatalf("F5*: %v", err)
+		}
+		if !bytes.Equal(got, decodeHex(t, v.F5Star)) {
+			t.Fatalf("vector %d f5* mismatch", v.ID)
+		}
+	}
+}
+
+func TestRoundHook(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	v := data.Tests[len(data.Tests)-1]
+	if v.KeccakIterations < 2 {
+		t.Fatalf("vector %d has %d Keccak iterations, want several", v.ID, v.KeccakIterations)
+	}
+	outs := map[string][]byte{}
+	last := map[string][]byte{}
+	calls := map[string]int{}
+	opts := append(optionsFromVector(v),
+		WithDebugHook(func(label string, data []byte) { outs[label] = data }),
+		WithRoundHook(func(label string, round int, step keccak.Step, state []byte) {
+			n := calls[label]
+			if round != n/5 || step != keccak.Step(n%5) {
+				t.Fatalf("%s call %d = round %d %v", label, n, round, step)
+			}
+			calls[label]++
+			last[label] = state
+		}))
+	tk, err := NewWithTOPc(decodeHex(t, v.K), decodeHex(t, v.Topc), decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF), opts...)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	res, _, _, _, err := tk.F2345()
+	if err != nil {
+		t.Fatalf("F2345: %v", err)
+	}
+	if !bytes.Equal(res, decodeHex(t, v.F2)) {
+		t.Fatalf("vector %d f2 mismatch with round hook", v.ID)
+	}
+	if len(calls) != v.KeccakIterations {
+		t.Fatalf("round hook labels = %v, want %d permutations", calls, v.KeccakIterations)
+	}
+	for label, n := range calls {
+		if n != 120 {
+			t.Fatalf("%s: %d callbacks, want 120", label, n)
+		}
+		if !bytes.Equal(last[label], outs[label]) {
+			t.Fatalf("%s: last iota state differs from debug hook output", label)
+		}
+	}
+}
+
+func TestExperimentalKeccakRounds(t *testing.T) {
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	v := data.Tests[0]
+	newTUAK := func(opts ...Option) *TUAK {
+		t.Helper()
+		tk, err := NewWithTOPc(decodeHex(t, v.K), decodeHex(t, v.Topc), decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF),
+			append(optionsFromVector(v), opts...)...)
+		if err != nil {
+			t.Fatalf("NewWithTOPc: %v", err)
+		}
+		return tk
+	}
+
+	full, err := newTUAK(WithExperimentalKeccakRounds(24)).F1()
+	if err != nil || !bytes.Equal(full, decodeHex(t, v.F1)) {
+		t.Fatalf("F1 with 24 rounds = %x, %v; want conformant %s", full, err, v.F1)
+	}
+	for _, rounds := range []int{1, 4, 12} {
+		var in []byte
+		var calls int
+		tk := newTUAK(WithExperimentalKeccakRounds(rounds), WithDebugHook(func(label string, data []byte) {
+			if label == "f1.in" {
+				in = data
+			}
+		}))
+		got, err := tk.F1()
+		if err != nil {
+			t.Fatalf("F1 with %d rounds: %v", rounds, err)
+		}
+		if in, err = keccak.PermuteP(in, rounds); err != nil {
+			t.Fatalf("PermuteP(%d): %v", rounds, err)
+		}
+		if want := pullData(in, offsetTOP, v.MAClength/8); !bytes.Equal(got, want) {
+			t.Fatalf("F1 with %d rounds = %x, want %x", rounds, got, want)
+		}
+		if bytes.Equal(got, full) {
+			t.Fatalf("F1 with %d rounds equals the conformant MAC", rounds)
+		}
+		hooked := newTUAK(WithExperimentalKeccakRounds(rounds), WithRoundHook(func(string, int, keccak.Step, []byte) { calls++ }))
+		if again, err := hooked.F1(); err != nil || !bytes.Equal(again, got) || calls != 5*rounds {
+			t.Fatalf("F1 with %d rounds and round hook = %x, %v after %d steps", rounds, again, err, calls)
+		}
+
+		// New derives TOPc under the same reduced rounds as f1.
+		opts := append(optionsFromVector(v), WithExperimentalKeccakRounds(rounds))
+		topc, err := ComputeTOPc(decodeHex(t, v.K), decodeHex(t, v.Top), opts...)
+		if err != nil {
+			t.Fatalf("ComputeTOPc with %d rounds: %v", rounds, err)
+		}
+		want, err := NewWithTOPc(decodeHex(t, v.K), topc, decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF), opts...)
+		if err != nil {
+			t.Fatalf("NewWithTOPc: %v", err)
+		}
+		wantMAC, err := want.F1()
+		if err != nil {
+			t.Fatalf("F1 with %d rounds: %v", rounds, err)
+		}
+		// The implicit TOPc derivation is not reported to the hooks.
+		calls = 0
+		var labels []string
+		opts = append(opts,
+			WithRoundHook(func(string, int, keccak.Step, []byte) { calls++ }),
+			WithDebugHook(func(label string, _ []byte) { labels = append(labels, label) }))
+		fromTOP, err := New(decodeHex(t, v.K), decodeHex(t, v.Top), decodeHex(t, v.Rand), decodeHex(t, v.SQN), decodeHex(t, v.AMF), opts...)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if mac, err := fromTOP.F1(); err != nil || !bytes.Equal(mac, wantMAC) || calls != 5*rounds {
+			t.Fatalf("F1 from TOP with %d rounds = %x, %v after %d steps; want %x", rounds, mac, err, calls, wantMAC)
+		}
+		for _, label := range labels {
+			if strings.HasPrefix(label, "topc.") {
+				t.Fatalf("debug hook saw %q while deriving TOPc", label)
+			}
+		}
+	}
+	if _, err := newTUAK(WithExperimentalKeccakRounds(25)).F1(); err == nil {
+		t.Fatalf("F1 accepted 25 rounds")
+	}
+}
+
 func newTUAKFromVector(t *testing.T, v testvectors.TUAKVector) (*TUAK, error) {
 	k := decodeHex(t, v.K)
 	topc := decodeHex(t, v.Topc)
@@ -0,0 +1,64 @@
+package udm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls generate-auth-data on a UDM.
+type Client struct {
+	// BaseURL is the UDM API root, e.g. "https://udm.example.org".
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// Error reports the ProblemDetails returned by the UDM.
+func (p *ProblemDetails) Error() string {
+	if p.Cause != "" {
+		return fmt.Sprintf("udm: %d %s: %s", p.Status, p.Cause, p.Detail)
+	}
+	return fmt.Sprintf("udm: %d: %s", p.Status, p.Detail)
+}
+
+// GenerateAuthData requests an authentication vector for supiOrSuci.
+// Non-2xx responses are returned as *ProblemDetails.
+func (c *Client) GenerateAuthData(ctx context.Context, supiOrSuci string, req *AuthenticationInfoRequest) (*AuthenticationInfoResult, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	u := strings.TrimSuffix(c.BaseURL, "/") + BasePath + "/" + url.PathEscape(supiOrSuci) + "/security-information/generate-auth-data"
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		p := &ProblemDetails{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(p); err != nil {
+			p.Detail = resp.Status
+		}
+		return nil, p
+	}
+	var out AuthenticationInfoResult
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("udm: decode response: %w", err)
+	}
+	return &out, nil
+}
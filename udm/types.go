@@ -0,0 +1,56 @@
+package udm
+
+// Authentication types (TS 29.503 AuthType).
+const (
+	AuthType5GAKA       = "5G_AKA"
+	AuthTypeEAPAKAPrime = "EAP_AKA_PRIME"
+)
+
+// Authentication vector types (TS 29.503 AvType).
+const (
+	AvType5GHEAKA     = "5G_HE_AKA"
+	AvTypeEAPAKAPrime = "EAP_AKA_PRIME"
+)
+
+// AuthenticationInfoRequest is the generate-auth-data request body.
+type AuthenticationInfoRequest struct {
+	SupportedFeatures     string                 `json:"supportedFeatures,omitempty"`
+	ServingNetworkName    string                 `json:"servingNetworkName"`
+	ResynchronizationInfo *ResynchronizationInfo `json:"resynchronizationInfo,omitempty"`
+	AusfInstanceID        string                 `json:"ausfInstanceId"`
+}
+
+// ResynchronizationInfo carries RAND and AUTS for SQN resynchronisation.
+type ResynchronizationInfo struct {
+	Rand string `json:"rand"`
+	Auts string `json:"auts"`
+}
+
+// AuthenticationInfoResult is the generate-auth-data response body.
+type AuthenticationInfoResult struct {
+	AuthType             string                `json:"authType"`
+	SupportedFeatures    string                `json:"supportedFeatures,omitempty"`
+	AuthenticationVector *AuthenticationVector `json:"authenticationVector,omitempty"`
+	Supi                 string                `json:"supi,omitempty"`
+}
+
+// AuthenticationVector is either an Av5GHeAka or an AvEapAkaPrime,
+// distinguished by AvType. All binary fields are hex encoded.
+type AuthenticationVector struct {
+	AvType   string `json:"avType"`
+	Rand     string `json:"rand"`
+	Autn     string `json:"autn"`
+	XresStar string `json:"xresStar,omitempty"`
+	Kausf    string `json:"kausf,omitempty"`
+	Xres     string `json:"xres,omitempty"`
+	CkPrime  string `json:"ckPrime,omitempty"`
+	IkPrime  string `json:"ikPrime,omitempty"`
+}
+
+// ProblemDetails is the SBI error body (TS 29.571).
+type ProblemDetails struct {
+	Title  string `json:"title,omitempty"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Cause  string `json:"cause,omitempty"`
+}
@@ -0,0 +1,250 @@
+// Package udm implements the generate-auth-data operation of the
+// Nudm_UEAuthentication service (TS 29.503) on top of TUAK.
+package udm
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"tuak"
+	"tuak/av"
+	"tuak/store"
+	"tuak/suci"
+)
+
+// BasePath is the API root of Nudm_UEAuthentication.
+const BasePath = "/nudm-ueau/v1"
+
+// Handler serves generate-auth-data requests.
+type Handler struct {
+	subs     store.SubscriberStore
+	gen      *av.Generator
+	authType string
+	keys     KeyProviderFunc
+	suciKeys *suci.KeyStore
+	mux      *http.ServeMux
+}
+
+// KeyProviderFunc returns the key provider for a subscriber whose K and
+// TOPc are stored wrapped (store.Subscriber.KeyWrap is set).
+type KeyProviderFunc func(sub *store.Subscriber) (tuak.KeyProvider, error)
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithAuthType selects 5G_AKA (default) or EAP_AKA_PRIME vectors.
+func WithAuthType(authType string) Option {
+	return func(h *Handler) {
+		h.authType = authType
+	}
+}
+
+// WithGenerator sets the vector generator (e.g. to control RAND).
+func WithGenerator(g *av.Generator) Option {
+	return func(h *Handler) {
+		h.gen = g
+	}
+}
+
+// WithKeyProvider sets how wrapped subscriber keys are unwrapped.
+func WithKeyProvider(f KeyProviderFunc) Option {
+	return func(h *Handler) {
+		h.keys = f
+	}
+}
+
+// WithSUCIKeys sets the home network private keys used to de-conceal
+// SUCIs. Without it only null-scheme SUCIs are accepted.
+func WithSUCIKeys(ks *suci.KeyStore) Option {
+	return func(h *Handler) {
+		h.suciKeys = ks
+	}
+}
+
+// NewHandler returns a handler serving paths below BasePath. Subscribers
+// are keyed by SUPI, to which SUCIs are de-concealed first; a nil AMF
+// defaults to 8000 and the AMF separation bit is always set.
+func NewHandler(subs store.SubscriberStore, opts ...Option) *Handler {
+	h := &Handler{
+		subs:     subs,
+		gen:      &av.Generator{},
+		authType: AuthType5GAKA,
+		mux:      http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("POST "+BasePath+"/{supiOrSuci}/security-information/generate-auth-data", h.generateAuthData)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) generateAuthData(w http.ResponseWriter, r *http.Request) {
+	supi, err := h.suciKeys.SUPI(r.PathValue("supiOrSuci"))
+	switch {
+	case errors.Is(err, suci.ErrUnsupportedSUCI):
+		writeProblem(w, http.StatusNotImplemented, "", err.Error())
+		return
+	case errors.Is(err, suci.ErrUnknownKey), errors.Is(err, suci.ErrMACFailure):
+		writeProblem(w, http.StatusForbidden, "AUTHENTICATION_REJECTED", err.Error())
+		return
+	case err != nil:
+		writeProblem(w, http.StatusBadRequest, "MANDATORY_IE_INCORRECT", err.Error())
+		return
+	}
+
+	var req AuthenticationInfoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, http.StatusBadRequest, "MANDATORY_IE_INCORRECT", err.Error())
+		return
+	}
+	if req.ServingNetworkName == "" || req.AusfInstanceID == "" {
+		writeProblem(w, http.StatusBadRequest, "MANDATORY_IE_MISSING", "servingNetworkName and ausfInstanceId are required")
+		return
+	}
+	if !strings.HasPrefix(req.ServingNetworkName, "5G:") {
+		writeProblem(w, http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "invalid servingNetworkName")
+		return
+	}
+
+	ctx := r.Context()
+	sub, err := h.subs.Get(ctx, supi)
+	if errors.Is(err, store.ErrNotFound) {
+		writeProblem(w, http.StatusNotFound, "USER_NOT_FOUND", "")
+		return
+	}
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	if req.ResynchronizationInfo != nil {
+		if status, cause, err := h.resync(ctx, supi, sub, req.ResynchronizationInfo); err != nil {
+			writeProblem(w, status, cause, err.Error())
+			return
+		}
+	}
+
+	sqn, err := h.subs.NextSQN(ctx, supi)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	vec, err := h.vector(sub, sqn, req.ServingNetworkName)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, "application/json", &AuthenticationInfoResult{
+		AuthType:             h.authType,
+		AuthenticationVector: vec,
+		Supi:                 supi,
+	})
+}
+
+func (h *Handler) resync(ctx context.Context, supi string, sub *store.Subscriber, info *ResynchronizationInfo) (int, string, error) {
+	rand, err := hex.DecodeString(info.Rand)
+	if err != nil || len(rand) != 16 {
+		return http.StatusBadRequest, "MANDATORY_IE_INCORRECT", fmt.Errorf("udm: invalid rand")
+	}
+	auts, err := hex.DecodeString(info.Auts)
+	if err != nil {
+		return http.StatusBadRequest, "MANDATORY_IE_INCORRECT", fmt.Errorf("udm: invalid auts")
+	}
+	cred, err := h.credentials(sub)
+	if err != nil {
+		return http.StatusInternalServerError, "", err
+	}
+	sqnMS, err := av.Resync(cred, rand, auts)
+	if errors.Is(err, av.ErrMACFailure) {
+		return http.StatusForbidden, "AUTHENTICATION_REJECTED", err
+	}
+	if err != nil {
+		return http.StatusBadRequest, "MANDATORY_IE_INCORRECT", err
+	}
+	if err := h.subs.SetSQN(ctx, supi, sqnMS); err != nil {
+		return http.StatusInternalServerError, "", err
+	}
+	return 0, "", nil
+}
+
+func (h *Handler) vector(sub *store.Subscriber, sqn []byte, snn string) (*AuthenticationVector, error) {
+	cred, err := h.credentials(sub)
+	if err != nil {
+		return nil, err
+	}
+	amf := []byte{0x80, 0x00}
+	if sub.AMF != nil {
+		amf = append([]byte(nil), sub.AMF...)
+		amf[0] |= 0x80
+	}
+
+	switch h.authType {
+	case AuthType5GAKA:
+		he, err := h.gen.HE5G(cred, sqn, amf, snn)
+		if err != nil {
+			return nil, err
+		}
+		return &AuthenticationVector{
+			AvType:   AvType5GHEAKA,
+			Rand:     hex.EncodeToString(he.RAND),
+			Autn:     hex.EncodeToString(he.AUTN),
+			XresStar: hex.EncodeToString(he.XRESStar),
+			Kausf:    hex.EncodeToString(he.KAUSF),
+		}, nil
+	case AuthTypeEAPAKAPrime:
+		ea, err := h.gen.EAPAKAPrime(cred, sqn, amf, snn)
+		if err != nil {
+			return nil, err
+		}
+		return &AuthenticationVector{
+			AvType:  AvTypeEAPAKAPrime,
+			Rand:    hex.EncodeToString(ea.RAND),
+			Autn:    hex.EncodeToString(ea.AUTN),
+			Xres:    hex.EncodeToString(ea.XRES),
+			CkPrime: hex.EncodeToString(ea.CKPrime),
+			IkPrime: hex.EncodeToString(ea.IKPrime),
+		}, nil
+	default:
+		return nil, fmt.Errorf("udm: unsupported auth type %q", h.authType)
+	}
+}
+
+func (h *Handler) credentials(sub *store.Subscriber) (av.Credentials, error) {
+	if sub.KeyWrap == "" {
+		return av.Credentials{K: sub.K, TOPc: sub.TOPc, Options: sub.TUAKOptions()}, nil
+	}
+	if h.keys == nil {
+		return av.Credentials{}, fmt.Errorf("udm: %s: no key provider for wrapped keys", sub.ID)
+	}
+	p, err := h.keys(sub)
+	if err != nil {
+		return av.Credentials{}, err
+	}
+	return av.Credentials{Options: append(sub.TUAKOptions(), tuak.WithKeyProvider(p))}, nil
+}
+
+func writeProblem(w http.ResponseWriter, status int, cause, detail string) {
+	writeJSON(w, status, "application/problem+json", &ProblemDetails{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Cause:  cause,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, contentType string, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,254 @@
+package udm
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tuak"
+	"tuak/av"
+	"tuak/keywrap"
+	"tuak/store"
+	"tuak/suci"
+	"tuak/testvectors"
+)
+
+const testSNN = "5G:mnc001.mcc001.3gppnetwork.org"
+
+func TestGenerateAuthData5GAKA(t *testing.T) {
+	subs := newMemSubscribers(t)
+	client := newTestClient(t, NewHandler(subs))
+
+	res, err := client.GenerateAuthData(context.Background(), "imsi-001010000000001", &AuthenticationInfoRequest{
+		ServingNetworkName: testSNN,
+		AusfInstanceID:     "ausf-1",
+	})
+	if err != nil {
+		t.Fatalf("GenerateAuthData: %v", err)
+	}
+	if res.AuthType != AuthType5GAKA || res.AuthenticationVector.AvType != AvType5GHEAKA {
+		t.Fatalf("authType=%s avType=%s", res.AuthType, res.AuthenticationVector.AvType)
+	}
+	v := res.AuthenticationVector
+	if len(v.Rand) != 32 || len(v.Autn) != 32 || len(v.XresStar) != 32 || len(v.Kausf) != 64 {
+		t.Fatalf("unexpected vector field lengths: %+v", v)
+	}
+	autn := decodeHex(t, v.Autn)
+	if autn[6]&0x80 == 0 {
+		t.Fatalf("AMF separation bit not set")
+	}
+}
+
+func TestGenerateAuthDataEAPAKAPrime(t *testing.T) {
+	subs := newMemSubscribers(t)
+	client := newTestClient(t, NewHandler(subs, WithAuthType(AuthTypeEAPAKAPrime)))
+
+	res, err := client.GenerateAuthData(context.Background(), "imsi-001010000000001", &AuthenticationInfoRequest{
+		ServingNetworkName: testSNN,
+		AusfInstanceID:     "ausf-1",
+	})
+	if err != nil {
+		t.Fatalf("GenerateAuthData: %v", err)
+	}
+	v := res.AuthenticationVector
+	if v.AvType != AvTypeEAPAKAPrime || v.Xres == "" || len(v.CkPrime) != 32 || len(v.IkPrime) != 32 {
+		t.Fatalf("unexpected EAP-AKA' vector: %+v", v)
+	}
+}
+
+func TestGenerateAuthDataResync(t *testing.T) {
+	subs := newMemSubscribers(t)
+	client := newTestClient(t, NewHandler(subs))
+	sub, err := subs.Get(context.Background(), "imsi-001010000000001")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	rand := bytes.Repeat([]byte{0x5a}, 16)
+	sqnMS := decodeHex(t, "000000001000")
+	tk, err := tuak.NewWithTOPc(sub.K, sub.TOPc, rand, sqnMS, make([]byte, 2), sub.TUAKOptions()...)
+	if err != nil {
+		t.Fatalf("NewWithTOPc: %v", err)
+	}
+	akStar, _ := tk.F5Star()
+	macS, _ := tk.F1Star()
+	auts := append(xorBytes(sqnMS, akStar), macS...)
+
+	req := &AuthenticationInfoRequest{
+		ServingNetworkName: testSNN,
+		AusfInstanceID:     "ausf-1",
+		ResynchronizationInfo: &ResynchronizationInfo{
+			Rand: hex.EncodeToString(rand),
+			Auts: hex.EncodeToString(auts),
+		},
+	}
+	if _, err := client.GenerateAuthData(context.Background(), "imsi-001010000000001", req); err != nil {
+		t.Fatalf("GenerateAuthData: %v", err)
+	}
+	sub, err = subs.Get(context.Background(), "imsi-001010000000001")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got, want := sub.SQN, decodeHex(t, "000000001001"); !bytes.Equal(got, want) {
+		t.Fatalf("SQN after resync = %x, want %x", got, want)
+	}
+
+	auts[len(auts)-1] ^= 0xff
+	req.ResynchronizationInfo.Auts = hex.EncodeToString(auts)
+	_, err = client.GenerateAuthData(context.Background(), "imsi-001010000000001", req)
+	var p *ProblemDetails
+	if !errors.As(err, &p) || p.Status != http.StatusForbidden || p.Cause != "AUTHENTICATION_REJECTED" {
+		t.Fatalf("bad AUTS: err = %v, want 403 AUTHENTICATION_REJECTED", err)
+	}
+}
+
+func TestGenerateAuthDataUnknownUser(t *testing.T) {
+	client := newTestClient(t, NewHandler(newMemSubscribers(t)))
+	_, err := client.GenerateAuthData(context.Background(), "imsi-999999999999999", &AuthenticationInfoRequest{
+		ServingNetworkName: testSNN,
+		AusfInstanceID:     "ausf-1",
+	})
+	var p *ProblemDetails
+	if !errors.As(err, &p) || p.Status != http.StatusNotFound || p.Cause != "USER_NOT_FOUND" {
+		t.Fatalf("err = %v, want 404 USER_NOT_FOUND", err)
+	}
+}
+
+func TestGenerateAuthDataSUCI(t *testing.T) {
+	data, err := testvectors.LoadSUCIVectors()
+	if err != nil {
+		t.Fatalf("LoadSUCIVectors: %v", err)
+	}
+	v := data.Tests[1]
+	priv := suci.PrivateKey{Scheme: suci.Scheme(v.Scheme), ID: uint8(v.KeyID), Key: decodeHex(t, v.HNPrivateKey)}
+	ks, err := suci.NewKeyStore(priv)
+	if err != nil {
+		t.Fatalf("NewKeyStore: %v", err)
+	}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatalf("Public: %v", err)
+	}
+	concealed, err := suci.Conceal("001", "01", "0000000001", "0", pub, crand.Reader)
+	if err != nil {
+		t.Fatalf("Conceal: %v", err)
+	}
+	req := &AuthenticationInfoRequest{ServingNetworkName: testSNN, AusfInstanceID: "ausf-1"}
+
+	client := newTestClient(t, NewHandler(newMemSubscribers(t), WithSUCIKeys(ks)))
+	res, err := client.GenerateAuthData(context.Background(), concealed.String(), req)
+	if err != nil {
+		t.Fatalf("GenerateAuthData: %v", err)
+	}
+	if res.Supi != "imsi-001010000000001" {
+		t.Fatalf("supi = %q, want imsi-001010000000001", res.Supi)
+	}
+
+	client = newTestClient(t, NewHandler(newMemSubscribers(t)))
+	_, err = client.GenerateAuthData(context.Background(), concealed.String(), req)
+	var p *ProblemDetails
+	if !errors.As(err, &p) || p.Status != http.StatusForbidden {
+		t.Fatalf("SUCI without home network key: err = %v, want 403", err)
+	}
+	null, err := suci.Conceal("001", "01", "0000000001", "0", suci.PublicKey{}, nil)
+	if err != nil {
+		t.Fatalf("Conceal: %v", err)
+	}
+	if _, err := client.GenerateAuthData(context.Background(), null.String(), req); err != nil {
+		t.Fatalf("null-scheme SUCI: %v", err)
+	}
+}
+
+func TestGenerateAuthDataWrappedKeys(t *testing.T) {
+	plain := newMemSubscribers(t)
+	sub, err := plain.Get(context.Background(), "imsi-001010000000001")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	kek := bytes.Repeat([]byte{0x0f}, 16)
+	wrapped := *sub
+	wrapped.KeyWrap = string(keywrap.SchemeAESKW)
+	if wrapped.K, err = keywrap.Wrap(keywrap.SchemeAESKW, kek, sub.K); err != nil {
+		t.Fatalf("Wrap K: %v", err)
+	}
+	if wrapped.TOPc, err = keywrap.Wrap(keywrap.SchemeAESKW, kek, sub.TOPc); err != nil {
+		t.Fatalf("Wrap TOPc: %v", err)
+	}
+	wrapped.Options.KLength = len(sub.K) * 8
+	subs := store.NewMemory()
+	if err := subs.Put(context.Background(), &wrapped); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	rand := bytes.Repeat([]byte{0x77}, 16)
+	req := &AuthenticationInfoRequest{ServingNetworkName: testSNN, AusfInstanceID: "ausf-1"}
+	gen := func(s store.SubscriberStore, opts ...Option) *AuthenticationVector {
+		opts = append(opts, WithGenerator(&av.Generator{Rand: bytes.NewReader(rand)}))
+		res, err := newTestClient(t, NewHandler(s, opts...)).GenerateAuthData(context.Background(), sub.ID, req)
+		if err != nil {
+			t.Fatalf("GenerateAuthData: %v", err)
+		}
+		return res.AuthenticationVector
+	}
+	want := gen(plain)
+	got := gen(subs, WithKeyProvider(keywrap.SubscriberProvider(kek)))
+	if *got != *want {
+		t.Fatalf("wrapped vector = %+v, want %+v", got, want)
+	}
+}
+
+func newTestClient(t *testing.T, h http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(h)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return &Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+}
+
+func newMemSubscribers(t *testing.T) *store.Memory {
+	t.Helper()
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	v := data.Tests[0]
+	m := store.NewMemory()
+	err = m.Put(context.Background(), &store.Subscriber{
+		ID:   "imsi-001010000000001",
+		K:    decodeHex(t, v.K),
+		TOPc: decodeHex(t, v.Topc),
+		Options: tuak.Options{
+			MACLength: v.MAClength,
+			RESLength: v.RESLength,
+			CKLength:  v.CKlength,
+			IKLength:  v.IKlength,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	return m
+}
+
+func xorBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}
@@ -0,0 +1,141 @@
+package usim
+
+import "errors"
+
+// Instruction and status words used by AUTHENTICATE (TS 31.102, TS 102 221).
+const (
+	InsAuthenticate = 0x88
+
+	SWSuccess             = 0x9000
+	SWWrongLength         = 0x6700
+	SWConditionsNotMet    = 0x6985
+	SWIncorrectData       = 0x6A80
+	SWIncorrectP1P2       = 0x6A86
+	SWInsNotSupported     = 0x6D00
+	SWClassNotSupported   = 0x6E00
+	SWIncorrectMAC        = 0x9862
+	SWContextNotSupported = 0x9864
+)
+
+// Authentication contexts encoded in P2 (b8 set, context in b3-b1).
+const (
+	ContextGSM = 0x80
+	Context3G  = 0x81
+	// ContextIMSAKA is the ISIM value of Context3G (TS 31.103).
+	ContextIMSAKA = 0x81
+	ContextGBA    = 0x84
+)
+
+// Tags of the AUTHENTICATE command and response data.
+const (
+	tagSuccess          = 0xDB
+	tagSyncFailure      = 0xDC
+	tagGBABootstrap     = 0xDD
+	tagGBANAFDerivation = 0xDE
+)
+
+// HandleAPDU processes a command APDU and returns the response data
+// followed by SW1 SW2.
+func (u *USIM) HandleAPDU(cmd []byte) []byte {
+	p2, data, status := parseCommand(cmd)
+	if status != SWSuccess {
+		return sw(nil, status)
+	}
+	switch p2 {
+	case ContextGSM:
+		return u.authenticateGSM(data)
+	case Context3G:
+		return u.authenticate3G(data, true)
+	case ContextGBA:
+		return u.authenticateGBA(data)
+	default:
+		return sw(nil, SWIncorrectP1P2)
+	}
+}
+
+// parseCommand checks the AUTHENTICATE header and returns P2 and the data.
+func parseCommand(cmd []byte) (byte, []byte, uint16) {
+	if len(cmd) < 5 {
+		return 0, nil, SWWrongLength
+	}
+	cla, ins, p1, p2 := cmd[0], cmd[1], cmd[2], cmd[3]
+	if cla&0xF0 != 0x00 && cla&0xF0 != 0x40 {
+		return 0, nil, SWClassNotSupported
+	}
+	if ins != InsAuthenticate {
+		return 0, nil, SWInsNotSupported
+	}
+	lc := int(cmd[4])
+	if len(cmd) != 5+lc && len(cmd) != 6+lc {
+		return 0, nil, SWWrongLength
+	}
+	if p1 != 0x00 {
+		return 0, nil, SWIncorrectP1P2
+	}
+	return p2, cmd[5 : 5+lc], SWSuccess
+}
+
+// authenticate3G handles the 3G (USIM) and IMS AKA (ISIM) contexts; the
+// latter returns no Kc.
+func (u *USIM) authenticate3G(data []byte, withKc bool) []byte {
+	fields, ok := splitLV(data, 2)
+	if !ok || len(fields[0]) != 16 {
+		return sw(nil, SWWrongLength)
+	}
+	res, err := u.Authenticate(fields[0], fields[1])
+	switch {
+	case errors.Is(err, ErrSyncFailure):
+		return sw(appendTLV(nil, tagSyncFailure, res.AUTS), SWSuccess)
+	case errors.Is(err, ErrMACFailure):
+		return sw(nil, SWIncorrectMAC)
+	case err != nil:
+		return sw(nil, SWContextNotSupported)
+	}
+	out := []byte{tagSuccess}
+	out = appendLV(out, res.RES)
+	out = appendLV(out, res.CK)
+	out = appendLV(out, res.IK)
+	if withKc && res.Kc != nil {
+		out = appendLV(out, res.Kc)
+	}
+	return sw(out, SWSuccess)
+}
+
+func (u *USIM) authenticateGSM(data []byte) []byte {
+	fields, ok := splitLV(data, 1)
+	if !ok || len(fields[0]) != 16 {
+		return sw(nil, SWWrongLength)
+	}
+	sres, kc, err := u.GSM(fields[0])
+	if err != nil {
+		return sw(nil, SWContextNotSupported)
+	}
+	return sw(appendLV(appendLV(nil, sres), kc), SWSuccess)
+}
+
+// splitLV splits data into exactly n length-value fields.
+func splitLV(data []byte, n int) ([][]byte, bool) {
+	out := make([][]byte, 0, n)
+	for len(data) > 0 {
+		l := int(data[0])
+		if len(data) < 1+l {
+			return nil, false
+		}
+		out = append(out, data[1:1+l])
+		data = data[1+l:]
+	}
+	return out, len(out) == n
+}
+
+func appendLV(dst, v []byte) []byte {
+	dst = append(dst, byte(len(v)))
+	return append(dst, v...)
+}
+
+func appendTLV(dst []byte, tag byte, v []byte) []byte {
+	return appendLV(append(dst, tag), v)
+}
+
+func sw(data []byte, status uint16) []byte {
+	return append(data, byte(status>>8), byte(status))
+}
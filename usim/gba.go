@@ -0,0 +1,75 @@
+package usim
+
+import (
+	"errors"
+
+	"tuak/gba"
+)
+
+// ErrNotBootstrapped is returned for NAF derivation before bootstrapping.
+var ErrNotBootstrapped = errors.New("usim: no GBA bootstrapping")
+
+// BootstrapGBA runs the GBA_U bootstrapping procedure (TS 33.220 5.3.2):
+// it recovers MAC from the MAC* carried in autnStar, authenticates like the
+// 3G context, keeps Ks = CK || IK and RAND on the card and returns only RES.
+// A MAC longer than 160 bits has no MAC* and yields ErrGBAUnsupported.
+func (u *USIM) BootstrapGBA(rand, autnStar []byte) (*Result, error) {
+	res, err := u.authenticate(rand, autnStar, true)
+	if err != nil {
+		return res, err
+	}
+	u.mu.Lock()
+	u.gbaRAND = append([]byte(nil), rand...)
+	u.gbaKs = append(append([]byte(nil), res.CK...), res.IK...)
+	u.mu.Unlock()
+	return &Result{RES: res.RES}, nil
+}
+
+// DeriveNAF returns Ks_ext_NAF for the GBA_U NAF derivation mode.
+// Ks_int_NAF never leaves the card.
+func (u *USIM) DeriveNAF(nafID []byte, impi string) ([]byte, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.gbaKs == nil {
+		return nil, ErrNotBootstrapped
+	}
+	b := gba.Bootstrap{RAND: u.gbaRAND, Ks: u.gbaKs, IMPI: impi}
+	return b.KsExtNAF(nafID), nil
+}
+
+func (u *USIM) authenticateGBA(data []byte) []byte {
+	if len(data) == 0 {
+		return sw(nil, SWWrongLength)
+	}
+	switch data[0] {
+	case tagGBABootstrap:
+		fields, ok := splitLV(data[1:], 2)
+		if !ok || len(fields[0]) != 16 {
+			return sw(nil, SWWrongLength)
+		}
+		res, err := u.BootstrapGBA(fields[0], fields[1])
+		switch {
+		case errors.Is(err, ErrSyncFailure):
+			return sw(appendTLV(nil, tagSyncFailure, res.AUTS), SWSuccess)
+		case errors.Is(err, ErrMACFailure):
+			return sw(nil, SWIncorrectMAC)
+		case errors.Is(err, ErrGBAUnsupported):
+			return sw(nil, SWConditionsNotMet)
+		case err != nil:
+			return sw(nil, SWContextNotSupported)
+		}
+		return sw(appendTLV(nil, tagSuccess, res.RES), SWSuccess)
+	case tagGBANAFDerivation:
+		fields, ok := splitLV(data[1:], 2)
+		if !ok {
+			return sw(nil, SWWrongLength)
+		}
+		ks, err := u.DeriveNAF(fields[0], string(fields[1]))
+		if err != nil {
+			return sw(nil, SWConditionsNotMet)
+		}
+		return sw(appendTLV(nil, tagSuccess, ks), SWSuccess)
+	default:
+		return sw(nil, SWIncorrectData)
+	}
+}
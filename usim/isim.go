@@ -0,0 +1,44 @@
+package usim
+
+import "tuak"
+
+// ISIM simulates an ISIM application (TS 31.103): the IMS AKA and GBA
+// contexts are supported, the GSM context is not.
+type ISIM struct {
+	usim *USIM
+}
+
+// NewISIM creates an ISIM with its own SQN state.
+func NewISIM(k, topc []byte, opts ...tuak.Option) *ISIM {
+	return &ISIM{usim: New(k, topc, opts...)}
+}
+
+// Authenticate runs IMS AKA; the result carries no Kc.
+func (i *ISIM) Authenticate(rand, autn []byte) (*Result, error) {
+	res, err := i.usim.Authenticate(rand, autn)
+	if res != nil {
+		res.Kc = nil
+	}
+	return res, err
+}
+
+// HighestSQN returns SQN_MS of the ISIM.
+func (i *ISIM) HighestSQN() []byte {
+	return i.usim.HighestSQN()
+}
+
+// HandleAPDU processes an AUTHENTICATE command APDU.
+func (i *ISIM) HandleAPDU(cmd []byte) []byte {
+	p2, data, status := parseCommand(cmd)
+	if status != SWSuccess {
+		return sw(nil, status)
+	}
+	switch p2 {
+	case ContextIMSAKA:
+		return i.usim.authenticate3G(data, false)
+	case ContextGBA:
+		return i.usim.authenticateGBA(data)
+	default:
+		return sw(nil, SWIncorrectP1P2)
+	}
+}
@@ -0,0 +1,115 @@
+package usim
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"tuak/av"
+	"tuak/gba"
+	"tuak/kdf"
+)
+
+func TestISIMIMSAKA(t *testing.T) {
+	v := loadVectors(t)[1]
+	cred := credentialsFromVector(t, v)
+	isim := NewISIM(cred.K, cred.TOPc, cred.Options...)
+	rand := decodeHex(t, v.Rand)
+	vec, err := av.ComputeUMTS(cred, rand, sqnBytes(1<<indBits), decodeHex(t, v.AMF))
+	if err != nil {
+		t.Fatalf("ComputeUMTS: %v", err)
+	}
+
+	data := checkSW(t, isim.HandleAPDU(authCommand(ContextIMSAKA, rand, vec.AUTN)), SWSuccess)
+	fields, ok := splitLV(data[1:], 3)
+	if data[0] != tagSuccess || !ok {
+		t.Fatalf("malformed IMS AKA response %x", data)
+	}
+	if !bytes.Equal(fields[0], vec.XRES) || !bytes.Equal(fields[1], vec.CK) || !bytes.Equal(fields[2], vec.IK) {
+		t.Fatalf("RES/CK/IK mismatch")
+	}
+	checkSW(t, isim.HandleAPDU(authCommand(ContextGSM, rand, nil)), SWIncorrectP1P2)
+}
+
+func TestGBAContext(t *testing.T) {
+	v := loadVectors(t)[0]
+	cred := credentialsFromVector(t, v)
+	isim := NewISIM(cred.K, cred.TOPc, cred.Options...)
+	rand := decodeHex(t, v.Rand)
+	nafID := append([]byte("naf.example.org"), 0x01, 0x00, 0x00, 0x00, 0x02)
+	impi := "001010000000001@ims.mnc001.mcc001.3gppnetwork.org"
+
+	naf := append([]byte{tagGBANAFDerivation}, appendLV(appendLV(nil, nafID), []byte(impi))...)
+	checkSW(t, isim.HandleAPDU(gbaCommand(naf)), SWConditionsNotMet)
+
+	vec, err := av.ComputeUMTS(cred, rand, sqnBytes(1<<indBits), decodeHex(t, v.AMF))
+	if err != nil {
+		t.Fatalf("ComputeUMTS: %v", err)
+	}
+	plain := append([]byte{tagGBABootstrap}, appendLV(appendLV(nil, rand), vec.AUTN)...)
+	checkSW(t, isim.HandleAPDU(gbaCommand(plain)), SWIncorrectMAC)
+
+	autnStar, err := gba.AUTNStar(vec.AUTN, vec.IK)
+	if err != nil {
+		t.Fatalf("AUTNStar: %v", err)
+	}
+	boot := append([]byte{tagGBABootstrap}, appendLV(appendLV(nil, rand), autnStar)...)
+	data := checkSW(t, isim.HandleAPDU(gbaCommand(boot)), SWSuccess)
+	if data[0] != tagSuccess || !bytes.Equal(data[2:], vec.XRES) {
+		t.Fatalf("bootstrapping response %x, want RES %x", data, vec.XRES)
+	}
+
+	data = checkSW(t, isim.HandleAPDU(gbaCommand(naf)), SWSuccess)
+	ks := append(append([]byte(nil), vec.CK...), vec.IK...)
+	want := kdf.Derive(ks, 0x01, []byte("gba-me"), rand, []byte(impi), nafID)
+	if data[0] != tagSuccess || !bytes.Equal(data[2:], want) {
+		t.Fatalf("Ks_ext_NAF = %x, want %x", data[2:], want)
+	}
+
+	// A 256-bit MAC has no MAC*: the card reports the configuration, not
+	// a MAC failure.
+	v = loadVectors(t)[2]
+	cred = credentialsFromVector(t, v)
+	rand = decodeHex(t, v.Rand)
+	vec, err = av.ComputeUMTS(cred, rand, sqnBytes(1<<indBits), decodeHex(t, v.AMF))
+	if err != nil {
+		t.Fatalf("ComputeUMTS: %v", err)
+	}
+	u := New(cred.K, cred.TOPc, cred.Options...)
+	if _, err := u.BootstrapGBA(rand, vec.AUTN); !errors.Is(err, ErrGBAUnsupported) {
+		t.Fatalf("BootstrapGBA with 256-bit MAC: err = %v, want ErrGBAUnsupported", err)
+	}
+	boot = append([]byte{tagGBABootstrap}, appendLV(appendLV(nil, rand), vec.AUTN)...)
+	checkSW(t, NewISIM(cred.K, cred.TOPc, cred.Options...).HandleAPDU(gbaCommand(boot)), SWConditionsNotMet)
+}
+
+func TestAuthenticate5G(t *testing.T) {
+	v := loadVectors(t)[0]
+	cred := credentialsFromVector(t, v)
+	u := New(cred.K, cred.TOPc, cred.Options...)
+	snn := "5G:mnc001.mcc001.3gppnetwork.org"
+	rand := decodeHex(t, v.Rand)
+	g := &av.Generator{Rand: bytes.NewReader(rand)}
+
+	he, err := g.HE5G(cred, sqnBytes(1<<indBits), []byte{0x80, 0x00}, snn)
+	if err != nil {
+		t.Fatalf("HE5G: %v", err)
+	}
+	res, err := u.Authenticate5G(he.RAND, he.AUTN, snn)
+	if err != nil {
+		t.Fatalf("Authenticate5G: %v", err)
+	}
+	if !bytes.Equal(res.RESStar, he.XRESStar) || !bytes.Equal(res.KAUSF, he.KAUSF) {
+		t.Fatalf("RES*/KAUSF mismatch")
+	}
+
+	vec, _ := av.ComputeUMTS(cred, rand, sqnBytes(2<<indBits), []byte{0x00, 0x00})
+	if _, err := u.Authenticate5G(rand, vec.AUTN, snn); !errors.Is(err, ErrAMFSeparation) {
+		t.Fatalf("err = %v, want ErrAMFSeparation", err)
+	}
+}
+
+func gbaCommand(data []byte) []byte {
+	cmd := []byte{0x00, InsAuthenticate, 0x00, ContextGBA, byte(len(data))}
+	return append(append(cmd, data...), 0x00)
+}
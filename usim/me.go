@@ -0,0 +1,45 @@
+package usim
+
+import (
+	"errors"
+	"fmt"
+
+	"tuak/kdf"
+)
+
+// ErrAMFSeparation is returned when the AMF separation bit of a 5G AUTN is not set.
+var ErrAMFSeparation = errors.New("usim: AMF separation bit not set")
+
+// Result5G holds the ME-side outputs of 5G AKA.
+type Result5G struct {
+	RESStar []byte
+	KAUSF   []byte
+	KSEAF   []byte
+	// AUTS is set on ErrSyncFailure.
+	AUTS []byte
+}
+
+// Authenticate5G performs the ME part of 5G AKA (TS 33.501 6.1.3.2): it
+// checks the AMF separation bit, lets the USIM verify AUTN and return RES,
+// CK and IK, and derives RES*, KAUSF and KSEAF for the serving network.
+func (u *USIM) Authenticate5G(rand, autn []byte, snn string) (*Result5G, error) {
+	if len(autn) < 8 {
+		return nil, fmt.Errorf("usim: AUTN length %d bytes", len(autn))
+	}
+	if autn[6]&0x80 == 0 {
+		return nil, ErrAMFSeparation
+	}
+	res, err := u.Authenticate(rand, autn)
+	if errors.Is(err, ErrSyncFailure) {
+		return &Result5G{AUTS: res.AUTS}, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	kausf := kdf.KAUSF(res.CK, res.IK, snn, autn[:6])
+	return &Result5G{
+		RESStar: kdf.XRESStar(res.CK, res.IK, snn, rand, res.RES),
+		KAUSF:   kausf,
+		KSEAF:   kdf.KSEAF(kausf, snn),
+	}, nil
+}
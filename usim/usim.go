@@ -0,0 +1,190 @@
+// Package usim simulates the USIM side of AKA with TUAK, answering
+// AUTHENTICATE command APDUs (TS 31.102 7.1.2).
+package usim
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+
+	"tuak"
+	"tuak/gba"
+)
+
+const (
+	// indBits is the length of IND in SQN = SEQ || IND (TS 33.102 C.3.2).
+	indBits = 5
+	// delta bounds how far SEQ may jump ahead of the highest accepted SEQ.
+	delta = 1 << 28
+)
+
+var (
+	// ErrMACFailure is returned when XMAC does not match the MAC in AUTN.
+	ErrMACFailure = errors.New("usim: MAC failure")
+	// ErrSyncFailure is returned when SQN is not fresh; AUTS is set in the result.
+	ErrSyncFailure = errors.New("usim: synchronisation failure")
+	// ErrGBAUnsupported is returned by BootstrapGBA when the MAC length
+	// has no GBA_U MAC* (it is longer than SHA-1).
+	ErrGBAUnsupported = errors.New("usim: GBA_U not supported with this MAC length")
+)
+
+// Result is the outcome of an authentication.
+type Result struct {
+	RES  []byte
+	CK   []byte
+	IK   []byte
+	Kc   []byte
+	AUTS []byte
+}
+
+// USIM holds the subscriber secrets and SQN state of one card. It is safe
+// for concurrent use.
+type USIM struct {
+	mu   sync.Mutex
+	k    []byte
+	topc []byte
+	opts []tuak.Option
+	// seq holds SEQ_MS per IND; sqnMS is the highest SQN accepted.
+	seq   [1 << indBits]uint64
+	sqnMS uint64
+	// gbaRAND and gbaKs hold the GBA_U bootstrapping state.
+	gbaRAND []byte
+	gbaKs   []byte
+}
+
+// New creates a USIM with all SEQ_MS values zero.
+func New(k, topc []byte, opts ...tuak.Option) *USIM {
+	return &USIM{k: k, topc: topc, opts: opts}
+}
+
+// HighestSQN returns SQN_MS, the highest SQN accepted so far.
+func (u *USIM) HighestSQN() []byte {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return sqnBytes(u.sqnMS)
+}
+
+// Authenticate verifies AUTN and computes RES, CK, IK and Kc (3G context).
+// On ErrSyncFailure the returned Result carries AUTS.
+func (u *USIM) Authenticate(rand, autn []byte) (*Result, error) {
+	return u.authenticate(rand, autn, false)
+}
+
+// authenticate implements Authenticate. With gbaU set, autn is the GBA_U
+// AUTN* and MAC is recovered from MAC* with the IK computed for RAND.
+func (u *USIM) authenticate(rand, autn []byte, gbaU bool) (*Result, error) {
+	if len(autn) < 8 {
+		return nil, fmt.Errorf("usim: AUTN length %d bytes", len(autn))
+	}
+	t, err := tuak.NewWithTOPc(u.k, u.topc, rand, nil, nil, u.opts...)
+	if err != nil {
+		return nil, err
+	}
+	res, ck, ik, ak, err := t.F2345()
+	if err != nil {
+		return nil, err
+	}
+	if gbaU {
+		if autn, err = gba.AUTNStar(autn, ik); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrGBAUnsupported, err)
+		}
+	}
+	sqn := xor(autn[:6], ak)
+	amf := autn[6:8]
+	mac := autn[8:]
+
+	t, err = tuak.NewWithTOPc(u.k, u.topc, rand, sqn, amf, u.opts...)
+	if err != nil {
+		return nil, err
+	}
+	xmac, err := t.F1()
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(xmac, mac) != 1 {
+		return nil, ErrMACFailure
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.fresh(sqnValue(sqn)) {
+		auts, err := u.auts(rand)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{AUTS: auts}, ErrSyncFailure
+	}
+	u.accept(sqnValue(sqn))
+	// Kc is only defined for 128-bit CK and IK; it is omitted otherwise.
+	kc, _ := tuak.C3(ck, ik)
+	return &Result{RES: res, CK: ck, IK: ik, Kc: kc}, nil
+}
+
+// GSM computes SRES and Kc for the GSM security context.
+func (u *USIM) GSM(rand []byte) (sres, kc []byte, err error) {
+	t, err := tuak.NewWithTOPc(u.k, u.topc, rand, nil, nil, u.opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t.GSM()
+}
+
+// fresh applies the SQN checks of TS 33.102 C.2.2.
+func (u *USIM) fresh(sqn uint64) bool {
+	seq, ind := sqn>>indBits, sqn&(1<<indBits-1)
+	if seq <= u.seq[ind] {
+		return false
+	}
+	return seq <= u.sqnMS>>indBits+delta
+}
+
+func (u *USIM) accept(sqn uint64) {
+	u.seq[sqn&(1<<indBits-1)] = sqn >> indBits
+	if sqn > u.sqnMS {
+		u.sqnMS = sqn
+	}
+}
+
+// auts builds SQN_MS xor AK* || MAC-S with the dummy AMF (TS 33.102 6.3.3).
+func (u *USIM) auts(rand []byte) ([]byte, error) {
+	sqnMS := sqnBytes(u.sqnMS)
+	t, err := tuak.NewWithTOPc(u.k, u.topc, rand, sqnMS, make([]byte, 2), u.opts...)
+	if err != nil {
+		return nil, err
+	}
+	akStar, err := t.F5Star()
+	if err != nil {
+		return nil, err
+	}
+	macS, err := t.F1Star()
+	if err != nil {
+		return nil, err
+	}
+	return append(xor(sqnMS, akStar), macS...), nil
+}
+
+func sqnValue(sqn []byte) uint64 {
+	var v uint64
+	for _, b := range sqn {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+func sqnBytes(v uint64) []byte {
+	out := make([]byte, 6)
+	for i := 5; i >= 0; i-- {
+		out[i] = byte(v)
+		v >>= 8
+	}
+	return out
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package usim
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"tuak"
+	"tuak/av"
+	"tuak/testvectors"
+)
+
+func TestAuthenticate3GAPDU(t *testing.T) {
+	for _, v := range loadVectors(t) {
+		cred := credentialsFromVector(t, v)
+		u := New(cred.K, cred.TOPc, cred.Options...)
+		rand := decodeHex(t, v.Rand)
+		sqn := sqnBytes(1<<indBits | 3)
+
+		vec, err := av.ComputeUMTS(cred, rand, sqn, decodeHex(t, v.AMF))
+		if err != nil {
+			t.Fatalf("ComputeUMTS: %v", err)
+		}
+		resp := u.HandleAPDU(authCommand(Context3G, rand, vec.AUTN))
+		data := checkSW(t, resp, SWSuccess)
+		if data[0] != tagSuccess {
+			t.Fatalf("vector %d: response tag %02x", v.ID, data[0])
+		}
+		n := 4
+		if v.CKlength != 128 || v.IKlength != 128 {
+			n = 3
+		}
+		fields, ok := splitLV(data[1:], n)
+		if !ok {
+			t.Fatalf("vector %d: malformed response %x", v.ID, data)
+		}
+		if !bytes.Equal(fields[0], vec.XRES) || !bytes.Equal(fields[1], vec.CK) || !bytes.Equal(fields[2], vec.IK) {
+			t.Fatalf("vector %d: RES/CK/IK mismatch", v.ID)
+		}
+		if n == 4 && len(fields[3]) != 8 {
+			t.Fatalf("vector %d: Kc length %d", v.ID, len(fields[3]))
+		}
+		if !bytes.Equal(u.HighestSQN(), sqn) {
+			t.Fatalf("vector %d: SQN_MS = %x, want %x", v.ID, u.HighestSQN(), sqn)
+		}
+	}
+}
+
+func TestAuthenticateSyncFailure(t *testing.T) {
+	v := loadVectors(t)[0]
+	cred := credentialsFromVector(t, v)
+	u := New(cred.K, cred.TOPc, cred.Options...)
+	rand := decodeHex(t, v.Rand)
+	amf := decodeHex(t, v.AMF)
+
+	accepted := sqnBytes(5<<indBits | 1)
+	vec, _ := av.ComputeUMTS(cred, rand, accepted, amf)
+	checkSW(t, u.HandleAPDU(authCommand(Context3G, rand, vec.AUTN)), SWSuccess)
+
+	// Replay of the same SQN.
+	data := checkSW(t, u.HandleAPDU(authCommand(Context3G, rand, vec.AUTN)), SWSuccess)
+	if data[0] != tagSyncFailure {
+		t.Fatalf("replay: response tag %02x, want DC", data[0])
+	}
+	sqnMS, err := av.Resync(cred, rand, data[2:])
+	if err != nil {
+		t.Fatalf("Resync: %v", err)
+	}
+	if !bytes.Equal(sqnMS, accepted) {
+		t.Fatalf("SQN_MS = %x, want %x", sqnMS, accepted)
+	}
+
+	// Same SEQ with another IND is fresh; a lower SEQ with that IND is not.
+	vec, _ = av.ComputeUMTS(cred, rand, sqnBytes(5<<indBits|2), amf)
+	checkSW(t, u.HandleAPDU(authCommand(Context3G, rand, vec.AUTN)), SWSuccess)
+	vec, _ = av.ComputeUMTS(cred, rand, sqnBytes(4<<indBits|2), amf)
+	if data := checkSW(t, u.HandleAPDU(authCommand(Context3G, rand, vec.AUTN)), SWSuccess); data[0] != tagSyncFailure {
+		t.Fatalf("old SEQ accepted")
+	}
+
+	// Too far ahead.
+	vec, _ = av.ComputeUMTS(cred, rand, sqnBytes((5+delta+1)<<indBits), amf)
+	if data := checkSW(t, u.HandleAPDU(authCommand(Context3G, rand, vec.AUTN)), SWSuccess); data[0] != tagSyncFailure {
+		t.Fatalf("SEQ beyond delta accepted")
+	}
+}
+
+func TestAuthenticateMACFailure(t *testing.T) {
+	v := loadVectors(t)[0]
+	cred := credentialsFromVector(t, v)
+	u := New(cred.K, cred.TOPc, cred.Options...)
+	rand := decodeHex(t, v.Rand)
+	vec, _ := av.ComputeUMTS(cred, rand, sqnBytes(1<<indBits), decodeHex(t, v.AMF))
+	vec.AUTN[len(vec.AUTN)-1] ^= 0x01
+	checkSW(t, u.HandleAPDU(authCommand(Context3G, rand, vec.AUTN)), SWIncorrectMAC)
+}
+
+func TestAuthenticateGSM(t *testing.T) {
+	v := loadVectors(t)[0]
+	cred := credentialsFromVector(t, v)
+	u := New(cred.K, cred.TOPc, cred.Options...)
+	data := checkSW(t, u.HandleAPDU(authCommand(ContextGSM, decodeHex(t, v.Rand), nil)), SWSuccess)
+	fields, ok := splitLV(data, 2)
+	if !ok || len(fields[0]) != 4 || len(fields[1]) != 8 {
+		t.Fatalf("malformed GSM response %x", data)
+	}
+	// RES is 32 bits for set 1, so SRES equals RES.
+	if !bytes.Equal(fields[0], decodeHex(t, v.F2)) {
+		t.Fatalf("SRES = %x, want %s", fields[0], v.F2)
+	}
+}
+
+func TestHandleAPDUErrors(t *testing.T) {
+	u := New(make([]byte, 16), make([]byte, 32))
+	checkSW(t, u.HandleAPDU([]byte{0x00, 0xA4, 0x00, 0x00, 0x00}), SWInsNotSupported)
+	checkSW(t, u.HandleAPDU([]byte{0x00, 0x88, 0x00, 0x85, 0x00}), SWIncorrectP1P2)
+	checkSW(t, u.HandleAPDU([]byte{0x00, 0x88, 0x00, 0x81, 0x05, 0x01}), SWWrongLength)
+}
+
+func authCommand(p2 byte, rand, autn []byte) []byte {
+	data := appendLV(nil, rand)
+	if autn != nil {
+		data = appendLV(data, autn)
+	}
+	cmd := []byte{0x00, InsAuthenticate, 0x00, p2, byte(len(data))}
+	cmd = append(cmd, data...)
+	return append(cmd, 0x00)
+}
+
+func checkSW(t *testing.T, resp []byte, want uint16) []byte {
+	t.Helper()
+	if len(resp) < 2 {
+		t.Fatalf("short response %x", resp)
+	}
+	got := uint16(resp[len(resp)-2])<<8 | uint16(resp[len(resp)-1])
+	if got != want {
+		t.Fatalf("SW = %04X, want %04X", got, want)
+	}
+	return resp[:len(resp)-2]
+}
+
+func loadVectors(t *testing.T) []testvectors.TUAKVector {
+	t.Helper()
+	data, err := testvectors.LoadTUAKVectors()
+	if err != nil {
+		t.Fatalf("LoadTUAKVectors: %v", err)
+	}
+	return data.Tests
+}
+
+func credentialsFromVector(t *testing.T, v testvectors.TUAKVector) av.Credentials {
+	t.Helper()
+	return av.Credentials{
+		K:    decodeHex(t, v.K),
+		TOPc: decodeHex(t, v.Topc),
+		Options: []tuak.Option{
+			tuak.WithMACLength(v.MAClength),
+			tuak.WithRESLength(v.RESLength),
+			tuak.WithCKLength(v.CKlength),
+			tuak.WithIKLength(v.IKlength),
+			tuak.WithKeccakIterations(v.KeccakIterations),
+		},
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+	return b
+}